@@ -0,0 +1,253 @@
+package main
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/api"
+	"CTI-Dashboard/scraper/captcha"
+	"CTI-Dashboard/scraper/input"
+	"CTI-Dashboard/scraper/logger"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultAPIAddress is where the REST API listens when the app runs without
+// a window and no address is configured.
+const DefaultAPIAddress = "127.0.0.1:8470"
+
+// invalidError marks an error caused by bad input rather than a failure, so
+// the API can answer it with 400.
+type invalidError struct{ error }
+
+func (e invalidError) Unwrap() error { return e.error }
+
+func invalid(format string, args ...any) error {
+	return invalidError{fmt.Errorf(format, args...)}
+}
+
+// apiBackend exposes the app to the REST API. It is not bound to the
+// frontend.
+type apiBackend struct {
+	app *App
+}
+
+func (b apiBackend) Forums() ([]models.Forum, error) {
+	return b.app.GetForums(), nil
+}
+
+func (b apiBackend) Forum(forumID string) (models.Forum, error) {
+	for _, forum := range b.app.GetForums() {
+		if forum.ForumID == forumID {
+			return forum, nil
+		}
+	}
+	return models.Forum{}, fmt.Errorf("forum %s: %w", forumID, api.ErrNotFound)
+}
+
+func (b apiBackend) CreateForum(forum models.Forum) (models.Forum, error) {
+	forumID, _, err := b.app.addForum(forum)
+	if err != nil {
+		return models.Forum{}, apiError(err)
+	}
+	return b.Forum(forumID)
+}
+
+func (b apiBackend) UpdateForum(forum models.Forum) (models.Forum, error) {
+	current, err := b.Forum(forum.ForumID)
+	if err != nil {
+		return models.Forum{}, err
+	}
+	if forum.ForumName != "" || forum.ForumURL != "" || forum.ForumDescription != "" {
+		name, url, description := current.ForumName, current.ForumURL, current.ForumDescription
+		if forum.ForumName != "" {
+			name = forum.ForumName
+		}
+		if forum.ForumURL != "" {
+			if err := input.CheckURL(forum.ForumURL); err != nil {
+				return models.Forum{}, apiError(invalid("invalid forum URL: %w", err))
+			}
+			url = forum.ForumURL
+		}
+		if forum.ForumDescription != "" {
+			description = forum.ForumDescription
+		}
+		if _, err := b.app.db.Exec(`UPDATE forums SET forum_name = ?, forum_url = ?, forum_description = ? WHERE forum_id = ?`,
+			name, url, description, forum.ForumID); err != nil {
+			logger.Error("Could not update forum", "error", err, "forum_id", forum.ForumID)
+			return models.Forum{}, err
+		}
+	}
+	if forum.ProxyType != "" {
+		if err := b.app.SetForumProxyType(forum.ForumID, forum.ProxyType); err != nil {
+			return models.Forum{}, apiError(err)
+		}
+	}
+	if forum.FetchMode != "" {
+		if err := b.app.SetForumFetchMode(forum.ForumID, forum.FetchMode); err != nil {
+			return models.Forum{}, apiError(err)
+		}
+	}
+	if forum.ReadyStrategy != "" || forum.ReadySelector != "" || forum.ReadyMaxWait != 0 {
+		strategy, selector, maxWait := current.ReadyStrategy, current.ReadySelector, current.ReadyMaxWait
+		if forum.ReadyStrategy != "" {
+			strategy = forum.ReadyStrategy
+		}
+		if forum.ReadySelector != "" {
+			selector = forum.ReadySelector
+		}
+		if forum.ReadyMaxWait != 0 {
+			maxWait = forum.ReadyMaxWait
+		}
+		if err := b.app.SetForumReadiness(forum.ForumID, strategy, selector, maxWait); err != nil {
+			return models.Forum{}, apiError(err)
+		}
+	}
+	if forum.CaptureFormats != nil {
+		if err := b.app.SetForumCaptureFormats(forum.ForumID, forum.CaptureFormats); err != nil {
+			return models.Forum{}, apiError(err)
+		}
+	}
+	return b.Forum(forum.ForumID)
+}
+
+func (b apiBackend) DeleteForum(forumID string) error {
+	if _, err := b.Forum(forumID); err != nil {
+		return err
+	}
+	return b.app.DeleteForum(forumID)
+}
+
+func (b apiBackend) Scrape(forumID string) error {
+	forum, err := b.Forum(forumID)
+	if err != nil {
+		return err
+	}
+	return b.app.SingularScrape(forum)
+}
+
+func (b apiBackend) ExtractPosts(forumID string) (int, error) {
+	if _, err := b.Forum(forumID); err != nil {
+		return 0, err
+	}
+	return b.app.Extract_posts(forumID)
+}
+
+func (b apiBackend) ScanPosts(forumID string) error {
+	return b.app.ScanPosts(forumID)
+}
+
+func (b apiBackend) Posts(forumID string) ([]models.Post, error) {
+	if _, err := b.Forum(forumID); err != nil {
+		return nil, err
+	}
+	return b.app.GetPosts(forumID)
+}
+
+func (b apiBackend) Chart(forumID string) ([]models.Chart, error) {
+	if _, err := b.Forum(forumID); err != nil {
+		return nil, err
+	}
+	return b.app.GetChartData(forumID)
+}
+
+func (b apiBackend) ScanHistory(forumID string) ([]models.ScanRun, error) {
+	if _, err := b.Forum(forumID); err != nil {
+		return nil, err
+	}
+	return b.app.GetScanHistory(forumID)
+}
+
+func (b apiBackend) Alerts(all bool) ([]models.Alert, error) {
+	return b.app.GetAlerts(all)
+}
+
+func (b apiBackend) AcknowledgeAlert(alertID string) error {
+	return b.app.AcknowledgeAlert(alertID)
+}
+
+func (b apiBackend) ExportSTIX(filter models.ExportFilter) (string, error) {
+	return b.app.ExportSTIX(filter)
+}
+
+func (b apiBackend) ExportMISP(filter models.ExportFilter) (string, error) {
+	return b.app.ExportMISP(filter)
+}
+
+func (b apiBackend) ExportCSV(filter models.ExportFilter) (string, error) {
+	return b.app.ExportCSV(filter)
+}
+
+func (b apiBackend) PendingCaptchas() []models.CaptchaChallenge {
+	return b.app.GetPendingCaptchas()
+}
+
+func (b apiBackend) SubmitCaptcha(id string, answer models.CaptchaAnswer) error {
+	err := b.app.SubmitCaptcha(id, answer)
+	if errors.Is(err, captcha.ErrUnknown) {
+		return fmt.Errorf("%w: %v", api.ErrNotFound, err)
+	}
+	return err
+}
+
+func (b apiBackend) Background(job func()) {
+	b.app.background(job)
+}
+
+// apiError turns input errors of the app into errors the API answers
+// with 400.
+func apiError(err error) error {
+	var bad invalidError
+	if errors.As(err, &bad) {
+		return fmt.Errorf("%w: %v", api.ErrInvalid, err)
+	}
+	return err
+}
+
+// Serve the REST API when an address is configured
+func (a *App) startAPI() {
+	cfg := a.config()
+	if cfg.APIAddress == "" {
+		return
+	}
+	if len(cfg.APIKeys) == 0 {
+		logger.Error("API server not started: no API keys configured", "address", cfg.APIAddress)
+		return
+	}
+	listener, err := api.NewServer(apiBackend{a}, cfg.APIKeys).Listen(cfg.APIAddress, cfg.APICertFile, cfg.APIKeyFile)
+	if err != nil {
+		logger.Error("Could not start API server", "error", err, "address", cfg.APIAddress)
+		return
+	}
+	a.mu.Lock()
+	a.api = listener
+	a.mu.Unlock()
+}
+
+// runHeadless serves the REST API without a window until the process is
+// interrupted.
+func runHeadless(app *App) error {
+	if app.cfg.APIAddress == "" {
+		app.cfg.APIAddress = DefaultAPIAddress
+	}
+	if len(app.cfg.APIKeys) == 0 {
+		return errors.New("no API keys configured: set CTI_API_KEYS to name=key pairs")
+	}
+	app.startServers()
+	if _, apiServer := app.servers(); apiServer == nil {
+		return fmt.Errorf("could not serve the API on %s", app.cfg.APIAddress)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	logger.Info("Shutting down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	app.shutdown(ctx)
+	return nil
+}
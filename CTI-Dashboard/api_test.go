@@ -0,0 +1,30 @@
+package main
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/api"
+	"errors"
+	"testing"
+)
+
+func TestUpdateForumURL(t *testing.T) {
+	app := testApp(t)
+	backend := apiBackend{app}
+	if _, err := app.db.Exec(`INSERT INTO forums (forum_id, forum_name, forum_url, forum_description, last_scaned)
+		VALUES ('f1', 'Bazaar', 'https://bazaar.example/', 'Marketplace', '')`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, url := range []string{"ftp://bazaar.example/", "http://expyuzz4wqqyqhjn.onion/", "http:///threads/"} {
+		if _, err := backend.UpdateForum(models.Forum{ForumID: "f1", ForumURL: url}); !errors.Is(err, api.ErrInvalid) {
+			t.Errorf("UpdateForum(%q) = %v, want ErrInvalid", url, err)
+		}
+	}
+	forum, err := backend.UpdateForum(models.Forum{ForumID: "f1", ForumURL: "https://bazaar.example/forum/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forum.ForumURL != "https://bazaar.example/forum/" || forum.ForumName != "Bazaar" {
+		t.Errorf("got %+v", forum)
+	}
+}
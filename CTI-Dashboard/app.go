@@ -3,74 +3,546 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/alerts"
+	"CTI-Dashboard/scraper/captcha"
+	"CTI-Dashboard/scraper/changes"
 	"CTI-Dashboard/scraper/config"
+	"CTI-Dashboard/scraper/connectivity"
+	"CTI-Dashboard/scraper/custody"
+	"CTI-Dashboard/scraper/export"
 	"CTI-Dashboard/scraper/extractor"
+	"CTI-Dashboard/scraper/headless"
+	"CTI-Dashboard/scraper/history"
+	"CTI-Dashboard/scraper/input"
 	"CTI-Dashboard/scraper/logger"
 	"CTI-Dashboard/scraper/output"
+	"CTI-Dashboard/scraper/proxy"
 	"CTI-Dashboard/scraper/scanner"
+	"CTI-Dashboard/scraper/search"
+	"CTI-Dashboard/scraper/session"
+	"CTI-Dashboard/scraper/severity"
+	"CTI-Dashboard/scraper/snapshots"
+	"CTI-Dashboard/scraper/taxii"
+	"CTI-Dashboard/scraper/torcontrol"
+	"CTI-Dashboard/scraper/vault"
+	"CTI-Dashboard/scraper/watchlist"
 
 	"github.com/google/uuid"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/pkg/browser"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// shutdownWait is the longest shutdown waits for background jobs.
+const shutdownWait = 30 * time.Second
+
 // App struct
 type App struct {
-	ctx    context.Context
-	cfg    config.Config
-	client *http.Client
-	writer *output.Writer
-	db     *sql.DB
-	wg     sync.WaitGroup
+	ctx        context.Context
+	configPath string
+	// mu guards cfg, the network built from it and the servers, which
+	// UpdateSettings replaces; updating serializes updates.
+	mu       sync.RWMutex
+	updating sync.Mutex
+	cfg      config.Config
+	pool     *proxy.Pool
+	isolator *proxy.Isolator
+	browser  *headless.Pool
+	cookies  *headless.CookieJar
+	vault    *vault.Vault
+	sessions *session.Store
+	captchas *captcha.Broker
+	custody  *custody.Signer
+	checker  connectivity.Checker
+	writer   *output.Writer
+	db       *sql.DB
+	taxii    *http.Server
+	api      *http.Server
+	// retired are isolators replaced by UpdateSettings, closed on shutdown
+	// since scans may still use their relays.
+	retired []*proxy.Isolator
+	// saved is the configuration as last saved by UpdateSettings; restart
+	// names the options in it that take effect on the next start.
+	saved   *config.Config
+	restart []string
+	// wg tracks background jobs, waited for on shutdown.
+	wg sync.WaitGroup
 }
 
-func NewApp(cfg config.Config, client *http.Client, writer *output.Writer, db *sql.DB) *App {
-	return &App{
-		cfg:    cfg,
-		client: client,
-		writer: writer,
-		db:     db,
+func NewApp(cfg config.Config, configPath string, pool *proxy.Pool, checker connectivity.Checker, writer *output.Writer, db *sql.DB) *App {
+	browserPool := headless.NewPool(headless.Config{
+		MaxTabs:      cfg.BrowserTabs,
+		RecycleAfter: cfg.BrowserRecycle,
+	})
+	credentials := vault.New(db)
+	app := &App{
+		configPath: configPath,
+		cfg:        cfg,
+		pool:       pool,
+		isolator:   proxy.NewIsolator(cfg),
+		browser:    browserPool,
+		cookies:    headless.NewCookieJar(),
+		vault:      credentials,
+		sessions:   session.NewStore(db, credentials),
+		checker:    checker,
+		writer:     writer,
+		db:         db,
 	}
+	signer, err := custody.NewSigner(cfg.OutputDir)
+	if err != nil {
+		logger.Error("Could not load the chain-of-custody key, manifests will not be signed", "error", err)
+	} else {
+		logger.Info("Signing chain-of-custody manifests", "fingerprint", signer.Fingerprint())
+	}
+	app.custody = signer
+	app.captchas = captcha.NewBroker(func(event string, challenge models.CaptchaChallenge) {
+		app.emit(event, challenge)
+	}, captcha.DefaultWait)
+	return app
 }
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.startServers()
+}
+
+// Start the servers other tools reach the data through
+func (a *App) startServers() {
+	a.startTAXII()
+	a.startAPI()
+}
+
+// Serve the TAXII collections when an address is configured
+func (a *App) startTAXII() {
+	cfg := a.config()
+	if cfg.TAXIIAddress == "" {
+		return
+	}
+	if len(cfg.TAXIIKeys) == 0 {
+		logger.Error("TAXII server not started: no API keys configured", "address", cfg.TAXIIAddress)
+		return
+	}
+	server, err := taxii.NewServer(a.db, cfg.TAXIIKeys).Listen(cfg.TAXIIAddress, cfg.TAXIICertFile, cfg.TAXIIKeyFile)
+	if err != nil {
+		logger.Error("Could not start TAXII server", "error", err, "address", cfg.TAXIIAddress)
+		return
+	}
+	a.mu.Lock()
+	a.taxii = server
+	a.mu.Unlock()
+}
+
+// The TAXII and API servers running
+func (a *App) servers() (*http.Server, *http.Server) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.taxii, a.api
+}
+
+// Push an event to the frontend once it is up
+func (a *App) emit(event string, data any) {
+	if a.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, event, data)
+}
+
+// Run a job in the background, tracked so shutdown can wait for it
+func (a *App) background(job func()) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		job()
+	}()
+}
+
+// Stop the servers first so no new jobs start, then give running jobs until
+// ctx is done, or shutdownWait at most, to finish with the browser and
+// proxies they use
+func (a *App) shutdown(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, shutdownWait)
+	defer cancel()
+	taxiiServer, apiServer := a.servers()
+	if taxiiServer != nil {
+		taxiiServer.Shutdown(ctx)
+	}
+	if apiServer != nil {
+		apiServer.Shutdown(ctx)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Error("Shutting down with background jobs still running")
+	}
+	a.browser.Close()
+	a.mu.Lock()
+	a.isolator.Close()
+	for _, isolator := range a.retired {
+		isolator.Close()
+	}
+	a.mu.Unlock()
+}
+
+// The configuration in effect
+func (a *App) config() config.Config {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.cfg
+}
+
+// The proxy pool, identities and connectivity check in effect
+func (a *App) network() (*proxy.Pool, *proxy.Isolator, connectivity.Checker) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.pool, a.isolator, a.checker
+}
+
+// Scan a target through a proxy of the forum's type using the forum's own
+// identity on that proxy. run is scanner.Run or scanner.RunPost. When the
+// proxy fails the scan moves on to the next endpoint of the type, until
+// every healthy one has been tried.
+func (a *App) scan(forumID string, name string, target string, run func(scanner.Options) error) error {
+	settings := a.forumSettings(forumID)
+	var tried []string
+	var err error
+	for {
+		lease, identity, acquireErr := a.identity(forumID, settings.proxyType, tried...)
+		if acquireErr != nil {
+			if len(tried) > 0 {
+				return err
+			}
+			return acquireErr
+		}
+		err = a.scanThrough(forumID, name, target, run, settings, lease, identity)
+
+		// A failed connectivity check is worth another endpoint, but may be
+		// the check's own endpoint being down rather than the proxy.
+		class := history.Classify(err)
+		lease.Release(class == history.ClassProxy || class == history.ClassRefused)
+		if class != history.ClassProxy && class != history.ClassRefused && class != history.ClassTor {
+			return err
+		}
+		tried = append(tried, lease.Endpoint.Name)
+		logger.Info("Trying another proxy", "forum_id", forumID, "failed_proxy", lease.Endpoint.Name, "error", err)
+	}
+}
+
+// Scan a target once through the leased endpoint
+func (a *App) scanThrough(forumID string, name string, target string, run func(scanner.Options) error, settings scanSettings, lease *proxy.Lease, identity *proxy.Identity) error {
+	cfg := a.config()
+	_, _, checker := a.network()
+	client := a.sessionClient(forumID, identity)
+	opts := scanner.Options{
+		Targets:    []string{target},
+		Client:     client,
+		Writer:     a.writer,
+		Timeout:    cfg.Timeout,
+		Retries:    cfg.MaxRetries,
+		TargetName: name,
+		ForumID:    forumID,
+		Proxy:      identity.BrowserProxy,
+		DB:         a.db,
+		Browser:    a.browser,
+		Readiness:  settings.readiness,
+		FetchMode:  settings.fetchMode,
+		Cookies:    a.cookies,
+		Captcha:    a.captchaHandler(),
+		Custody:    a.custody,
+		Alert: func(alert models.Alert) {
+			a.emit("alert:new", alert)
+		},
+
+		CaptureFormats: settings.captureFormats,
+		Connectivity:   connectivity.None{},
+	}
+	if settings.fetchMode == scanner.FetchBrowser {
+		a.cookies.Import(forumID, a.sessions.Jar(forumID).All())
+	}
+	cred, credErr := a.vault.Credential(forumID)
+	if credErr == nil {
+		opts.Reauthenticate = func() error {
+			return a.login(forumID, client, target, cred)
+		}
+	}
+	if settings.proxyType == proxy.TypeTor {
+		opts.Connectivity = connectivity.ForEndpoint(checker, lease.Endpoint.Name)
+		if cfg.TorControl != "" {
+			opts.NewIdentity = a.newTorIdentity
+		}
+	}
+
+	err := run(opts)
+	if err == nil && credErr == nil {
+		a.sessions.Save(forumID)
+	}
+	return err
+}
+
+// The analyst is asked to solve CAPTCHAs when the window or the REST API is
+// there to answer; otherwise, as in the CLI, the page fails at once
+func (a *App) captchaHandler() func(models.CaptchaChallenge) (models.CaptchaAnswer, error) {
+	if _, apiServer := a.servers(); a.ctx != nil || apiServer != nil {
+		return a.captchas.Ask
+	}
+	return func(models.CaptchaChallenge) (models.CaptchaAnswer, error) {
+		return models.CaptchaAnswer{}, captcha.ErrNoAnalyst
+	}
+}
+
+// Lease a proxy of the given type, other than the named endpoints, and the
+// forum's identity on it
+func (a *App) identity(forumID string, proxyType string, exclude ...string) (*proxy.Lease, *proxy.Identity, error) {
+	pool, isolator, _ := a.network()
+	lease, err := pool.Acquire(forumID, proxyType, exclude...)
+	if err != nil {
+		logger.Error("Could not acquire a proxy", "error", err, "forum_id", forumID, "type", proxyType)
+		return nil, nil, err
+	}
+	identity, err := isolator.Identity(forumID, lease.Endpoint)
+	if err != nil {
+		logger.Error("Could not create proxy identity", "error", err, "forum_id", forumID)
+		lease.Release(true)
+		return nil, nil, err
+	}
+	return lease, identity, nil
+}
+
+// The identity's client with the forum's session cookies. The transport is
+// shared, so the forum keeps its circuit.
+func (a *App) sessionClient(forumID string, identity *proxy.Identity) *http.Client {
+	client := *identity.Client
+	client.Jar = a.sessions.Jar(forumID)
+	return &client
+}
+
+// Log in to the forum and keep the session for both fetch modes
+func (a *App) login(forumID string, client *http.Client, pageURL string, cred vault.Credential) error {
+	if err := session.Login(client, pageURL, cred); err != nil {
+		logger.Error("Could not log in to forum", "error", err, "forum_id", forumID)
+		return err
+	}
+	a.cookies.Import(forumID, a.sessions.Jar(forumID).All())
+	return a.sessions.Save(forumID)
+}
+
+// How a forum is fetched
+type scanSettings struct {
+	proxyType      string
+	fetchMode      string
+	readiness      headless.Readiness
+	captureFormats []string
+}
+
+// Proxy type, fetch mode, page readiness and capture formats configured for the forum
+func (a *App) forumSettings(forumID string) scanSettings {
+	var proxyType, fetchMode, strategy, selector sql.NullString
+	var maxWait sql.NullInt64
+	var formats sql.NullString
+	err := a.db.QueryRow(`SELECT proxy_type, fetch_mode, ready_strategy, ready_selector, ready_max_wait, capture_formats FROM forums WHERE forum_id = ?`, forumID).
+		Scan(&proxyType, &fetchMode, &strategy, &selector, &maxWait, &formats)
+	if err != nil {
+		logger.Error("Could not read forum settings", "error", err, "forum_id", forumID)
+		return scanSettings{proxyType: proxy.TypeTor, fetchMode: scanner.FetchHTTP}
+	}
+	if proxyType.String == "" {
+		proxyType.String = proxy.TypeTor
+	}
+	if fetchMode.String == "" {
+		fetchMode.String = scanner.FetchHTTP
+	}
+	return scanSettings{
+		proxyType: proxyType.String,
+		fetchMode: fetchMode.String,
+		readiness: headless.Readiness{
+			Strategy: strategy.String,
+			Selector: selector.String,
+			MaxWait:  time.Duration(maxWait.Int64) * time.Second,
+		},
+		captureFormats: splitFormats(formats.String),
+	}
+}
+
+func splitFormats(formats string) []string {
+	var list []string
+	for _, format := range strings.Split(formats, ",") {
+		if format = strings.TrimSpace(format); format != "" {
+			list = append(list, format)
+		}
+	}
+	return list
+}
+
+func (a *App) torController() (*torcontrol.Controller, error) {
+	cfg := a.config()
+	return torcontrol.Connect(cfg.TorControl, torcontrol.Auth{
+		Password:   cfg.TorControlPassword,
+		CookiePath: cfg.TorControlCookie,
+	}, 5*time.Second)
+}
+
+func (a *App) newTorIdentity() error {
+	controller, err := a.torController()
+	if err != nil {
+		return err
+	}
+	defer controller.Close()
+	return controller.NewIdentity()
 }
 
 // Add forum
 func (a *App) CreateForum(forumData models.Forum) (string, error) {
+	_, message, err := a.addForum(forumData)
+	return message, err
+}
+
+// addForum stores a new forum and returns its ID with the message shown to
+// the analyst.
+func (a *App) addForum(forumData models.Forum) (string, string, error) {
 	if forumData.ForumName == "" || forumData.ForumURL == "" {
-		return "Error: Forum name and URL cannot be empty.", errors.New("forum name and URL cannot be empty")
+		return "", "Error: Forum name and URL cannot be empty.", invalid("forum name and URL cannot be empty")
+	}
+	if err := input.CheckURL(forumData.ForumURL); err != nil {
+		return "", "Error: Invalid forum URL.", invalid("invalid forum URL: %w", err)
+	}
+
+	proxyType := forumData.ProxyType
+	if proxyType == "" {
+		proxyType = proxy.TypeTor
+	}
+	if !proxy.ValidType(proxyType) {
+		return "", "Error: Unknown proxy type.", invalid("unknown proxy type %q", proxyType)
+	}
+	fetchMode := forumData.FetchMode
+	if fetchMode == "" {
+		fetchMode = scanner.FetchHTTP
+	}
+	if fetchMode != scanner.FetchHTTP && fetchMode != scanner.FetchBrowser {
+		return "", "Error: Unknown fetch mode.", invalid("unknown fetch mode %q", fetchMode)
+	}
+	engineHint := ""
+	if forumData.EngineHint != "" {
+		i := slices.IndexFunc(scanner.Engines, func(engine string) bool { return strings.EqualFold(engine, forumData.EngineHint) })
+		if i < 0 {
+			return "", "Error: Unknown engine.", invalid("unknown engine %q, known are %s", forumData.EngineHint, strings.Join(scanner.Engines, ", "))
+		}
+		engineHint = scanner.Engines[i]
+	}
+	if forumData.Schedule != "" {
+		every, err := time.ParseDuration(forumData.Schedule)
+		if err != nil || every < time.Minute {
+			return "", "Error: Invalid schedule.", invalid("schedule must be a duration of at least 1m, such as 6h: %q", forumData.Schedule)
+		}
+	}
+	var tags []string
+	for _, tag := range forumData.Tags {
+		if tag = strings.TrimSpace(strings.ReplaceAll(tag, ",", " ")); tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
 	}
 
 	forum_id := uuid.New().String()
-	statement, err := a.db.Prepare(`INSERT INTO forums (forum_id, forum_name, forum_url, forum_description, last_scaned) VALUES (?, ?, ?, ?, ?)`)
+	statement, err := a.db.Prepare(`INSERT INTO forums (forum_id, forum_name, forum_url, forum_description, last_scaned, proxy_type, fetch_mode, engine_hint, tags, schedule) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		logger.Error("Could not prepare the database statement", "error", err)
-		return "Error: Could not prepare the database statement", err
+		return "", "Error: Could not prepare the database statement", err
 	}
 	defer statement.Close()
 
-	_, err = statement.Exec(forum_id, forumData.ForumName, forumData.ForumURL, forumData.ForumDescription, "NULL")
+	_, err = statement.Exec(forum_id, forumData.ForumName, forumData.ForumURL, forumData.ForumDescription, "NULL", proxyType, fetchMode,
+		engineHint, strings.Join(tags, ","), forumData.Schedule)
 	if err != nil {
 		logger.Error("Could not insert forum into the database", "error", err)
-		return "Error: Could not insert forum into the database", err
+		return "", "Error: Could not insert forum into the database", err
 	}
 	logger.Info("Successfully added forum", "name", forumData.ForumName)
-	return fmt.Sprintf("Successfully added forum: %s", forumData.ForumName), nil
+	return forum_id, fmt.Sprintf("Successfully added forum: %s", forumData.ForumName), nil
+}
+
+// Add the forums of a targets file, the configured one when path is empty,
+// skipping invalid targets and forums already added
+func (a *App) ImportTargets(path string) (models.TargetImport, error) {
+	if path == "" {
+		path = a.config().TargetFile
+	}
+	targets, err := input.ReadTargets(path)
+	if err != nil {
+		logger.Error("Could not read targets file", "error", err, "path", path)
+		return models.TargetImport{}, err
+	}
+
+	report := models.TargetImport{File: path, Rows: []models.TargetRow{}}
+	known := make(map[string]string)
+	for _, forum := range a.GetForums() {
+		known[input.NormalizeURL(forum.ForumURL)] = forum.ForumID
+	}
+	for _, target := range targets {
+		row := models.TargetRow{Row: target.Row, Name: target.Name, URL: target.URL}
+		if row.Name == "" {
+			if parsed, err := url.Parse(target.URL); err == nil {
+				row.Name = parsed.Hostname()
+			}
+		}
+		key := input.NormalizeURL(target.URL)
+		if forumID, ok := known[key]; ok {
+			row.Status, row.ForumID = "duplicate", forumID
+		} else {
+			forumID, _, err := a.addForum(models.Forum{
+				ForumName:        row.Name,
+				ForumURL:         target.URL,
+				ForumDescription: target.Description,
+				EngineHint:       target.Engine,
+				Tags:             target.Tags,
+				Schedule:         target.Schedule,
+			})
+			var bad invalidError
+			switch {
+			case errors.As(err, &bad):
+				row.Status, row.Error = "invalid", err.Error()
+			case err != nil:
+				return report, err
+			default:
+				row.Status, row.ForumID = "added", forumID
+				known[key] = forumID
+			}
+		}
+		switch row.Status {
+		case "added":
+			report.Added++
+		case "duplicate":
+			report.Duplicates++
+		default:
+			report.Invalid++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	logger.Info("Imported targets", "path", path, "added", report.Added, "duplicates", report.Duplicates, "invalid", report.Invalid)
+	return report, nil
 }
 
 // Get Forum
 func (a *App) GetForums() []models.Forum {
-	rows, err := a.db.Query("SELECT forum_id, forum_url, forum_description, forum_name, last_scaned FROM forums")
+	rows, err := a.db.Query(`SELECT forum_id, forum_url, forum_description, forum_name, last_scaned, COALESCE(proxy_type, 'tor'), COALESCE(status, 'unknown'), COALESCE(fetch_mode, 'http'),
+		COALESCE(ready_strategy, 'network-idle'), COALESCE(ready_selector, ''), COALESCE(ready_max_wait, 25),
+		COALESCE(capture_formats, ''), COALESCE(forum_mhtml, ''), COALESCE(forum_pdf, ''), COALESCE(forum_engine, ''), COALESCE(engine_hint, ''),
+		COALESCE(tags, ''), COALESCE(schedule, '') FROM forums`)
 	if err != nil {
 		logger.Error("Could not prepare the database statement", "error", err)
 		return nil
@@ -80,11 +552,15 @@ func (a *App) GetForums() []models.Forum {
 	var forums []models.Forum
 	for rows.Next() {
 		var f models.Forum
-		err := rows.Scan(&f.ForumID, &f.ForumURL, &f.ForumDescription, &f.ForumName, &f.LastScaned)
+		var formats, tags string
+		err := rows.Scan(&f.ForumID, &f.ForumURL, &f.ForumDescription, &f.ForumName, &f.LastScaned, &f.ProxyType, &f.Status, &f.FetchMode,
+			&f.ReadyStrategy, &f.ReadySelector, &f.ReadyMaxWait, &formats, &f.ForumMHTML, &f.ForumPDF, &f.ForumEngine, &f.EngineHint, &tags, &f.Schedule)
 		if err != nil {
 			logger.Error("Could not scan the database rows", "error", err)
 			continue
 		}
+		f.CaptureFormats = splitFormats(formats)
+		f.Tags = splitFormats(tags)
 		forums = append(forums, f)
 	}
 	return forums
@@ -93,16 +569,7 @@ func (a *App) GetForums() []models.Forum {
 
 // Singular Forum Scrape
 func (a *App) SingularScrape(forum models.Forum) error {
-	err := scanner.Run(scanner.Options{
-		Targets:    []string{forum.ForumURL},
-		Client:     a.client,
-		Writer:     a.writer,
-		Timeout:    a.cfg.Timeout,
-		Retries:    a.cfg.MaxRetries,
-		TargetName: forum.ForumName,
-		Proxy:      a.cfg.TorProxy,
-		DB:         a.db,
-	})
+	err := a.scan(forum.ForumID, forum.ForumName, forum.ForumURL, scanner.Run)
 	if err != nil {
 		logger.Error("Could not scrape forum", "error", err)
 		return err
@@ -115,16 +582,7 @@ func (a *App) SingularScrape(forum models.Forum) error {
 func (a *App) MultipleScrape(forums []models.Forum) []models.Forum {
 	var errforums []models.Forum
 	for _, forum := range forums {
-		err := scanner.Run(scanner.Options{
-			Targets:    []string{forum.ForumURL},
-			Client:     a.client,
-			Writer:     a.writer,
-			Timeout:    a.cfg.Timeout,
-			Retries:    a.cfg.MaxRetries,
-			TargetName: forum.ForumName,
-			Proxy:      a.cfg.TorProxy,
-			DB:         a.db,
-		})
+		err := a.scan(forum.ForumID, forum.ForumName, forum.ForumURL, scanner.Run)
 		if err != nil {
 			logger.Error("Could not scrape forum", "error", err)
 			errforums = append(errforums, forum)
@@ -147,6 +605,56 @@ func (a *App) DeleteForum(forumID string) error {
 		return err
 	}
 
+	_, err = a.db.Exec(`DELETE FROM scan_runs WHERE forum_id = ?`, forumID)
+	if err != nil {
+		logger.Error("Could not delete associated scan runs from the database", "error", err)
+		return err
+	}
+
+	_, err = a.db.Exec(`DELETE FROM alerts WHERE forum_id = ?`, forumID)
+	if err != nil {
+		logger.Error("Could not delete associated alerts from the database", "error", err)
+		return err
+	}
+
+	_, err = a.db.Exec(`DELETE FROM indicator_matches WHERE forum_id = ?`, forumID)
+	if err != nil {
+		logger.Error("Could not delete associated indicator matches from the database", "error", err)
+		return err
+	}
+
+	_, err = a.db.Exec(`DELETE FROM change_events WHERE forum_id = ?`, forumID)
+	if err != nil {
+		logger.Error("Could not delete associated changes from the database", "error", err)
+		return err
+	}
+
+	_, err = a.db.Exec(`DELETE FROM forum_threads WHERE forum_id = ?`, forumID)
+	if err != nil {
+		logger.Error("Could not delete associated threads from the database", "error", err)
+		return err
+	}
+
+	// WARC files are append-only archives shared by every forum scanned on
+	// the same day; only the index entries go.
+	_, err = a.db.Exec(`DELETE FROM warc_records WHERE forum_id = ?`, forumID)
+	if err != nil {
+		logger.Error("Could not delete associated WARC records from the database", "error", err)
+		return err
+	}
+
+	// Snapshot files stay on disk: they are content-addressed and may be
+	// shared with other forums.
+	_, err = a.db.Exec(`DELETE FROM snapshots WHERE forum_id = ?`, forumID)
+	if err != nil {
+		logger.Error("Could not delete associated snapshots from the database", "error", err)
+		return err
+	}
+
+	if err := a.DeleteForumCredentials(forumID); err != nil {
+		return err
+	}
+
 	statement, err := a.db.Prepare(`DELETE FROM forums WHERE forum_id = ?`)
 	if err != nil {
 		logger.Error("Could not prepare the database statement", "error", err)
@@ -206,8 +714,17 @@ func (a *App) GetPosts(forumID string) ([]models.Post, error) {
 	return posts, nil
 }
 
+// Search scraped posts, a page of 25 hits at a time counted from 1
+func (a *App) SearchPosts(query string, filter models.SearchFilter, page int) (models.SearchResults, error) {
+	results, err := search.Search(a.db, query, filter, page)
+	if errors.Is(err, search.ErrQuery) {
+		return results, invalid("%w", err)
+	}
+	return results, err
+}
+
 func (a *App) ScanPosts(forumID string) error {
-	statement, err := a.db.Prepare(`SELECT p.post_id, p.thread_url, f.forum_name FROM posts p JOIN forums f ON p.forum_id = f.forum_id WHERE p.forum_id = ?`)
+	statement, err := a.db.Prepare(`SELECT p.post_id, p.forum_id, p.thread_url, f.forum_name FROM posts p JOIN forums f ON p.forum_id = f.forum_id WHERE p.forum_id = ?`)
 	if err != nil {
 		logger.Error("Could not prepare statement", "error", err)
 		return err
@@ -225,7 +742,7 @@ func (a *App) ScanPosts(forumID string) error {
 	for rows.Next() {
 		var job models.Job
 		var threadUrl sql.NullString
-		err := rows.Scan(&job.JobID, &threadUrl, &job.ForumName)
+		err := rows.Scan(&job.JobID, &job.ForumID, &threadUrl, &job.ForumName)
 		if err != nil {
 			logger.Error("Could not scan job row", "error", err)
 			continue
@@ -240,7 +757,7 @@ func (a *App) ScanPosts(forumID string) error {
 		return err
 	}
 
-	batchSize := a.cfg.Workers
+	batchSize := a.config().Workers
 	if batchSize <= 0 {
 		batchSize = 10
 	}
@@ -262,16 +779,7 @@ func (a *App) ScanPosts(forumID string) error {
 				defer batchWg.Done()
 
 				logger.Info("Processing job", "JobID", j.JobID)
-				err := scanner.RunPost(scanner.Options{
-					Targets:    []string{j.ThreadURL},
-					Client:     a.client,
-					Writer:     a.writer,
-					Timeout:    a.cfg.Timeout,
-					Retries:    a.cfg.MaxRetries,
-					TargetName: j.ForumName,
-					Proxy:      a.cfg.TorProxy,
-					DB:         a.db,
-				})
+				err := a.scan(j.ForumID, j.ForumName, j.ThreadURL, scanner.RunPost)
 
 				if err != nil {
 					logger.Error("Job failed", "id", j.JobID, "error", err, "Post URL", j.ThreadURL)
@@ -341,6 +849,424 @@ func (a *App) GetChartData(forumID string) ([]models.Chart, error) {
 	return chartData, nil
 }
 
+// Scan history of a forum, newest first
+func (a *App) GetScanHistory(forumID string) ([]models.ScanRun, error) {
+	return history.GetHistory(a.db, forumID, 100)
+}
+
+// Uptime and availability derived from the scan history
+func (a *App) GetForumHealth(forumID string) (models.ForumHealth, error) {
+	return history.GetHealth(a.db, forumID)
+}
+
+// Change the proxy type a forum is scraped through
+func (a *App) SetForumProxyType(forumID string, proxyType string) error {
+	if !proxy.ValidType(proxyType) {
+		return invalid("unknown proxy type %q", proxyType)
+	}
+	_, err := a.db.Exec(`UPDATE forums SET proxy_type = ? WHERE forum_id = ?`, proxyType, forumID)
+	if err != nil {
+		logger.Error("Could not update forum proxy type", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Change how long screenshots wait for a forum's pages to render
+func (a *App) SetForumReadiness(forumID string, strategy string, selector string, maxWait int) error {
+	readiness := headless.Readiness{Strategy: strategy, Selector: selector, MaxWait: time.Duration(maxWait) * time.Second}
+	if err := readiness.Validate(); err != nil {
+		return invalidError{err}
+	}
+	_, err := a.db.Exec(`UPDATE forums SET ready_strategy = ?, ready_selector = ?, ready_max_wait = ? WHERE forum_id = ?`, strategy, selector, maxWait, forumID)
+	if err != nil {
+		logger.Error("Could not update forum readiness", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Switch a forum between plain HTTP fetching and rendering in the browser
+func (a *App) SetForumFetchMode(forumID string, mode string) error {
+	if mode != scanner.FetchHTTP && mode != scanner.FetchBrowser {
+		return invalid("unknown fetch mode %q", mode)
+	}
+	_, err := a.db.Exec(`UPDATE forums SET fetch_mode = ? WHERE forum_id = ?`, mode, forumID)
+	if err != nil {
+		logger.Error("Could not update forum fetch mode", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Choose the extra formats captured with a forum's screenshot: mhtml, pdf
+func (a *App) SetForumCaptureFormats(forumID string, formats []string) error {
+	var list []string
+	for _, format := range formats {
+		if format != scanner.CaptureMHTML && format != scanner.CapturePDF {
+			return invalid("unknown capture format %q", format)
+		}
+		if !slices.Contains(list, format) {
+			list = append(list, format)
+		}
+	}
+	_, err := a.db.Exec(`UPDATE forums SET capture_formats = ? WHERE forum_id = ?`, strings.Join(list, ","), forumID)
+	if err != nil {
+		logger.Error("Could not update forum capture formats", "error", err)
+		return err
+	}
+	return nil
+}
+
+// Unlock the credential vault, creating it with this passphrase on first use
+func (a *App) UnlockVault(passphrase string) error {
+	return a.vault.Unlock(passphrase)
+}
+
+func (a *App) LockVault() {
+	a.vault.Lock()
+}
+
+func (a *App) IsVaultLocked() bool {
+	return a.vault.Locked()
+}
+
+// Store the login used to reach a forum's members-only sections
+func (a *App) SetForumCredentials(forumID string, username string, password string) error {
+	return a.vault.SetCredential(forumID, vault.Credential{Username: username, Password: password})
+}
+
+// Forget a forum's login and session
+func (a *App) DeleteForumCredentials(forumID string) error {
+	if err := a.vault.DeleteCredential(forumID); err != nil {
+		return err
+	}
+	return a.sessions.Clear(forumID)
+}
+
+// Log in to a forum now, e.g. to check newly stored credentials
+func (a *App) LoginForum(forumID string) error {
+	var forumURL string
+	err := a.db.QueryRow(`SELECT forum_url FROM forums WHERE forum_id = ?`, forumID).Scan(&forumURL)
+	if err != nil {
+		logger.Error("Could not find forum", "error", err, "forum_id", forumID)
+		return err
+	}
+	cred, err := a.vault.Credential(forumID)
+	if err != nil {
+		return err
+	}
+	settings := a.forumSettings(forumID)
+	lease, identity, err := a.identity(forumID, settings.proxyType)
+	if err != nil {
+		return err
+	}
+	err = a.login(forumID, a.sessionClient(forumID, identity), forumURL, cred)
+	lease.Release(false)
+	return err
+}
+
+// Seizure and ownership alerts, only the unacknowledged ones unless all is set
+func (a *App) GetAlerts(all bool) ([]models.Alert, error) {
+	return alerts.List(a.db, all)
+}
+
+func (a *App) AcknowledgeAlert(alertID string) error {
+	return alerts.Acknowledge(a.db, alertID)
+}
+
+// Past snapshots of a forum and its posts, newest first
+func (a *App) GetSnapshots(forumID string) ([]models.Snapshot, error) {
+	return snapshots.List(a.db, forumID, 500)
+}
+
+func (a *App) GetPostSnapshots(postID string) ([]models.Snapshot, error) {
+	return snapshots.ListPost(a.db, postID)
+}
+
+// Open the HTML of a past snapshot in the system browser
+func (a *App) OpenSnapshot(snapshotID string) error {
+	snapshot, err := snapshots.Get(a.db, snapshotID)
+	if err != nil {
+		logger.Error("Could not find snapshot", "error", err, "snapshot_id", snapshotID)
+		return err
+	}
+	return browser.OpenFile(snapshot.HTMLPath)
+}
+
+// Screenshot of a past snapshot as a data URL
+func (a *App) GetSnapshotScreenshot(snapshotID string) (string, error) {
+	snapshot, err := snapshots.Get(a.db, snapshotID)
+	if err != nil {
+		logger.Error("Could not find snapshot", "error", err, "snapshot_id", snapshotID)
+		return "", err
+	}
+	if snapshot.ScreenshotPath == "" {
+		return "", errors.New("snapshot has no screenshot")
+	}
+	data, err := os.ReadFile(snapshot.ScreenshotPath)
+	if err != nil {
+		logger.Error("Could not read snapshot screenshot", "error", err, "path", snapshot.ScreenshotPath)
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Changes detected on a forum and its threads since the given time, empty for all
+func (a *App) GetChanges(forumID string, since string) ([]models.ChangeEvent, error) {
+	return changes.List(a.db, forumID, since)
+}
+
+// Line diff between two snapshots of the same page
+func (a *App) GetSnapshotDiff(oldSnapshotID string, newSnapshotID string) (string, error) {
+	var texts []string
+	for _, id := range []string{oldSnapshotID, newSnapshotID} {
+		snapshot, err := snapshots.Get(a.db, id)
+		if err != nil {
+			logger.Error("Could not find snapshot", "error", err, "snapshot_id", id)
+			return "", err
+		}
+		body, err := os.ReadFile(snapshot.HTMLPath)
+		if err != nil {
+			logger.Error("Could not read snapshot HTML", "error", err, "path", snapshot.HTMLPath)
+			return "", err
+		}
+		texts = append(texts, changes.SnapshotText(snapshot, body))
+	}
+	return changes.Diff(texts[0], texts[1]), nil
+}
+
+// WARC records written for a forum and its posts
+func (a *App) GetWARCRecords(forumID string) ([]models.WARCRecord, error) {
+	return output.WARCRecords(a.db, forumID, 500)
+}
+
+// Replay a response stored in the WARC files
+func (a *App) ReplayWARCRecord(recordID string) (models.ReplayedResponse, error) {
+	record, err := output.WARCRecordByID(a.db, recordID)
+	if err != nil {
+		logger.Error("Could not find WARC record", "error", err, "record_id", recordID)
+		return models.ReplayedResponse{}, err
+	}
+	response, body, err := output.ReplayResponse(record.File, record.Offset)
+	if err != nil {
+		logger.Error("Could not replay WARC record", "error", err, "record_id", recordID, "file", record.File)
+		return models.ReplayedResponse{}, err
+	}
+	headers := make(map[string]string, len(response.Header))
+	for name := range response.Header {
+		headers[name] = strings.Join(response.Header.Values(name), ", ")
+	}
+	return models.ReplayedResponse{
+		RecordID:  record.RecordID,
+		TargetURI: record.TargetURI,
+		Date:      record.Date,
+		Status:    response.Status,
+		Headers:   headers,
+		Body:      string(body),
+	}, nil
+}
+
+// Fingerprint of the key manifests are signed with, to be handed to whoever
+// verifies them apart from the evidence
+func (a *App) CustodyFingerprint() string {
+	if a.custody == nil {
+		return ""
+	}
+	return a.custody.Fingerprint()
+}
+
+// Check the artifacts of a scan run against its signed manifest, which must
+// be signed with the key of the fingerprint given
+func (a *App) VerifyRun(runID string, fingerprint string) (*models.CustodyReport, error) {
+	outputDir := a.config().OutputDir
+	path := custody.ManifestPath(outputDir, runID)
+	key, err := custody.PinnedKey(path, fingerprint)
+	if err != nil {
+		logger.Error("Could not load the chain-of-custody public key", "error", err, "run_id", runID)
+		return nil, err
+	}
+	report, err := custody.Verify(path, key, outputDir)
+	if err != nil {
+		logger.Error("Could not verify manifest", "error", err, "run_id", runID)
+		return nil, err
+	}
+	return report, nil
+}
+
+// STIX 2.1 bundle of the forums, posts, authors and indicators the filter selects
+func (a *App) ExportSTIX(filter models.ExportFilter) (string, error) {
+	bundle, err := export.STIXJSON(a.db, filter)
+	if err != nil {
+		logger.Error("Could not export STIX bundle", "error", err)
+		return "", err
+	}
+	return bundle, nil
+}
+
+// MISP event JSON of the posts the filter selects, with their indicators, links and screenshots
+func (a *App) ExportMISP(filter models.ExportFilter) (string, error) {
+	event, err := export.MISPJSON(a.db, filter)
+	if err != nil {
+		logger.Error("Could not export MISP event", "error", err)
+		return "", err
+	}
+	return event, nil
+}
+
+// CSV of the posts the filter selects, one row per post with its indicators
+func (a *App) ExportCSV(filter models.ExportFilter) (string, error) {
+	data, err := export.CSV(a.db, filter)
+	if err != nil {
+		logger.Error("Could not export CSV", "error", err)
+		return "", err
+	}
+	return data, nil
+}
+
+// Assess the severity of the stored posts again, of one forum or all when
+// forumID is empty, and flag those mentioning watched indicators
+func (a *App) RescorePosts(forumID string) (int, error) {
+	query := `SELECT post_id, COALESCE(forum_id, ''), thread_url, content FROM posts
+		WHERE thread_url IS NOT NULL AND content IS NOT NULL AND content != ''`
+	var args []any
+	if forumID != "" {
+		query += ` AND forum_id = ?`
+		args = append(args, forumID)
+	}
+	rows, err := a.db.Query(query, args...)
+	if err != nil {
+		logger.Error("Could not query posts to rescore", "error", err)
+		return 0, err
+	}
+	type stored struct{ postID, forumID, threadURL, content string }
+	var posts []stored
+	for rows.Next() {
+		var post stored
+		if err := rows.Scan(&post.postID, &post.forumID, &post.threadURL, &post.content); err != nil {
+			logger.Error("Could not scan post row", "error", err)
+			continue
+		}
+		posts = append(posts, post)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger.Error("Error during rows iteration", "error", err)
+		return 0, err
+	}
+
+	rescored := 0
+	for _, post := range posts {
+		// A post that cannot be assessed keeps its rating.
+		level, err := severity.Assess(strings.NewReader(post.content))
+		if err != nil {
+			logger.Error("Failed to assess severity", "error", err, "thread_url", post.threadURL)
+			continue
+		}
+		if level == "" {
+			level = "unassigned"
+		}
+		if _, err := a.db.Exec(`UPDATE posts SET severity_level = ? WHERE post_id = ?`, level, post.postID); err != nil {
+			logger.Error("Could not update severity level", "error", err, "thread_url", post.threadURL)
+			return rescored, err
+		}
+		rescored++
+		matches, err := watchlist.Check(a.db, post.forumID, post.postID, post.threadURL, []byte(post.content))
+		if err != nil {
+			return rescored, err
+		}
+		if len(matches) > 0 {
+			values := make([]string, 0, len(matches))
+			for _, match := range matches {
+				values = append(values, match.Value)
+			}
+			message := fmt.Sprintf("Thread %s mentions watched indicators: %s", post.threadURL, strings.Join(values, ", "))
+			if alert, err := alerts.Raise(a.db, post.forumID, alerts.KindIndicator, message); err == nil {
+				a.emit("alert:new", alert)
+			}
+		}
+	}
+	logger.Info("Rescored posts", "count", rescored, "forum_id", forumID)
+	return rescored, nil
+}
+
+// Import the indicators of a MISP event file; scraped posts containing them are flagged
+func (a *App) ImportMISP(path string) (models.MISPImport, error) {
+	return watchlist.ImportMISP(a.db, path)
+}
+
+// Indicators imported from MISP
+func (a *App) GetWatchIndicators() ([]models.WatchIndicator, error) {
+	return watchlist.List(a.db)
+}
+
+// Remove an imported indicator
+func (a *App) DeleteWatchIndicator(indicatorID string) error {
+	return watchlist.Delete(a.db, indicatorID)
+}
+
+// Posts flagged for containing imported indicators, of one forum or all when forumID is empty
+func (a *App) GetIndicatorMatches(forumID string) ([]models.IndicatorMatch, error) {
+	return watchlist.Matches(a.db, forumID)
+}
+
+// CAPTCHAs waiting for the analyst, e.g. after the window was reloaded
+func (a *App) GetPendingCaptchas() []models.CaptchaChallenge {
+	return a.captchas.Pending()
+}
+
+// Answer a CAPTCHA and resume the paused scan
+func (a *App) SubmitCaptcha(id string, answer models.CaptchaAnswer) error {
+	return a.captchas.Answer(id, answer)
+}
+
+// Give up on a CAPTCHA; the paused scan fails
+func (a *App) CancelCaptcha(id string) error {
+	return a.captchas.Cancel(id)
+}
+
+// Health of every proxy in the pool
+func (a *App) GetProxyStatus() []models.ProxyStatus {
+	pool, _, _ := a.network()
+	return pool.Status()
+}
+
+// Tor bootstrap state from the control port
+func (a *App) GetTorStatus() (models.TorBootstrap, error) {
+	var status models.TorBootstrap
+	if a.config().TorControl == "" {
+		return status, errors.New("tor control port is not configured")
+	}
+	controller, err := a.torController()
+	if err != nil {
+		logger.Error("Could not connect to the Tor control port", "error", err)
+		return status, err
+	}
+	defer controller.Close()
+
+	bootstrap, err := controller.Bootstrap()
+	if err != nil {
+		logger.Error("Could not read Tor bootstrap phase", "error", err)
+		return status, err
+	}
+	status.Progress = bootstrap.Progress
+	status.Tag = bootstrap.Tag
+	status.Summary = bootstrap.Summary
+	status.Warning = bootstrap.Warning
+
+	circuits, err := controller.Circuits()
+	if err != nil {
+		logger.Error("Could not read Tor circuit status", "error", err)
+		return status, err
+	}
+	for _, circuit := range circuits {
+		if circuit.Status == "BUILT" {
+			status.CircuitsBuilt++
+		}
+	}
+	return status, nil
+}
+
 func (a *App) OpenHTMLInBrowser(PostContent string) error {
 	tmpFile := filepath.Join(os.TempDir(), ".html")
 	err := os.WriteFile(tmpFile, []byte(PostContent), 0644)
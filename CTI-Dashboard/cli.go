@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"CTI-Dashboard/models"
+)
+
+// cliUsage is printed by cti help.
+const cliUsage = `usage: cti <command> [flags] [arguments]
+
+commands:
+  forum add      add a forum
+  forum list     list the forums
+  forum delete   delete a forum and everything scraped from it
+  scrape         scrape the page of a forum
+  extract        collect the threads a scraped forum page lists
+  scan-posts     scrape the collected threads of a forum
+  rescore        assess the severity of stored posts again
+  export         export posts as STIX, CSV or MISP
+  import         add the forums of a targets file, YAML, CSV or one URL per line
+
+Forums are given by ID or name. Run cti <command> -h for the flags of a command.
+`
+
+// errUsage is returned for bad arguments; the usage has been printed.
+var errUsage = errors.New("usage")
+
+type cliCommand func(app *App, args []string) error
+
+var cliCommands = map[string]cliCommand{
+	"forum":      cliForum,
+	"scrape":     cliScrape,
+	"extract":    cliExtract,
+	"scan-posts": cliScanPosts,
+	"rescore":    cliRescore,
+	"export":     cliExport,
+	"import":     cliImport,
+}
+
+// isCommand reports whether the arguments start with a cti command.
+func isCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	_, ok := cliCommands[args[0]]
+	return ok || args[0] == "help"
+}
+
+// runCLI runs a cti command and returns the exit status: 1 if it failed and
+// 2 for bad arguments. The dashboard binary doubles as the cti command-line
+// interface: given a command it runs it on the same database and exits
+// instead of opening the window, so cron jobs and pipelines can drive
+// collection.
+//
+//	cti forum add -name <name> -url <url> [-description <text>] [-proxy tor] [-fetch http] [-engine XenForo] [-tags a,b] [-schedule 6h]
+//	cti forum list
+//	cti forum delete <forum>
+//	cti scrape <forum>
+//	cti extract <forum>
+//	cti scan-posts <forum>
+//	cti rescore [<forum>]
+//	cti export -format stix|csv|misp [-forum <id>,...] [-post <id>,...] [-severity high,...] [-since <time>] [-tlp amber] [-o <file>]
+//	cti import [<targets file>]
+//
+// Forums are given by ID or name. Every command takes -json to print JSON
+// instead of a table. Log lines go to stderr.
+func runCLI(app *App, args []string) int {
+	log.SetOutput(os.Stderr)
+	if args[0] == "help" {
+		fmt.Print(cliUsage)
+		return 0
+	}
+	err := cliCommands[args[0]](app, args[1:])
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "cti:", err)
+		return 1
+	}
+	return 0
+}
+
+// flags returns the flag set of a command with its -json flag.
+func flags(name string, usage string) (*flag.FlagSet, *bool) {
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	asJSON := set.Bool("json", false, "print JSON instead of a table")
+	set.Usage = func() {
+		fmt.Fprintf(set.Output(), "usage: cti %s\n", usage)
+		set.PrintDefaults()
+	}
+	return set, asJSON
+}
+
+// parse parses the flags of a command and checks the number of arguments
+// left.
+func parse(set *flag.FlagSet, args []string, minArgs int, maxArgs int) error {
+	if err := set.Parse(args); err != nil {
+		return errUsage
+	}
+	if set.NArg() < minArgs || set.NArg() > maxArgs {
+		set.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func cliForum(app *App, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, "usage: cti forum add|list|delete [flags]\n")
+		return errUsage
+	}
+	switch args[0] {
+	case "add":
+		set, asJSON := flags("forum add", "forum add -name <name> -url <url> [flags]")
+		var forum models.Forum
+		set.StringVar(&forum.ForumName, "name", "", "name of the forum")
+		set.StringVar(&forum.ForumURL, "url", "", "URL of the forum page listing the threads")
+		set.StringVar(&forum.ForumDescription, "description", "", "description of the forum")
+		set.StringVar(&forum.ProxyType, "proxy", "", "proxy type: tor, i2p, socks5, http or direct (default tor)")
+		set.StringVar(&forum.FetchMode, "fetch", "", "fetch mode: http or browser (default http)")
+		set.StringVar(&forum.EngineHint, "engine", "", "forum engine to assume until a scrape identifies it: XenForo or phpBB")
+		tags := set.String("tags", "", "comma-separated tags")
+		set.StringVar(&forum.Schedule, "schedule", "", "how often to scrape, as a duration such as 6h")
+		if err := parse(set, args[1:], 0, 0); err != nil {
+			return err
+		}
+		forum.Tags = split(*tags)
+		forumID, _, err := app.addForum(forum)
+		if err != nil {
+			return err
+		}
+		created, err := apiBackend{app}.Forum(forumID)
+		if err != nil {
+			return err
+		}
+		return printForums(*asJSON, []models.Forum{created})
+
+	case "list":
+		set, asJSON := flags("forum list", "forum list [-json]")
+		if err := parse(set, args[1:], 0, 0); err != nil {
+			return err
+		}
+		return printForums(*asJSON, app.GetForums())
+
+	case "delete":
+		set, asJSON := flags("forum delete", "forum delete [-json] <forum>")
+		if err := parse(set, args[1:], 1, 1); err != nil {
+			return err
+		}
+		forum, err := findForum(app, set.Arg(0))
+		if err != nil {
+			return err
+		}
+		if err := app.DeleteForum(forum.ForumID); err != nil {
+			return err
+		}
+		return printResult(*asJSON, map[string]any{"deleted": forum.ForumID})
+	}
+	fmt.Fprintf(os.Stderr, "cti forum: unknown command %q\n", args[0])
+	return errUsage
+}
+
+func cliScrape(app *App, args []string) error {
+	set, asJSON := flags("scrape", "scrape [-json] <forum>")
+	if err := parse(set, args, 1, 1); err != nil {
+		return err
+	}
+	forum, err := findForum(app, set.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := app.SingularScrape(forum); err != nil {
+		return err
+	}
+	scraped, err := apiBackend{app}.Forum(forum.ForumID)
+	if err != nil {
+		return err
+	}
+	return printForums(*asJSON, []models.Forum{scraped})
+}
+
+func cliExtract(app *App, args []string) error {
+	set, asJSON := flags("extract", "extract [-json] <forum>")
+	if err := parse(set, args, 1, 1); err != nil {
+		return err
+	}
+	forum, err := findForum(app, set.Arg(0))
+	if err != nil {
+		return err
+	}
+	count, err := app.Extract_posts(forum.ForumID)
+	if err != nil {
+		return err
+	}
+	return printResult(*asJSON, map[string]any{"forum_id": forum.ForumID, "posts": count})
+}
+
+func cliScanPosts(app *App, args []string) error {
+	set, asJSON := flags("scan-posts", "scan-posts [-json] <forum>")
+	if err := parse(set, args, 1, 1); err != nil {
+		return err
+	}
+	forum, err := findForum(app, set.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := app.ScanPosts(forum.ForumID); err != nil {
+		return err
+	}
+	posts, err := app.GetPosts(forum.ForumID)
+	if err != nil {
+		return err
+	}
+	statuses := make(map[string]int)
+	for _, post := range posts {
+		statuses[post.Status]++
+	}
+	return printResult(*asJSON, map[string]any{"forum_id": forum.ForumID, "posts": len(posts), "status": statuses})
+}
+
+func cliRescore(app *App, args []string) error {
+	set, asJSON := flags("rescore", "rescore [-json] [<forum>]")
+	if err := parse(set, args, 0, 1); err != nil {
+		return err
+	}
+	forumID := ""
+	if set.NArg() == 1 {
+		forum, err := findForum(app, set.Arg(0))
+		if err != nil {
+			return err
+		}
+		forumID = forum.ForumID
+	}
+	count, err := app.RescorePosts(forumID)
+	if err != nil {
+		return err
+	}
+	return printResult(*asJSON, map[string]any{"forum_id": forumID, "posts": count})
+}
+
+func cliExport(app *App, args []string) error {
+	set := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := set.String("format", "stix", "export format: stix, csv or misp")
+	forums := set.String("forum", "", "comma-separated forum IDs or names, default all")
+	posts := set.String("post", "", "comma-separated post IDs, default all")
+	severities := set.String("severity", "", "comma-separated severities, default all")
+	since := set.String("since", "", "only posts added since this date or RFC 3339 time")
+	tlp := set.String("tlp", "", "TLP marking of STIX and MISP exports (default amber)")
+	out := set.String("o", "", "file to write, default stdout")
+	set.Usage = func() {
+		fmt.Fprintln(set.Output(), "usage: cti export -format stix|csv|misp [flags]")
+		set.PrintDefaults()
+	}
+	if err := parse(set, args, 0, 0); err != nil {
+		return err
+	}
+
+	filter := models.ExportFilter{PostIDs: split(*posts), Severities: split(*severities), Since: *since, TLP: *tlp}
+	for _, name := range split(*forums) {
+		forum, err := findForum(app, name)
+		if err != nil {
+			return err
+		}
+		filter.ForumIDs = append(filter.ForumIDs, forum.ForumID)
+	}
+
+	var data string
+	var err error
+	switch *format {
+	case "stix":
+		data, err = app.ExportSTIX(filter)
+	case "csv":
+		data, err = app.ExportCSV(filter)
+	case "misp":
+		data, err = app.ExportMISP(filter)
+	default:
+		set.Usage()
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+	if !strings.HasSuffix(data, "\n") {
+		data += "\n"
+	}
+	if *out == "" {
+		_, err = io.WriteString(os.Stdout, data)
+		return err
+	}
+	return os.WriteFile(*out, []byte(data), 0644)
+}
+
+func cliImport(app *App, args []string) error {
+	set, asJSON := flags("import", "import [-json] [<targets file>]")
+	if err := parse(set, args, 0, 1); err != nil {
+		return err
+	}
+	report, err := app.ImportTargets(set.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		err = printJSON(report)
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ROW\tSTATUS\tNAME\tURL\tFORUM ID\tERROR")
+		for _, row := range report.Rows {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", row.Row, row.Status, row.Name, row.URL, row.ForumID, row.Error)
+		}
+		err = w.Flush()
+	}
+	if err == nil && report.Invalid > 0 {
+		err = fmt.Errorf("%d of %d targets are invalid", report.Invalid, len(report.Rows))
+	}
+	return err
+}
+
+// findForum looks a forum up by ID, or else by name.
+func findForum(app *App, forum string) (models.Forum, error) {
+	var byName []models.Forum
+	for _, f := range app.GetForums() {
+		if f.ForumID == forum {
+			return f, nil
+		}
+		if strings.EqualFold(f.ForumName, forum) {
+			byName = append(byName, f)
+		}
+	}
+	switch len(byName) {
+	case 0:
+		return models.Forum{}, fmt.Errorf("no forum %q", forum)
+	case 1:
+		return byName[0], nil
+	}
+	return models.Forum{}, fmt.Errorf("%d forums are named %q, give the ID", len(byName), forum)
+}
+
+func printForums(asJSON bool, forums []models.Forum) error {
+	if asJSON {
+		return printJSON(forums)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATUS\tPROXY\tFETCH\tLAST SCANNED\tURL")
+	for _, f := range forums {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", f.ForumID, f.ForumName, f.Status, f.ProxyType, f.FetchMode, f.LastScaned, f.ForumURL)
+	}
+	return w.Flush()
+}
+
+// printResult prints the fields of a command's result, one per line.
+func printResult(asJSON bool, result map[string]any) error {
+	if asJSON {
+		return printJSON(result)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, key := range slices.Sorted(maps.Keys(result)) {
+		fmt.Fprintf(w, "%s\t%v\n", key, result[key])
+	}
+	return w.Flush()
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// split splits a comma-separated flag value.
+func split(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,98 @@
+// Command cti-verify checks captured evidence against its signed
+// chain-of-custody manifests.
+//
+//	cti-verify -pub key.pub [-dir output/] [-json] <run ID or manifest path>...
+//
+// The public key must come from the analyst who collected the evidence, not
+// from the output directory: whoever could alter the evidence could replace
+// the key stored with it. Compare its fingerprint, printed with every
+// report, with the one the analyst gives.
+//
+// It exits with status 1 if any signature or artifact does not check out.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/custody"
+)
+
+func main() {
+	dir := flag.String("dir", "output/", "output directory the artifacts were written to")
+	pub := flag.String("pub", "", "Ed25519 public key (PEM) the manifests must be signed with, obtained apart from the evidence (required)")
+	asJSON := flag.Bool("json", false, "print the reports as JSON")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s -pub key.pub [flags] <run ID or manifest path>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || *pub == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	key, err := custody.LoadPublicKey(*pub)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cti-verify:", err)
+		os.Exit(2)
+	}
+
+	ok := true
+	var reports []*models.CustodyReport
+	for _, arg := range flag.Args() {
+		path := arg
+		if !strings.HasSuffix(arg, ".json") {
+			path = custody.ManifestPath(*dir, arg)
+		}
+		report, err := custody.Verify(path, key, *dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cti-verify: %s: %v\n", arg, err)
+			ok = false
+			continue
+		}
+		ok = ok && report.OK
+		reports = append(reports, report)
+		if !*asJSON {
+			printReport(report)
+		}
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(reports)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func printReport(report *models.CustodyReport) {
+	fmt.Printf("%s\n  run %s, created %s by tool version %s\n", report.Manifest, report.RunID, report.CreatedAt, report.ToolVersion)
+	fmt.Printf("  key %s\n", report.Fingerprint)
+	switch {
+	case !report.SignatureValid:
+		fmt.Println("  signature: INVALID")
+	case !report.KeyMatches:
+		fmt.Println("  signature: valid, but the manifest names a different key")
+	default:
+		fmt.Println("  signature: valid")
+	}
+	for _, check := range report.Checks {
+		fmt.Printf("  %-8s %-11s %s\n", check.Status, check.Kind, check.Path)
+		if check.Actual != "" {
+			fmt.Printf("           expected %s, got %s\n", check.Expected, check.Actual)
+		}
+		if check.Error != "" {
+			fmt.Printf("           %s\n", check.Error)
+		}
+	}
+	if report.OK {
+		fmt.Println("  OK")
+	} else {
+		fmt.Println("  FAILED")
+	}
+}
@@ -0,0 +1,185 @@
+--Forums Table
+CREATE TABLE IF NOT EXISTS forums (
+    forum_id TEXT PRIMARY KEY,
+    forum_name TEXT NOT NULL,
+    forum_url TEXT NOT NULL,
+    forum_html TEXT,
+    forum_description TEXT,
+    forum_screenshot TEXT,
+    forum_mhtml TEXT, -- set when the forum captures MHTML
+    forum_pdf TEXT, -- set when the forum captures PDF
+    last_scaned DATETIME,
+    forum_engine TEXT,
+    engine_hint TEXT, -- engine named when the forum was imported, used until a scrape identifies one
+    status TEXT DEFAULT 'unknown', -- unknown, ok, seized, blocked, challenge, login_wall, down
+    page_title TEXT, -- title of the last good snapshot, to notice a change of owner
+    proxy_type TEXT DEFAULT 'tor', -- tor, i2p, socks5, http, direct
+    fetch_mode TEXT DEFAULT 'http', -- http, browser
+    ready_strategy TEXT DEFAULT 'network-idle', -- network-idle, selector, max-wait
+    ready_selector TEXT,
+    ready_max_wait INTEGER DEFAULT 25, -- seconds
+    capture_formats TEXT DEFAULT '', -- comma-separated extra captures: mhtml, pdf
+    tags TEXT DEFAULT '', -- comma-separated
+    schedule TEXT DEFAULT '', -- how often to scrape, as a duration such as 6h
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+
+CREATE TABLE IF NOT EXISTS posts (
+    post_id TEXT PRIMARY KEY,
+    forum_id TEXT,
+    thread_url TEXT UNIQUE,
+    status TEXT DEFAULT 'pending', -- pending, scraped, analyzed, failed
+    severity_level TEXT DEFAULT 'unassigned', -- unassigned, low, medium, high
+    title TEXT,
+    content TEXT,
+    author TEXT,
+    date TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY(forum_id) REFERENCES forums(forum_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS scan_runs (
+    run_id TEXT PRIMARY KEY,
+    forum_id TEXT,
+    target_url TEXT NOT NULL,
+    kind TEXT DEFAULT 'forum', -- forum, post
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    duration_ms INTEGER DEFAULT 0,
+    outcome TEXT DEFAULT 'failed', -- success, failed
+    http_status INTEGER DEFAULT 0,
+    bytes INTEGER DEFAULT 0,
+    error_class TEXT,
+    error TEXT,
+    exit_ip TEXT,
+    page_class TEXT, -- ok, seized, blocked, challenge, login_wall, down
+    attempts TEXT, -- JSON log of every attempt, with redirects and retry decisions
+    FOREIGN KEY(forum_id) REFERENCES forums(forum_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_scan_runs_forum ON scan_runs(forum_id, started_at);
+
+CREATE TABLE IF NOT EXISTS vault (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    salt BLOB NOT NULL,
+    verifier BLOB NOT NULL -- known text sealed with the key, checked on unlock
+);
+
+CREATE TABLE IF NOT EXISTS forum_credentials (
+    forum_id TEXT PRIMARY KEY,
+    secret BLOB NOT NULL, -- username and password sealed with the vault key
+    updated_at DATETIME,
+    FOREIGN KEY(forum_id) REFERENCES forums(forum_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS forum_sessions (
+    forum_id TEXT PRIMARY KEY,
+    cookies BLOB NOT NULL, -- cookie jar sealed with the vault key
+    updated_at DATETIME,
+    FOREIGN KEY(forum_id) REFERENCES forums(forum_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    alert_id TEXT PRIMARY KEY,
+    forum_id TEXT,
+    kind TEXT NOT NULL, -- seizure, ownership_change, thread_deleted, indicator_match
+    message TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    acknowledged INTEGER DEFAULT 0,
+    FOREIGN KEY(forum_id) REFERENCES forums(forum_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS snapshots (
+    snapshot_id TEXT PRIMARY KEY,
+    forum_id TEXT,
+    post_id TEXT, -- set for post snapshots
+    target_url TEXT NOT NULL,
+    kind TEXT DEFAULT 'forum', -- forum, post
+    run_id TEXT,
+    fetched_at DATETIME NOT NULL,
+    html_sha256 TEXT NOT NULL,
+    html_path TEXT NOT NULL, -- content-addressed, shared by identical snapshots
+    html_size INTEGER DEFAULT 0,
+    screenshot_sha256 TEXT,
+    screenshot_path TEXT,
+    screenshot_size INTEGER DEFAULT 0,
+    mhtml_sha256 TEXT,
+    mhtml_path TEXT,
+    pdf_sha256 TEXT,
+    pdf_path TEXT,
+    FOREIGN KEY(forum_id) REFERENCES forums(forum_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_snapshots_forum ON snapshots(forum_id, fetched_at);
+CREATE INDEX IF NOT EXISTS idx_snapshots_post ON snapshots(post_id, fetched_at);
+
+-- Threads listed on each forum page, kept to tell what changed between scrapes.
+CREATE TABLE IF NOT EXISTS forum_threads (
+    forum_id TEXT NOT NULL,
+    thread_url TEXT NOT NULL,
+    title TEXT,
+    first_seen DATETIME NOT NULL,
+    last_seen DATETIME NOT NULL,
+    listed INTEGER DEFAULT 1, -- 0 once the thread dropped off the forum page
+    PRIMARY KEY (forum_id, thread_url),
+    FOREIGN KEY(forum_id) REFERENCES forums(forum_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS change_events (
+    event_id TEXT PRIMARY KEY,
+    forum_id TEXT,
+    post_id TEXT,
+    kind TEXT NOT NULL, -- thread_added, thread_renamed, thread_unlisted, post_edited, post_deleted, post_restored
+    target_url TEXT NOT NULL,
+    detail TEXT, -- old and new title, line diff of the first post, or why the thread is gone
+    snapshot_id TEXT,
+    previous_snapshot_id TEXT,
+    detected_at DATETIME NOT NULL,
+    FOREIGN KEY(forum_id) REFERENCES forums(forum_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_change_events_forum ON change_events(forum_id, detected_at);
+
+-- Where each WARC record was written, for replaying stored responses.
+CREATE TABLE IF NOT EXISTS warc_records (
+    record_id TEXT PRIMARY KEY, -- WARC-Record-ID
+    forum_id TEXT,
+    post_id TEXT,
+    run_id TEXT,
+    record_type TEXT NOT NULL, -- request, response, metadata, resource
+    target_uri TEXT NOT NULL,
+    warc_date TEXT NOT NULL,
+    content_type TEXT,
+    payload_digest TEXT,
+    warc_file TEXT NOT NULL,
+    record_offset INTEGER NOT NULL, -- start of the record's gzip member
+    record_length INTEGER NOT NULL,
+    FOREIGN KEY(forum_id) REFERENCES forums(forum_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_warc_records_forum ON warc_records(forum_id, warc_date);
+CREATE INDEX IF NOT EXISTS idx_warc_records_uri ON warc_records(target_uri, warc_date);
+
+-- Indicators imported from MISP events; posts containing one are flagged.
+CREATE TABLE IF NOT EXISTS watch_indicators (
+    indicator_id TEXT PRIMARY KEY,
+    type TEXT NOT NULL, -- url, domain, ipv4, md5, sha1, sha256
+    value TEXT NOT NULL,
+    event_uuid TEXT,
+    event_info TEXT,
+    source TEXT, -- file the indicator was imported from
+    imported_at DATETIME NOT NULL,
+    UNIQUE (type, value)
+);
+
+CREATE TABLE IF NOT EXISTS indicator_matches (
+    post_id TEXT NOT NULL,
+    indicator_id TEXT NOT NULL,
+    forum_id TEXT,
+    thread_url TEXT NOT NULL,
+    matched_at DATETIME NOT NULL,
+    PRIMARY KEY (post_id, indicator_id),
+    FOREIGN KEY(forum_id) REFERENCES forums(forum_id) ON DELETE CASCADE,
+    FOREIGN KEY(indicator_id) REFERENCES watch_indicators(indicator_id) ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+-- Text read from each scraped thread page, for full-text search: the opening
+-- post and the replies after it. The FTS5 index over them is set up by the
+-- search package, since the SQLite driver only has FTS5 when built with
+-- -tags sqlite_fts5.
+ALTER TABLE posts ADD COLUMN body_text TEXT DEFAULT '';
+ALTER TABLE posts ADD COLUMN replies TEXT DEFAULT '';
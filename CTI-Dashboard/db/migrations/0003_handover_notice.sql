@@ -0,0 +1,3 @@
+-- The handover phrase last seen on a forum's page, so the ownership alert it
+-- raises is raised once rather than on every scrape.
+ALTER TABLE forums ADD COLUMN handover_notice TEXT DEFAULT '';
@@ -0,0 +1,11 @@
+-- When what an export says about a post last changed: its title, author or
+-- text on a scrape, or its severity on a rescore. STIX takes it as the
+-- version of the post's report.
+ALTER TABLE posts ADD COLUMN modified_at DATETIME;
+
+CREATE TRIGGER IF NOT EXISTS posts_modified AFTER UPDATE OF title, author, body_text, replies, severity_level ON posts
+WHEN old.title IS NOT new.title OR old.author IS NOT new.author OR old.body_text IS NOT new.body_text
+	OR old.replies IS NOT new.replies OR old.severity_level IS NOT new.severity_level
+BEGIN
+	UPDATE posts SET modified_at = strftime('%Y-%m-%d %H:%M:%f', 'now') WHERE post_id = new.post_id;
+END;
@@ -0,0 +1,244 @@
+// Package migrations keeps the database schema up to date. Each embedded
+// file NNNN_name.sql is a migration, applied once and in order of its version
+// number, and recorded in the schema_migrations table.
+//
+// A change to the schema goes in a new file; files already released are
+// never edited.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"CTI-Dashboard/scraper/logger"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// ErrNewerSchema is returned for a database migrated by a newer build.
+var ErrNewerSchema = errors.New("database schema is newer than this build")
+
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// All returns the embedded migrations in order.
+func All() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+	var migrations []Migration
+	for _, entry := range entries {
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s: name must be NNNN_name.sql", entry.Name())
+		}
+		data, err := files.ReadFile(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(data)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migration version %d is used twice", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// Apply runs the migrations the database has not had yet, each in its own
+// transaction, and returns the version it is at. It refuses a database at a
+// version this build does not know.
+func Apply(db *sql.DB) (int, error) {
+	migrations, err := All()
+	if err != nil {
+		return 0, err
+	}
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+
+	// Databases created from db/schema.sql before there were migrations
+	// have tables but no schema_migrations.
+	var tracked, legacy int
+	err = db.QueryRow(`SELECT
+		(SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'),
+		(SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'forums')`).Scan(&tracked, &legacy)
+	if err != nil {
+		logger.Error("Could not read the database schema", "error", err)
+		return 0, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		logger.Error("Could not create schema_migrations", "error", err)
+		return 0, err
+	}
+
+	current, err := version(db)
+	if err != nil {
+		return 0, err
+	}
+	if current > latest {
+		logger.Error("Database schema is newer than this build", "version", current, "latest", latest)
+		return current, fmt.Errorf("%w: at version %d, this build knows up to %d", ErrNewerSchema, current, latest)
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= current {
+			continue
+		}
+		if err := apply(db, migration, tracked == 0 && legacy > 0); err != nil {
+			logger.Error("Could not apply database migration", "error", err, "version", migration.Version, "name", migration.Name)
+			return current, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		current = migration.Version
+		logger.Info("Applied database migration", "version", migration.Version, "name", migration.Name)
+	}
+	return current, nil
+}
+
+func version(db *sql.DB) (int, error) {
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		logger.Error("Could not read the schema version", "error", err)
+		return 0, err
+	}
+	return current, nil
+}
+
+func apply(db *sql.DB, migration Migration, legacy bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Another process may have applied it since the version was read.
+	var done int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, migration.Version).Scan(&done); err != nil {
+		return err
+	}
+	if done > 0 {
+		return nil
+	}
+	if legacy && migration.Version == 1 {
+		if err := adopt(tx, migration.SQL); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(migration.SQL); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, migration.Version, migration.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// adopt brings the tables of a database created from an older db/schema.sql
+// up to the first migration by adding the columns they lack. The columns
+// expected are read from the schema built in a scratch database.
+func adopt(tx *sql.Tx, schema string) error {
+	scratch, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return err
+	}
+	defer scratch.Close()
+	// Every connection gets its own in-memory database.
+	scratch.SetMaxOpenConns(1)
+	if _, err := scratch.Exec(schema); err != nil {
+		return err
+	}
+
+	tables, err := names(scratch.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`))
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		existing, err := names(tx.Query(`SELECT name FROM pragma_table_info(?)`, table))
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			continue // created by the migration itself
+		}
+		have := make(map[string]bool, len(existing))
+		for _, column := range existing {
+			have[column] = true
+		}
+
+		rows, err := scratch.Query(`SELECT name, type, "notnull", dflt_value FROM pragma_table_info(?) ORDER BY cid`, table)
+		if err != nil {
+			return err
+		}
+		var additions []string
+		for rows.Next() {
+			var name, kind string
+			var notNull bool
+			var def sql.NullString
+			if err := rows.Scan(&name, &kind, &notNull, &def); err != nil {
+				rows.Close()
+				return err
+			}
+			if have[name] {
+				continue
+			}
+			column := fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s`, table, name, kind)
+			if notNull {
+				column += " NOT NULL"
+			}
+			if def.Valid {
+				column += " DEFAULT " + def.String
+			}
+			additions = append(additions, column)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, statement := range additions {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+			logger.Info("Added missing column", "table", table, "statement", statement)
+		}
+	}
+	return nil
+}
+
+// names reads a single-column result.
+func names(rows *sql.Rows, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// legacySchema is the forums table of the first db/schema.sql.
+const legacySchema = `CREATE TABLE forums (
+    forum_id TEXT PRIMARY KEY,
+    forum_name TEXT NOT NULL,
+    forum_url TEXT NOT NULL,
+    forum_html TEXT,
+    forum_description TEXT,
+    forum_screenshot TEXT,
+    last_scaned DATETIME,
+    forum_engine TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO forums (forum_id, forum_name, forum_url) VALUES ('f1', 'Exploit Bazaar', 'http://bazaar.onion/');`
+
+func open(t *testing.T) *sql.DB {
+	t.Helper()
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func latest(t *testing.T) int {
+	t.Helper()
+	migrations, err := All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func TestApplyFresh(t *testing.T) {
+	db := open(t)
+	for i := 0; i < 2; i++ {
+		version, err := Apply(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != latest(t) {
+			t.Fatalf("version = %d, want %d", version, latest(t))
+		}
+	}
+	var applied int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != latest(t) {
+		t.Errorf("%d migrations recorded, want %d", applied, latest(t))
+	}
+	if _, err := db.Exec(`INSERT INTO forums (forum_id, forum_name, forum_url, tags) VALUES ('f1', 'a', 'http://a.onion/', 'x')`); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApplyLegacy(t *testing.T) {
+	db := open(t)
+	if _, err := db.Exec(legacySchema); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Apply(db); err != nil {
+		t.Fatal(err)
+	}
+	var name, status, proxyType string
+	var maxWait int
+	err := db.QueryRow(`SELECT forum_name, status, proxy_type, ready_max_wait FROM forums WHERE forum_id = 'f1'`).Scan(&name, &status, &proxyType, &maxWait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Exploit Bazaar" || status != "unknown" || proxyType != "tor" || maxWait != 25 {
+		t.Errorf("got %q %q %q %d, want the forum kept with the column defaults", name, status, proxyType, maxWait)
+	}
+	if _, err := db.Exec(`SELECT COUNT(*) FROM scan_runs`); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApplyNewer(t *testing.T) {
+	db := open(t)
+	if _, err := Apply(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, 'from_the_future')`, latest(t)+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Apply(db); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("err = %v, want ErrNewerSchema", err)
+	}
+}
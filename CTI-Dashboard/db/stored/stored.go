@@ -0,0 +1,27 @@
+// Package stored is how values are written to the database, shared by the
+// packages that write it.
+package stored
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TimeLayout is how times are stored: in UTC with milliseconds, so rows
+// written within the same second still order correctly.
+const TimeLayout = "2006-01-02 15:04:05.000"
+
+// Time formats t for storing.
+func Time(t time.Time) string {
+	return t.UTC().Format(TimeLayout)
+}
+
+// Now is the current time as stored.
+func Now() string {
+	return Time(time.Now())
+}
+
+// Nullable stores an empty string as NULL.
+func Nullable(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -8,6 +8,8 @@ import Forums from "./pages/Forums";
 import Dashboard from "./pages/Dashboard";
 import { ModeToggle } from "./components/mode-toggle";
 import { Toaster } from "./components/ui/sonner";
+import { CaptchaPrompt } from "./components/captcha-prompt";
+import { AlertListener } from "./components/alert-listener";
 
 
 
@@ -30,6 +32,8 @@ function App() {
               </Routes>
             </SidebarInset>
           </SidebarProvider>
+          <CaptchaPrompt />
+          <AlertListener />
           <Toaster />
       </div>
     </ThemeProvider>
@@ -0,0 +1,25 @@
+import { useEffect } from "react"
+import { EventsOn } from "../../wailsjs/runtime/runtime"
+import { AcknowledgeAlert } from "../../wailsjs/go/main/App"
+import { models } from "../../wailsjs/go/models"
+import { toast } from "sonner"
+
+// Toasts seizure and ownership alerts as the scanner raises them.
+export function AlertListener() {
+  useEffect(() => {
+    return EventsOn("alert:new", (alert: models.Alert) => {
+      toast.error(alert.kind === "seizure" ? "Forum seized" : "Forum ownership change", {
+        description: alert.message,
+        duration: Infinity,
+        action: {
+          label: "Acknowledge",
+          onClick: () => {
+            AcknowledgeAlert(alert.alert_id)
+          },
+        },
+      })
+    })
+  }, [])
+
+  return null
+}
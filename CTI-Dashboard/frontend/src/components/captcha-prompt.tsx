@@ -0,0 +1,96 @@
+import React, { useEffect, useState } from "react"
+import { CancelCaptcha, GetPendingCaptchas, SubmitCaptcha } from "../../wailsjs/go/main/App"
+import { EventsOn } from "../../wailsjs/runtime/runtime"
+import { models } from "../../wailsjs/go/models"
+import { Sheet, SheetContent, SheetDescription, SheetFooter, SheetHeader, SheetTitle } from "@/components/ui/sheet"
+import { Button } from "@/components/ui/button"
+import { Input } from "@/components/ui/input"
+import { toast } from "sonner"
+
+// Shows CAPTCHAs met by the scanner one at a time. The scan of that forum
+// is paused until the answer is submitted or the CAPTCHA is cancelled.
+export function CaptchaPrompt() {
+  const [queue, setQueue] = useState<models.CaptchaChallenge[]>([])
+  const [text, setText] = useState("")
+  const [clicks, setClicks] = useState<models.CaptchaPoint[]>([])
+
+  useEffect(() => {
+    GetPendingCaptchas().then((pending) => setQueue(pending ?? []))
+    const offChallenge = EventsOn("captcha:challenge", (challenge: models.CaptchaChallenge) => {
+      setQueue((current) => [...current, challenge])
+    })
+    const offResolved = EventsOn("captcha:resolved", (challenge: models.CaptchaChallenge) => {
+      setQueue((current) => current.filter((c) => c.id !== challenge.id))
+    })
+    return () => {
+      offChallenge()
+      offResolved()
+    }
+  }, [])
+
+  const current = queue[0]
+
+  useEffect(() => {
+    setText("")
+    setClicks([])
+  }, [current?.id])
+
+  if (!current) {
+    return null
+  }
+
+  // Clicks are sent in screenshot pixels, which are the page's pixels.
+  const handleClick = (e: React.MouseEvent<HTMLImageElement>) => {
+    const img = e.currentTarget
+    const rect = img.getBoundingClientRect()
+    const x = ((e.clientX - rect.left) * img.naturalWidth) / rect.width
+    const y = ((e.clientY - rect.top) * img.naturalHeight) / rect.height
+    setClicks((points) => [...points, new models.CaptchaPoint({ x, y })])
+  }
+
+  const handleSubmit = (e: React.FormEvent) => {
+    e.preventDefault()
+    SubmitCaptcha(current.id, new models.CaptchaAnswer({ text, clicks }))
+      .then(() => toast.success("CAPTCHA answer submitted"))
+      .catch((err) => toast.error(String(err)))
+  }
+
+  const handleCancel = () => {
+    CancelCaptcha(current.id).catch((err) => toast.error(String(err)))
+  }
+
+  return (
+    <Sheet open onOpenChange={(open) => !open && handleCancel()}>
+      <SheetContent side="right" className="sm:max-w-3xl overflow-y-auto">
+        <SheetHeader>
+          <SheetTitle>CAPTCHA for {current.forum_name}</SheetTitle>
+          <SheetDescription>
+            {current.prompt} Attempt {current.attempt}. {current.url}
+          </SheetDescription>
+        </SheetHeader>
+        <form onSubmit={handleSubmit} className="px-4 space-y-4">
+          <div className="relative">
+            <img src={current.screenshot} onClick={handleClick} className="w-full cursor-crosshair border" />
+            {clicks.length > 0 && (
+              <p className="text-sm text-gray-500">
+                {clicks.length} click(s) recorded.{" "}
+                <button type="button" className="underline" onClick={() => setClicks([])}>
+                  Clear
+                </button>
+              </p>
+            )}
+          </div>
+          <Input value={text} onChange={(e) => setText(e.target.value)} placeholder="Answer" autoFocus />
+          <SheetFooter className="flex-row gap-2 px-0">
+            <Button type="submit" disabled={!text && clicks.length === 0}>
+              Submit
+            </Button>
+            <Button type="button" variant="outline" onClick={handleCancel}>
+              Cancel
+            </Button>
+          </SheetFooter>
+        </form>
+      </SheetContent>
+    </Sheet>
+  )
+}
@@ -8,6 +8,7 @@ import {
   SidebarMenuItem,
 } from "@/components/ui/sidebar";
 import { Link } from "react-router-dom";
+import { TorStatus } from "@/components/tor-status";
 
 export function AppSidebar() {
   return (
@@ -39,7 +40,9 @@ export function AppSidebar() {
           </SidebarMenu>
         </SidebarGroup>
       </SidebarContent>
-      <SidebarFooter/>
+      <SidebarFooter>
+        <TorStatus />
+      </SidebarFooter>
     </Sidebar>
   );
 }
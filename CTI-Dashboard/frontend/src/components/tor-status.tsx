@@ -0,0 +1,45 @@
+import { useEffect, useState } from "react"
+import { GetTorStatus } from "../../wailsjs/go/main/App"
+import { models } from "../../wailsjs/go/models"
+
+export function TorStatus() {
+  const [status, setStatus] = useState<models.TorBootstrap | null>(null)
+  const [error, setError] = useState<string | null>(null)
+
+  useEffect(() => {
+    const refresh = () => {
+      GetTorStatus()
+        .then((data) => {
+          setStatus(data)
+          setError(null)
+        })
+        .catch((err) => {
+          setStatus(null)
+          setError(String(err))
+        })
+    }
+    refresh()
+    const timer = setInterval(refresh, 15000)
+    return () => clearInterval(timer)
+  }, [])
+
+  if (error || !status) {
+    return (
+      <div className="text-sm text-gray-500" title={error ?? undefined}>
+        Tor: control port unavailable
+      </div>
+    )
+  }
+
+  const ready = status.progress === 100
+  return (
+    <div className="text-sm">
+      <p className={ready ? "text-green-500" : "text-yellow-500"}>
+        Tor: {ready ? "Ready" : `Bootstrapping ${status.progress}%`}
+      </p>
+      <p className="text-gray-500">{status.summary}</p>
+      <p className="text-gray-500">Circuits built: {status.circuits_built}</p>
+      {status.warning && <p className="text-red-400">{status.warning}</p>}
+    </div>
+  )
+}
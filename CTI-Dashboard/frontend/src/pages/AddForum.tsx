@@ -10,18 +10,20 @@ const AddForum: React.FC = () => {
   const [url, setUrl] = useState('');
   const [name, setName] = useState('');
   const [description, setDescription] = useState('');
+  const [proxyType, setProxyType] = useState('tor');
   const [result, setResult] = useState('');
 
 
 const handleSubmit = (e: React.FormEvent) => {
   e.preventDefault();
-  const forumData = { forum_id: '', forum_url: url, forum_name: name, forum_description: description, last_scaned: '', forum_html: '', forum_screenshot: '', forum_engine: '' };
+  const forumData = { forum_id: '', forum_url: url, forum_name: name, forum_description: description, last_scaned: '', forum_html: '', forum_screenshot: '', forum_mhtml: '', forum_pdf: '', forum_engine: '', proxy_type: proxyType, status: 'unknown', fetch_mode: 'http', ready_strategy: 'network-idle', ready_selector: '', ready_max_wait: 25, capture_formats: [] };
   CreateForum(forumData)
     .then((resultMessage: string) => {
       setResult(resultMessage);
       setName('');
       setUrl('');
       setDescription('');
+      setProxyType('tor');
       toast.success("Forum Created");
     }).catch((errorMessage: string) => {
       setResult(errorMessage);
@@ -76,6 +78,26 @@ const handleSubmit = (e: React.FormEvent) => {
               />
             </Field>
             <FieldSeparator />
+            <Field orientation="responsive">
+              <FieldContent>
+                <FieldLabel htmlFor='proxy'>Proxy</FieldLabel>
+                <FieldDescription>Network the forum is reached through.</FieldDescription>
+              </FieldContent>
+              <FieldSeparator />
+              <select
+                id="proxy"
+                className="border rounded-md px-3 py-2 bg-transparent"
+                value={proxyType}
+                onChange={(e) => setProxyType(e.target.value)}
+              >
+                <option value="tor">Tor</option>
+                <option value="i2p">I2P</option>
+                <option value="socks5">SOCKS5</option>
+                <option value="http">HTTP</option>
+                <option value="direct">Direct</option>
+              </select>
+            </Field>
+            <FieldSeparator />
             <Button type="submit">Submit Forum</Button>
             {result && <p className="mt-4">{result}</p>}
           </FieldSet>
@@ -1,9 +1,14 @@
 import React, { useState, useEffect } from 'react';
-import { GetForums, SingularScrape, MultipleScrape, DeleteForum, Extract_posts, ScanPosts} from '../../wailsjs/go/main/App';
+import { GetForums, SingularScrape, MultipleScrape, DeleteForum, Extract_posts, ScanPosts, SetForumCaptureFormats} from '../../wailsjs/go/main/App';
 import { models } from '../../wailsjs/go/models';
 import { Button } from '@/components/ui/button';
 import { toast } from "sonner"
 
+// Formats that can be saved along with a forum's screenshot.
+const captureFormats = [
+  { value: "mhtml", label: "MHTML archive" },
+  { value: "pdf", label: "PDF" },
+];
 const Forums: React.FC = () => {
   const [forums, setForums] = useState<models.Forum[]>([]);
   const [loading, setLoading] = useState<boolean>(true);
@@ -55,6 +60,16 @@ const Forums: React.FC = () => {
     });
   };
 
+  const handleCaptureFormat = (forum: models.Forum, format: string, checked: boolean) => {
+    const current = forum.capture_formats || [];
+    const formats = checked ? [...current, format] : current.filter((f) => f !== format);
+    SetForumCaptureFormats(forum.forum_id, formats).then(() => {
+      setForums((prevForums) => prevForums.map((f) => (f.forum_id === forum.forum_id ? models.Forum.createFrom({ ...f, capture_formats: formats }) : f)));
+    }).catch((err) => {
+      toast.error("Failed to save capture formats: " + err);
+    });
+  };
+
   const handleExtractPosts = (forum: models.Forum) => {
     Extract_posts(forum.forum_id).then((link_number) => {
       toast.success(link_number + " posts extracted");
@@ -101,6 +116,19 @@ const Forums: React.FC = () => {
               {forum.last_scaned && (
                 <p className="text-sm text-gray-500 mt-2">Last scaned: {new Date(forum.last_scaned).toLocaleString()}</p>
               )}
+              <div className="flex gap-4 mt-2 text-sm">
+                <span className="text-gray-500">Save with the screenshot:</span>
+                {captureFormats.map((format) => (
+                  <label key={format.value} className="flex items-center gap-1">
+                    <input
+                      type="checkbox"
+                      checked={(forum.capture_formats || []).includes(format.value)}
+                      onChange={(e) => handleCaptureFormat(forum, format.value, e.target.checked)}
+                    />
+                    {format.label}
+                  </label>
+                ))}
+              </div>
               <Button
                   className="mt-4"
                   size="sm"
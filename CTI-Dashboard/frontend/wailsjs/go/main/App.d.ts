@@ -2,22 +2,104 @@
 // This file is automatically generated. DO NOT EDIT
 import {models} from '../models';
 
+export function AcknowledgeAlert(arg1:string):Promise<void>;
+
+export function CancelCaptcha(arg1:string):Promise<void>;
+
 export function CreateForum(arg1:models.Forum):Promise<string>;
 
+export function CustodyFingerprint():Promise<string>;
+
 export function DeleteForum(arg1:string):Promise<void>;
 
+export function DeleteForumCredentials(arg1:string):Promise<void>;
+
+export function DeleteWatchIndicator(arg1:string):Promise<void>;
+
+export function ExportCSV(arg1:models.ExportFilter):Promise<string>;
+
+export function ExportMISP(arg1:models.ExportFilter):Promise<string>;
+
+export function ExportSTIX(arg1:models.ExportFilter):Promise<string>;
+
 export function Extract_posts(arg1:string):Promise<number>;
 
+export function GetAlerts(arg1:boolean):Promise<Array<models.Alert>>;
+
+export function GetChanges(arg1:string,arg2:string):Promise<Array<models.ChangeEvent>>;
+
 export function GetChartData(arg1:string):Promise<Array<models.Chart>>;
 
+export function GetForumHealth(arg1:string):Promise<models.ForumHealth>;
+
 export function GetForums():Promise<Array<models.Forum>>;
 
+export function GetIndicatorMatches(arg1:string):Promise<Array<models.IndicatorMatch>>;
+
+export function GetPendingCaptchas():Promise<Array<models.CaptchaChallenge>>;
+
+export function GetPostSnapshots(arg1:string):Promise<Array<models.Snapshot>>;
+
 export function GetPosts(arg1:string):Promise<Array<models.Post>>;
 
+export function GetProxyStatus():Promise<Array<models.ProxyStatus>>;
+
+export function GetScanHistory(arg1:string):Promise<Array<models.ScanRun>>;
+
+export function GetSettings():Promise<models.Settings>;
+
+export function GetSnapshotDiff(arg1:string,arg2:string):Promise<string>;
+
+export function GetSnapshotScreenshot(arg1:string):Promise<string>;
+
+export function GetSnapshots(arg1:string):Promise<Array<models.Snapshot>>;
+
+export function GetTorStatus():Promise<models.TorBootstrap>;
+
+export function GetWARCRecords(arg1:string):Promise<Array<models.WARCRecord>>;
+
+export function GetWatchIndicators():Promise<Array<models.WatchIndicator>>;
+
+export function ImportMISP(arg1:string):Promise<models.MISPImport>;
+
+export function ImportTargets(arg1:string):Promise<models.TargetImport>;
+
+export function IsVaultLocked():Promise<boolean>;
+
+export function LockVault():Promise<void>;
+
+export function LoginForum(arg1:string):Promise<void>;
+
 export function MultipleScrape(arg1:Array<models.Forum>):Promise<Array<models.Forum>>;
 
 export function OpenHTMLInBrowser(arg1:string):Promise<void>;
 
+export function OpenSnapshot(arg1:string):Promise<void>;
+
+export function ReplayWARCRecord(arg1:string):Promise<models.ReplayedResponse>;
+
+export function RescorePosts(arg1:string):Promise<number>;
+
 export function ScanPosts(arg1:string):Promise<void>;
 
+export function SearchPosts(arg1:string,arg2:models.SearchFilter,arg3:number):Promise<models.SearchResults>;
+
+export function SetForumCaptureFormats(arg1:string,arg2:Array<string>):Promise<void>;
+
+export function SetForumCredentials(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function SetForumFetchMode(arg1:string,arg2:string):Promise<void>;
+
+export function SetForumProxyType(arg1:string,arg2:string):Promise<void>;
+
+export function SetForumReadiness(arg1:string,arg2:string,arg3:string,arg4:number):Promise<void>;
+
 export function SingularScrape(arg1:models.Forum):Promise<void>;
+
+export function SubmitCaptcha(arg1:string,arg2:models.CaptchaAnswer):Promise<void>;
+
+export function UnlockVault(arg1:string):Promise<void>;
+
+export function UpdateSettings(arg1:models.Settings):Promise<models.Settings>;
+
+export function VerifyRun(arg1:string,arg2:string):Promise<models.CustodyReport>;
@@ -2,30 +2,142 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AcknowledgeAlert(arg1) {
+  return window['go']['main']['App']['AcknowledgeAlert'](arg1);
+}
+
+export function CancelCaptcha(arg1) {
+  return window['go']['main']['App']['CancelCaptcha'](arg1);
+}
+
 export function CreateForum(arg1) {
   return window['go']['main']['App']['CreateForum'](arg1);
 }
 
+export function CustodyFingerprint() {
+  return window['go']['main']['App']['CustodyFingerprint']();
+}
+
 export function DeleteForum(arg1) {
   return window['go']['main']['App']['DeleteForum'](arg1);
 }
 
+export function DeleteForumCredentials(arg1) {
+  return window['go']['main']['App']['DeleteForumCredentials'](arg1);
+}
+
+export function DeleteWatchIndicator(arg1) {
+  return window['go']['main']['App']['DeleteWatchIndicator'](arg1);
+}
+
+export function ExportCSV(arg1) {
+  return window['go']['main']['App']['ExportCSV'](arg1);
+}
+
+export function ExportMISP(arg1) {
+  return window['go']['main']['App']['ExportMISP'](arg1);
+}
+
+export function ExportSTIX(arg1) {
+  return window['go']['main']['App']['ExportSTIX'](arg1);
+}
+
 export function Extract_posts(arg1) {
   return window['go']['main']['App']['Extract_posts'](arg1);
 }
 
+export function GetAlerts(arg1) {
+  return window['go']['main']['App']['GetAlerts'](arg1);
+}
+
+export function GetChanges(arg1, arg2) {
+  return window['go']['main']['App']['GetChanges'](arg1, arg2);
+}
+
 export function GetChartData(arg1) {
   return window['go']['main']['App']['GetChartData'](arg1);
 }
 
+export function GetForumHealth(arg1) {
+  return window['go']['main']['App']['GetForumHealth'](arg1);
+}
+
 export function GetForums() {
   return window['go']['main']['App']['GetForums']();
 }
 
+export function GetIndicatorMatches(arg1) {
+  return window['go']['main']['App']['GetIndicatorMatches'](arg1);
+}
+
+export function GetPendingCaptchas() {
+  return window['go']['main']['App']['GetPendingCaptchas']();
+}
+
+export function GetPostSnapshots(arg1) {
+  return window['go']['main']['App']['GetPostSnapshots'](arg1);
+}
+
 export function GetPosts(arg1) {
   return window['go']['main']['App']['GetPosts'](arg1);
 }
 
+export function GetProxyStatus() {
+  return window['go']['main']['App']['GetProxyStatus']();
+}
+
+export function GetScanHistory(arg1) {
+  return window['go']['main']['App']['GetScanHistory'](arg1);
+}
+
+export function GetSettings() {
+  return window['go']['main']['App']['GetSettings']();
+}
+
+export function GetSnapshotDiff(arg1, arg2) {
+  return window['go']['main']['App']['GetSnapshotDiff'](arg1, arg2);
+}
+
+export function GetSnapshotScreenshot(arg1) {
+  return window['go']['main']['App']['GetSnapshotScreenshot'](arg1);
+}
+
+export function GetSnapshots(arg1) {
+  return window['go']['main']['App']['GetSnapshots'](arg1);
+}
+
+export function GetTorStatus() {
+  return window['go']['main']['App']['GetTorStatus']();
+}
+
+export function GetWARCRecords(arg1) {
+  return window['go']['main']['App']['GetWARCRecords'](arg1);
+}
+
+export function GetWatchIndicators() {
+  return window['go']['main']['App']['GetWatchIndicators']();
+}
+
+export function ImportMISP(arg1) {
+  return window['go']['main']['App']['ImportMISP'](arg1);
+}
+
+export function ImportTargets(arg1) {
+  return window['go']['main']['App']['ImportTargets'](arg1);
+}
+
+export function IsVaultLocked() {
+  return window['go']['main']['App']['IsVaultLocked']();
+}
+
+export function LockVault() {
+  return window['go']['main']['App']['LockVault']();
+}
+
+export function LoginForum(arg1) {
+  return window['go']['main']['App']['LoginForum'](arg1);
+}
+
 export function MultipleScrape(arg1) {
   return window['go']['main']['App']['MultipleScrape'](arg1);
 }
@@ -34,10 +146,62 @@ export function OpenHTMLInBrowser(arg1) {
   return window['go']['main']['App']['OpenHTMLInBrowser'](arg1);
 }
 
+export function OpenSnapshot(arg1) {
+  return window['go']['main']['App']['OpenSnapshot'](arg1);
+}
+
+export function ReplayWARCRecord(arg1) {
+  return window['go']['main']['App']['ReplayWARCRecord'](arg1);
+}
+
+export function RescorePosts(arg1) {
+  return window['go']['main']['App']['RescorePosts'](arg1);
+}
+
 export function ScanPosts(arg1) {
   return window['go']['main']['App']['ScanPosts'](arg1);
 }
 
+export function SearchPosts(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SearchPosts'](arg1, arg2, arg3);
+}
+
+export function SetForumCaptureFormats(arg1, arg2) {
+  return window['go']['main']['App']['SetForumCaptureFormats'](arg1, arg2);
+}
+
+export function SetForumCredentials(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetForumCredentials'](arg1, arg2, arg3);
+}
+
+export function SetForumFetchMode(arg1, arg2) {
+  return window['go']['main']['App']['SetForumFetchMode'](arg1, arg2);
+}
+
+export function SetForumProxyType(arg1, arg2) {
+  return window['go']['main']['App']['SetForumProxyType'](arg1, arg2);
+}
+
+export function SetForumReadiness(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SetForumReadiness'](arg1, arg2, arg3, arg4);
+}
+
 export function SingularScrape(arg1) {
   return window['go']['main']['App']['SingularScrape'](arg1);
 }
+
+export function SubmitCaptcha(arg1, arg2) {
+  return window['go']['main']['App']['SubmitCaptcha'](arg1, arg2);
+}
+
+export function UnlockVault(arg1) {
+  return window['go']['main']['App']['UnlockVault'](arg1);
+}
+
+export function UpdateSettings(arg1) {
+  return window['go']['main']['App']['UpdateSettings'](arg1);
+}
+
+export function VerifyRun(arg1, arg2) {
+  return window['go']['main']['App']['VerifyRun'](arg1, arg2);
+}
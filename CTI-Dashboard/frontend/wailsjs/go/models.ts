@@ -1,5 +1,131 @@
 export namespace models {
 	
+	export class Alert {
+	    alert_id: string;
+	    forum_id: string;
+	    forum_name: string;
+	    kind: string;
+	    message: string;
+	    created_at: string;
+	    acknowledged: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Alert(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.alert_id = source["alert_id"];
+	        this.forum_id = source["forum_id"];
+	        this.forum_name = source["forum_name"];
+	        this.kind = source["kind"];
+	        this.message = source["message"];
+	        this.created_at = source["created_at"];
+	        this.acknowledged = source["acknowledged"];
+	    }
+	}
+	export class CaptchaAnswer {
+	    text: string;
+	    clicks: CaptchaPoint[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CaptchaAnswer(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.text = source["text"];
+	        this.clicks = this.convertValues(source["clicks"], CaptchaPoint);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CaptchaChallenge {
+	    id: string;
+	    forum_id: string;
+	    forum_name: string;
+	    url: string;
+	    screenshot: string;
+	    prompt: string;
+	    attempt: number;
+	    created_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CaptchaChallenge(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.forum_id = source["forum_id"];
+	        this.forum_name = source["forum_name"];
+	        this.url = source["url"];
+	        this.screenshot = source["screenshot"];
+	        this.prompt = source["prompt"];
+	        this.attempt = source["attempt"];
+	        this.created_at = source["created_at"];
+	    }
+	}
+	export class CaptchaPoint {
+	    x: number;
+	    y: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CaptchaPoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.x = source["x"];
+	        this.y = source["y"];
+	    }
+	}
+	export class ChangeEvent {
+	    event_id: string;
+	    forum_id: string;
+	    forum_name: string;
+	    post_id: string;
+	    kind: string;
+	    target_url: string;
+	    detail: string;
+	    snapshot_id: string;
+	    previous_snapshot_id: string;
+	    detected_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChangeEvent(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.event_id = source["event_id"];
+	        this.forum_id = source["forum_id"];
+	        this.forum_name = source["forum_name"];
+	        this.post_id = source["post_id"];
+	        this.kind = source["kind"];
+	        this.target_url = source["target_url"];
+	        this.detail = source["detail"];
+	        this.snapshot_id = source["snapshot_id"];
+	        this.previous_snapshot_id = source["previous_snapshot_id"];
+	        this.detected_at = source["detected_at"];
+	    }
+	}
 	export class Chart {
 	    forum_id: string;
 	    forum_name: string;
@@ -28,6 +154,102 @@ export namespace models {
 	        this.last_scaned = source["last_scaned"];
 	    }
 	}
+	export class CustodyCheck {
+	    kind: string;
+	    path: string;
+	    url: string;
+	    captured_at: string;
+	    expected_sha256: string;
+	    actual_sha256: string;
+	    status: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CustodyCheck(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.path = source["path"];
+	        this.url = source["url"];
+	        this.captured_at = source["captured_at"];
+	        this.expected_sha256 = source["expected_sha256"];
+	        this.actual_sha256 = source["actual_sha256"];
+	        this.status = source["status"];
+	        this.error = source["error"];
+	    }
+	}
+	export class CustodyReport {
+	    manifest: string;
+	    run_id: string;
+	    forum_id: string;
+	    created_at: string;
+	    tool_version: string;
+	    public_key: string;
+	    fingerprint: string;
+	    signature_valid: boolean;
+	    key_matches: boolean;
+	    checks: CustodyCheck[];
+	    ok: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CustodyReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.manifest = source["manifest"];
+	        this.run_id = source["run_id"];
+	        this.forum_id = source["forum_id"];
+	        this.created_at = source["created_at"];
+	        this.tool_version = source["tool_version"];
+	        this.public_key = source["public_key"];
+	        this.fingerprint = source["fingerprint"];
+	        this.signature_valid = source["signature_valid"];
+	        this.key_matches = source["key_matches"];
+	        this.checks = this.convertValues(source["checks"], CustodyCheck);
+	        this.ok = source["ok"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ExportFilter {
+	    forum_ids: string[];
+	    post_ids: string[];
+	    severities: string[];
+	    since: string;
+	    tlp: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ExportFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.forum_ids = source["forum_ids"];
+	        this.post_ids = source["post_ids"];
+	        this.severities = source["severities"];
+	        this.since = source["since"];
+	        this.tlp = source["tlp"];
+	    }
+	}
 	export class Forum {
 	    forum_id: string;
 	    forum_url: string;
@@ -36,7 +258,19 @@ export namespace models {
 	    last_scaned: string;
 	    forum_html: string;
 	    forum_screenshot: string;
+	    forum_mhtml: string;
+	    forum_pdf: string;
 	    forum_engine: string;
+	    proxy_type: string;
+	    status: string;
+	    fetch_mode: string;
+	    ready_strategy: string;
+	    ready_selector: string;
+	    ready_max_wait: number;
+	    capture_formats: string[];
+	    engine_hint: string;
+	    tags: string[];
+	    schedule: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new Forum(source);
@@ -51,7 +285,97 @@ export namespace models {
 	        this.last_scaned = source["last_scaned"];
 	        this.forum_html = source["forum_html"];
 	        this.forum_screenshot = source["forum_screenshot"];
+	        this.forum_mhtml = source["forum_mhtml"];
+	        this.forum_pdf = source["forum_pdf"];
 	        this.forum_engine = source["forum_engine"];
+	        this.proxy_type = source["proxy_type"];
+	        this.status = source["status"];
+	        this.fetch_mode = source["fetch_mode"];
+	        this.ready_strategy = source["ready_strategy"];
+	        this.ready_selector = source["ready_selector"];
+	        this.ready_max_wait = source["ready_max_wait"];
+	        this.capture_formats = source["capture_formats"];
+	        this.engine_hint = source["engine_hint"];
+	        this.tags = source["tags"];
+	        this.schedule = source["schedule"];
+	    }
+	}
+	export class ForumHealth {
+	    forum_id: string;
+	    total_runs: number;
+	    successful_runs: number;
+	    failed_runs: number;
+	    uptime: number;
+	    availability_7d: number;
+	    availability_30d: number;
+	    avg_duration_ms: number;
+	    consecutive_failures: number;
+	    last_success: string;
+	    last_failure: string;
+	    last_error_class: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ForumHealth(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.forum_id = source["forum_id"];
+	        this.total_runs = source["total_runs"];
+	        this.successful_runs = source["successful_runs"];
+	        this.failed_runs = source["failed_runs"];
+	        this.uptime = source["uptime"];
+	        this.availability_7d = source["availability_7d"];
+	        this.availability_30d = source["availability_30d"];
+	        this.avg_duration_ms = source["avg_duration_ms"];
+	        this.consecutive_failures = source["consecutive_failures"];
+	        this.last_success = source["last_success"];
+	        this.last_failure = source["last_failure"];
+	        this.last_error_class = source["last_error_class"];
+	    }
+	}
+	export class IndicatorMatch {
+	    post_id: string;
+	    forum_id: string;
+	    thread_url: string;
+	    indicator_id: string;
+	    type: string;
+	    value: string;
+	    event_info: string;
+	    matched_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new IndicatorMatch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.post_id = source["post_id"];
+	        this.forum_id = source["forum_id"];
+	        this.thread_url = source["thread_url"];
+	        this.indicator_id = source["indicator_id"];
+	        this.type = source["type"];
+	        this.value = source["value"];
+	        this.event_info = source["event_info"];
+	        this.matched_at = source["matched_at"];
+	    }
+	}
+	export class MISPImport {
+	    file: string;
+	    events: number;
+	    indicators: number;
+	    added: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new MISPImport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.events = source["events"];
+	        this.indicators = source["indicators"];
+	        this.added = source["added"];
 	    }
 	}
 	export class Post {
@@ -82,6 +406,460 @@ export namespace models {
 	        this.date = source["date"];
 	    }
 	}
+	export class ProxyStatus {
+	    name: string;
+	    type: string;
+	    address: string;
+	    in_flight: number;
+	    failures: number;
+	    healthy: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProxyStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.address = source["address"];
+	        this.in_flight = source["in_flight"];
+	        this.failures = source["failures"];
+	        this.healthy = source["healthy"];
+	    }
+	}
+	export class ReplayedResponse {
+	    record_id: string;
+	    target_uri: string;
+	    date: string;
+	    status: string;
+	    headers: {[key: string]: string};
+	    body: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReplayedResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.record_id = source["record_id"];
+	        this.target_uri = source["target_uri"];
+	        this.date = source["date"];
+	        this.status = source["status"];
+	        this.headers = source["headers"];
+	        this.body = source["body"];
+	    }
+	}
+	export class ScanAttempt {
+	    attempt: number;
+	    started_at: string;
+	    duration_ms: number;
+	    http_status: number;
+	    url: string;
+	    redirects: string[];
+	    error_class: string;
+	    error: string;
+	    retry: boolean;
+	    delay_ms: number;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanAttempt(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.attempt = source["attempt"];
+	        this.started_at = source["started_at"];
+	        this.duration_ms = source["duration_ms"];
+	        this.http_status = source["http_status"];
+	        this.url = source["url"];
+	        this.redirects = source["redirects"];
+	        this.error_class = source["error_class"];
+	        this.error = source["error"];
+	        this.retry = source["retry"];
+	        this.delay_ms = source["delay_ms"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class ScanRun {
+	    run_id: string;
+	    forum_id: string;
+	    target_url: string;
+	    kind: string;
+	    started_at: string;
+	    finished_at: string;
+	    duration_ms: number;
+	    outcome: string;
+	    http_status: number;
+	    bytes: number;
+	    error_class: string;
+	    error: string;
+	    exit_ip: string;
+	    page_class: string;
+	    attempts: ScanAttempt[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanRun(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.run_id = source["run_id"];
+	        this.forum_id = source["forum_id"];
+	        this.target_url = source["target_url"];
+	        this.kind = source["kind"];
+	        this.started_at = source["started_at"];
+	        this.finished_at = source["finished_at"];
+	        this.duration_ms = source["duration_ms"];
+	        this.outcome = source["outcome"];
+	        this.http_status = source["http_status"];
+	        this.bytes = source["bytes"];
+	        this.error_class = source["error_class"];
+	        this.error = source["error"];
+	        this.exit_ip = source["exit_ip"];
+	        this.page_class = source["page_class"];
+	        this.attempts = this.convertValues(source["attempts"], ScanAttempt);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SearchFilter {
+	    forum_ids: string[];
+	    severities: string[];
+	    statuses: string[];
+	    from: string;
+	    to: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.forum_ids = source["forum_ids"];
+	        this.severities = source["severities"];
+	        this.statuses = source["statuses"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	    }
+	}
+	export class SearchHit {
+	    post_id: string;
+	    forum_id: string;
+	    forum_name: string;
+	    thread_url: string;
+	    title: string;
+	    author: string;
+	    status: string;
+	    severity_level: string;
+	    created_at: string;
+	    snippet: string;
+	    score: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchHit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.post_id = source["post_id"];
+	        this.forum_id = source["forum_id"];
+	        this.forum_name = source["forum_name"];
+	        this.thread_url = source["thread_url"];
+	        this.title = source["title"];
+	        this.author = source["author"];
+	        this.status = source["status"];
+	        this.severity_level = source["severity_level"];
+	        this.created_at = source["created_at"];
+	        this.snippet = source["snippet"];
+	        this.score = source["score"];
+	    }
+	}
+	export class SearchResults {
+	    query: string;
+	    total: number;
+	    page: number;
+	    page_size: number;
+	    hits: SearchHit[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchResults(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.query = source["query"];
+	        this.total = source["total"];
+	        this.page = source["page"];
+	        this.page_size = source["page_size"];
+	        this.hits = this.convertValues(source["hits"], SearchHit);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Settings {
+	    target_file: string;
+	    db_path: string;
+	    output_dir: string;
+	    tor_proxy: string;
+	    tor_control: string;
+	    proxy_strategy: string;
+	    timeout: string;
+	    max_retries: number;
+	    workers: number;
+	    connectivity_check: string;
+	    canary_url: string;
+	    connectivity_ttl: string;
+	    browser_tabs: number;
+	    browser_recycle: number;
+	    warc_rotation: string;
+	    taxii_address: string;
+	    api_address: string;
+	    config_file: string;
+	    restart_required: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.target_file = source["target_file"];
+	        this.db_path = source["db_path"];
+	        this.output_dir = source["output_dir"];
+	        this.tor_proxy = source["tor_proxy"];
+	        this.tor_control = source["tor_control"];
+	        this.proxy_strategy = source["proxy_strategy"];
+	        this.timeout = source["timeout"];
+	        this.max_retries = source["max_retries"];
+	        this.workers = source["workers"];
+	        this.connectivity_check = source["connectivity_check"];
+	        this.canary_url = source["canary_url"];
+	        this.connectivity_ttl = source["connectivity_ttl"];
+	        this.browser_tabs = source["browser_tabs"];
+	        this.browser_recycle = source["browser_recycle"];
+	        this.warc_rotation = source["warc_rotation"];
+	        this.taxii_address = source["taxii_address"];
+	        this.api_address = source["api_address"];
+	        this.config_file = source["config_file"];
+	        this.restart_required = source["restart_required"];
+	    }
+	}
+	export class Snapshot {
+	    snapshot_id: string;
+	    forum_id: string;
+	    post_id: string;
+	    target_url: string;
+	    kind: string;
+	    run_id: string;
+	    fetched_at: string;
+	    html_sha256: string;
+	    html_path: string;
+	    html_size: number;
+	    screenshot_sha256: string;
+	    screenshot_path: string;
+	    screenshot_size: number;
+	    mhtml_sha256: string;
+	    mhtml_path: string;
+	    pdf_sha256: string;
+	    pdf_path: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Snapshot(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.snapshot_id = source["snapshot_id"];
+	        this.forum_id = source["forum_id"];
+	        this.post_id = source["post_id"];
+	        this.target_url = source["target_url"];
+	        this.kind = source["kind"];
+	        this.run_id = source["run_id"];
+	        this.fetched_at = source["fetched_at"];
+	        this.html_sha256 = source["html_sha256"];
+	        this.html_path = source["html_path"];
+	        this.html_size = source["html_size"];
+	        this.screenshot_sha256 = source["screenshot_sha256"];
+	        this.screenshot_path = source["screenshot_path"];
+	        this.screenshot_size = source["screenshot_size"];
+	        this.mhtml_sha256 = source["mhtml_sha256"];
+	        this.mhtml_path = source["mhtml_path"];
+	        this.pdf_sha256 = source["pdf_sha256"];
+	        this.pdf_path = source["pdf_path"];
+	    }
+	}
+	export class TargetImport {
+	    file: string;
+	    added: number;
+	    duplicates: number;
+	    invalid: number;
+	    rows: TargetRow[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TargetImport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.added = source["added"];
+	        this.duplicates = source["duplicates"];
+	        this.invalid = source["invalid"];
+	        this.rows = this.convertValues(source["rows"], TargetRow);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TargetRow {
+	    row: number;
+	    name: string;
+	    url: string;
+	    status: string;
+	    forum_id: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TargetRow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.row = source["row"];
+	        this.name = source["name"];
+	        this.url = source["url"];
+	        this.status = source["status"];
+	        this.forum_id = source["forum_id"];
+	        this.error = source["error"];
+	    }
+	}
+	export class TorBootstrap {
+	    progress: number;
+	    tag: string;
+	    summary: string;
+	    warning: string;
+	    circuits_built: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TorBootstrap(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.progress = source["progress"];
+	        this.tag = source["tag"];
+	        this.summary = source["summary"];
+	        this.warning = source["warning"];
+	        this.circuits_built = source["circuits_built"];
+	    }
+	}
+	export class WARCRecord {
+	    record_id: string;
+	    forum_id: string;
+	    post_id: string;
+	    run_id: string;
+	    type: string;
+	    target_uri: string;
+	    date: string;
+	    content_type: string;
+	    payload_digest: string;
+	    file: string;
+	    offset: number;
+	    length: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new WARCRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.record_id = source["record_id"];
+	        this.forum_id = source["forum_id"];
+	        this.post_id = source["post_id"];
+	        this.run_id = source["run_id"];
+	        this.type = source["type"];
+	        this.target_uri = source["target_uri"];
+	        this.date = source["date"];
+	        this.content_type = source["content_type"];
+	        this.payload_digest = source["payload_digest"];
+	        this.file = source["file"];
+	        this.offset = source["offset"];
+	        this.length = source["length"];
+	    }
+	}
+	export class WatchIndicator {
+	    indicator_id: string;
+	    type: string;
+	    value: string;
+	    event_uuid: string;
+	    event_info: string;
+	    source: string;
+	    imported_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new WatchIndicator(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.indicator_id = source["indicator_id"];
+	        this.type = source["type"];
+	        this.value = source["value"];
+	        this.event_uuid = source["event_uuid"];
+	        this.event_info = source["event_info"];
+	        this.source = source["source"];
+	        this.imported_at = source["imported_at"];
+	    }
+	}
 
 }
 
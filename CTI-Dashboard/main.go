@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"embed"
-	"time"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
 
 	_ "github.com/mattn/go-sqlite3"
 
+	"CTI-Dashboard/db/migrations"
 	"CTI-Dashboard/scraper/config"
+	"CTI-Dashboard/scraper/connectivity"
 	"CTI-Dashboard/scraper/logger"
 	"CTI-Dashboard/scraper/output"
 	"CTI-Dashboard/scraper/proxy"
+	"CTI-Dashboard/scraper/search"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -21,34 +28,51 @@ import (
 var assets embed.FS
 
 func main() {
-	cfg := config.Config{
-		Timeout:    time.Duration(30) * time.Second,
-		MaxRetries: 3,
-		OutputDir:  "output/",
-		TorProxy:   "127.0.0.1:9050",
-		TargetFile: "targets.yaml",
-		Workers:    5,
-	}
-	db, err := sql.Open("sqlite3", "./db/database.db")
-	if err != nil {
-		logger.Error("Could not connect to the database", "error", err)
+	headless := flag.Bool("headless", false, "serve the REST API without opening a window")
+	listen := flag.String("listen", "", "address of the REST API, e.g. "+DefaultAPIAddress)
+	configFile := flag.String("config", "config.yaml", "YAML configuration file; options can be overridden with "+config.EnvPrefix+"* variables")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags]\n       %s <command> [flags] [arguments]\n", os.Args[0], os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprint(flag.CommandLine.Output(), "\n"+cliUsage)
+	}
+	flag.Parse()
+	if flag.NArg() > 0 && !isCommand(flag.Args()) {
+		flag.Usage()
+		os.Exit(2)
 	}
 
-	_, err = db.Exec("PRAGMA foreign_keys = ON;")
+	cfg, err := config.Load(*configFile)
 	if err != nil {
-		logger.Error("Could not enable foreign keys", "error", err)
-		return
+		println("Error loading configuration:", err.Error())
+		os.Exit(1)
+	}
+	if *listen != "" {
+		cfg.APIAddress = *listen
 	}
-
 	if err := logger.Init(cfg.OutputDir); err != nil {
 		println("Error initializing logger:", err.Error())
-		return
+		os.Exit(1)
 	}
 	defer logger.Close()
 
-	client, err := proxy.TorClient(cfg)
+	db, err := openDatabase(cfg.DBPath)
+	if err != nil {
+		println("Error opening database:", err.Error())
+		logger.Close()
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	pool, err := proxy.NewPool(cfg)
+	if err != nil {
+		logger.Error("Error initializing proxy pool:", "error", err)
+		return
+	}
+
+	checker, err := connectivity.New(cfg)
 	if err != nil {
-		logger.Error("Error initializing Tor client:", "error", err)
+		logger.Error("Error initializing connectivity check:", "error", err)
 		return
 	}
 
@@ -57,8 +81,26 @@ func main() {
 		logger.Error("Error initializing writer:", "error", err)
 		return
 	}
+	if cfg.WARCRotation != "" {
+		if err := writer.EnableWARC(cfg.WARCRotation); err != nil {
+			logger.Error("Error initializing WARC output:", "error", err)
+			return
+		}
+	}
 
-	app := NewApp(cfg, client, writer, db)
+	app := NewApp(cfg, *configFile, pool, checker, writer, db)
+	if flag.NArg() > 0 {
+		status := runCLI(app, flag.Args())
+		app.shutdown(context.Background())
+		logger.Close()
+		os.Exit(status)
+	}
+	if *headless {
+		if err := runHeadless(app); err != nil {
+			logger.Error("Could not run headless", "error", err)
+		}
+		return
+	}
 
 	err = wails.Run(&options.App{
 		Title:      "CTI-Dashboard",
@@ -70,6 +112,7 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
+		OnShutdown:       app.shutdown,
 		Bind: []interface{}{
 			app,
 		},
@@ -79,3 +122,32 @@ func main() {
 		logger.Error("Error starting application", "error", err)
 	}
 }
+
+// openDatabase opens the SQLite database at path, creating it if needed, and
+// applies the migrations it has not had yet and sets up the search index.
+func openDatabase(path string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		logger.Error("Could not create the database directory", "error", err, "path", path)
+		return nil, err
+	}
+	// Set in the DSN so that every connection in the pool enforces foreign keys.
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
+	if err != nil {
+		logger.Error("Could not connect to the database", "error", err, "path", path)
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		logger.Error("Could not connect to the database", "error", err, "path", path)
+		db.Close()
+		return nil, err
+	}
+	if _, err := migrations.Apply(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := search.Prepare(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"CTI-Dashboard/scraper/logger"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenDatabaseForeignKeys(t *testing.T) {
+	dir := t.TempDir()
+	if err := logger.Init(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	db, err := openDatabase(filepath.Join(dir, "db", "cti.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Hold two connections at once so the pool has to open a second one.
+	var conns []*sql.Conn
+	for i := 0; i < 2; i++ {
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	for i, conn := range conns {
+		var on bool
+		if err := conn.QueryRowContext(context.Background(), `PRAGMA foreign_keys`).Scan(&on); err != nil {
+			t.Fatal(err)
+		}
+		if !on {
+			t.Errorf("connection %d does not enforce foreign keys", i)
+		}
+	}
+}
@@ -8,7 +8,23 @@ type Forum struct {
 	LastScaned       string `json:"last_scaned"`
 	ForumHTML        string `json:"forum_html"`
 	ForumScreenshot  string `json:"forum_screenshot"`
+	ForumMHTML       string `json:"forum_mhtml"`
+	ForumPDF         string `json:"forum_pdf"`
 	ForumEngine      string `json:"forum_engine"`
+	ProxyType        string `json:"proxy_type"`
+	Status           string `json:"status"`
+	FetchMode        string `json:"fetch_mode"`
+	ReadyStrategy    string `json:"ready_strategy"`
+	ReadySelector    string `json:"ready_selector"`
+	ReadyMaxWait     int    `json:"ready_max_wait"`
+	// CaptureFormats are extra formats saved with the screenshot: mhtml, pdf.
+	CaptureFormats []string `json:"capture_formats"`
+	// EngineHint is the engine given when the forum was added, used while
+	// scrapes cannot identify one.
+	EngineHint string   `json:"engine_hint"`
+	Tags       []string `json:"tags"`
+	// Schedule is how often the forum should be scraped, e.g. 6h.
+	Schedule string `json:"schedule"`
 }
 
 type Post struct {
@@ -37,6 +53,314 @@ type Chart struct {
 
 type Job struct {
 	JobID     string `json:"job_id"`
+	ForumID   string `json:"forum_id"`
 	ThreadURL string `json:"thread_url"`
 	ForumName string `json:"forum_name"`
 }
+
+type ScanRun struct {
+	RunID      string `json:"run_id"`
+	ForumID    string `json:"forum_id"`
+	TargetURL  string `json:"target_url"`
+	Kind       string `json:"kind"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at"`
+	DurationMs int64  `json:"duration_ms"`
+	Outcome    string `json:"outcome"`
+	HTTPStatus int    `json:"http_status"`
+	Bytes      int64  `json:"bytes"`
+	ErrorClass string `json:"error_class"`
+	Error      string `json:"error"`
+	ExitIP     string `json:"exit_ip"`
+	PageClass  string `json:"page_class"`
+	// Attempts is the log of every try within the run.
+	Attempts []ScanAttempt `json:"attempts"`
+}
+
+type ScanAttempt struct {
+	Attempt    int      `json:"attempt"`
+	StartedAt  string   `json:"started_at"`
+	DurationMs int64    `json:"duration_ms"`
+	HTTPStatus int      `json:"http_status"`
+	URL        string   `json:"url"`
+	Redirects  []string `json:"redirects"`
+	ErrorClass string   `json:"error_class"`
+	Error      string   `json:"error"`
+	Retry      bool     `json:"retry"`
+	DelayMs    int64    `json:"delay_ms"`
+	Reason     string   `json:"reason"`
+}
+
+type ForumHealth struct {
+	ForumID             string  `json:"forum_id"`
+	TotalRuns           int     `json:"total_runs"`
+	SuccessfulRuns      int     `json:"successful_runs"`
+	FailedRuns          int     `json:"failed_runs"`
+	Uptime              float64 `json:"uptime"`
+	Availability7d      float64 `json:"availability_7d"`
+	Availability30d     float64 `json:"availability_30d"`
+	AvgDurationMs       int64   `json:"avg_duration_ms"`
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	LastSuccess         string  `json:"last_success"`
+	LastFailure         string  `json:"last_failure"`
+	LastErrorClass      string  `json:"last_error_class"`
+}
+
+type TorBootstrap struct {
+	Progress      int    `json:"progress"`
+	Tag           string `json:"tag"`
+	Summary       string `json:"summary"`
+	Warning       string `json:"warning"`
+	CircuitsBuilt int    `json:"circuits_built"`
+}
+
+type ProxyStatus struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Address  string `json:"address"`
+	InFlight int    `json:"in_flight"`
+	Failures int    `json:"failures"`
+	Healthy  bool   `json:"healthy"`
+}
+
+type CaptchaChallenge struct {
+	ID         string `json:"id"`
+	ForumID    string `json:"forum_id"`
+	ForumName  string `json:"forum_name"`
+	URL        string `json:"url"`
+	Screenshot string `json:"screenshot"` // data URL of the visible page
+	Prompt     string `json:"prompt"`
+	Attempt    int    `json:"attempt"`
+	CreatedAt  string `json:"created_at"`
+}
+
+type CaptchaPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type CaptchaAnswer struct {
+	Text   string         `json:"text"`
+	Clicks []CaptchaPoint `json:"clicks"` // in screenshot pixels, clicked before the text is submitted
+}
+
+type Alert struct {
+	AlertID      string `json:"alert_id"`
+	ForumID      string `json:"forum_id"`
+	ForumName    string `json:"forum_name"`
+	Kind         string `json:"kind"`
+	Message      string `json:"message"`
+	CreatedAt    string `json:"created_at"`
+	Acknowledged bool   `json:"acknowledged"`
+}
+
+type Snapshot struct {
+	SnapshotID       string `json:"snapshot_id"`
+	ForumID          string `json:"forum_id"`
+	PostID           string `json:"post_id"`
+	TargetURL        string `json:"target_url"`
+	Kind             string `json:"kind"`
+	RunID            string `json:"run_id"`
+	FetchedAt        string `json:"fetched_at"`
+	HTMLSHA256       string `json:"html_sha256"`
+	HTMLPath         string `json:"html_path"`
+	HTMLSize         int64  `json:"html_size"`
+	ScreenshotSHA256 string `json:"screenshot_sha256"`
+	ScreenshotPath   string `json:"screenshot_path"`
+	ScreenshotSize   int64  `json:"screenshot_size"`
+	MHTMLSHA256      string `json:"mhtml_sha256"`
+	MHTMLPath        string `json:"mhtml_path"`
+	PDFSHA256        string `json:"pdf_sha256"`
+	PDFPath          string `json:"pdf_path"`
+}
+
+type ChangeEvent struct {
+	EventID            string `json:"event_id"`
+	ForumID            string `json:"forum_id"`
+	ForumName          string `json:"forum_name"`
+	PostID             string `json:"post_id"`
+	Kind               string `json:"kind"`
+	TargetURL          string `json:"target_url"`
+	Detail             string `json:"detail"`
+	SnapshotID         string `json:"snapshot_id"`
+	PreviousSnapshotID string `json:"previous_snapshot_id"`
+	DetectedAt         string `json:"detected_at"`
+}
+
+type WARCRecord struct {
+	RecordID      string `json:"record_id"`
+	ForumID       string `json:"forum_id"`
+	PostID        string `json:"post_id"`
+	RunID         string `json:"run_id"`
+	Type          string `json:"type"`
+	TargetURI     string `json:"target_uri"`
+	Date          string `json:"date"`
+	ContentType   string `json:"content_type"`
+	PayloadDigest string `json:"payload_digest"`
+	File          string `json:"file"`
+	Offset        int64  `json:"offset"`
+	Length        int64  `json:"length"`
+}
+
+// ReplayedResponse is a response read back from a WARC file.
+type ReplayedResponse struct {
+	RecordID  string            `json:"record_id"`
+	TargetURI string            `json:"target_uri"`
+	Date      string            `json:"date"`
+	Status    string            `json:"status"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body"`
+}
+
+// CustodyReport is the outcome of verifying a chain-of-custody manifest.
+// KeyMatches is false when the manifest names another key than the one it
+// was checked with, whose fingerprint is Fingerprint.
+type CustodyReport struct {
+	Manifest       string         `json:"manifest"`
+	RunID          string         `json:"run_id"`
+	ForumID        string         `json:"forum_id"`
+	CreatedAt      string         `json:"created_at"`
+	ToolVersion    string         `json:"tool_version"`
+	PublicKey      string         `json:"public_key"`
+	Fingerprint    string         `json:"fingerprint"`
+	SignatureValid bool           `json:"signature_valid"`
+	KeyMatches     bool           `json:"key_matches"`
+	Checks         []CustodyCheck `json:"checks"`
+	OK             bool           `json:"ok"`
+}
+
+type CustodyCheck struct {
+	Kind       string `json:"kind"`
+	Path       string `json:"path"`
+	URL        string `json:"url"`
+	CapturedAt string `json:"captured_at"`
+	Expected   string `json:"expected_sha256"`
+	Actual     string `json:"actual_sha256"`
+	Status     string `json:"status"` // ok, modified, missing
+	Error      string `json:"error"`
+}
+
+// WatchIndicator is an indicator imported from a MISP event. Scraped posts
+// containing it are flagged.
+type WatchIndicator struct {
+	IndicatorID string `json:"indicator_id"`
+	Type        string `json:"type"`
+	Value       string `json:"value"`
+	EventUUID   string `json:"event_uuid"`
+	EventInfo   string `json:"event_info"`
+	Source      string `json:"source"`
+	ImportedAt  string `json:"imported_at"`
+}
+
+type IndicatorMatch struct {
+	PostID      string `json:"post_id"`
+	ForumID     string `json:"forum_id"`
+	ThreadURL   string `json:"thread_url"`
+	IndicatorID string `json:"indicator_id"`
+	Type        string `json:"type"`
+	Value       string `json:"value"`
+	EventInfo   string `json:"event_info"`
+	MatchedAt   string `json:"matched_at"`
+}
+
+// MISPImport summarizes an imported MISP file.
+type MISPImport struct {
+	File       string `json:"file"`
+	Events     int    `json:"events"`
+	Indicators int    `json:"indicators"`
+	Added      int    `json:"added"`
+}
+
+// Settings are the options of the configuration file that can be changed
+// from the window. Durations are given like 30s or 5m; secrets are left out.
+type Settings struct {
+	TargetFile        string `json:"target_file"`
+	DBPath            string `json:"db_path"`
+	OutputDir         string `json:"output_dir"`
+	TorProxy          string `json:"tor_proxy"`
+	TorControl        string `json:"tor_control"`
+	ProxyStrategy     string `json:"proxy_strategy"`
+	Timeout           string `json:"timeout"`
+	MaxRetries        int    `json:"max_retries"`
+	Workers           int    `json:"workers"`
+	ConnectivityCheck string `json:"connectivity_check"`
+	CanaryURL         string `json:"canary_url"`
+	ConnectivityTTL   string `json:"connectivity_ttl"`
+	BrowserTabs       int    `json:"browser_tabs"`
+	BrowserRecycle    int    `json:"browser_recycle"`
+	WARCRotation      string `json:"warc_rotation"`
+	TAXIIAddress      string `json:"taxii_address"`
+	APIAddress        string `json:"api_address"`
+	// ConfigFile is where the settings are saved. RestartRequired lists the
+	// options changed that take effect on the next start. Both are ignored
+	// by UpdateSettings.
+	ConfigFile      string   `json:"config_file"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+// TargetImport reports what became of each target of an imported targets
+// file.
+type TargetImport struct {
+	File       string      `json:"file"`
+	Added      int         `json:"added"`
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Rows       []TargetRow `json:"rows"`
+}
+
+// TargetRow is one target of a targets file. Status is added, duplicate or
+// invalid; ForumID is the forum added, or the existing one it duplicates.
+type TargetRow struct {
+	Row     int    `json:"row"`
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	Status  string `json:"status"`
+	ForumID string `json:"forum_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ExportFilter selects what goes into an export. Empty fields select
+// everything; TLP defaults to amber.
+type ExportFilter struct {
+	ForumIDs   []string `json:"forum_ids"`
+	PostIDs    []string `json:"post_ids"`
+	Severities []string `json:"severities"`
+	Since      string   `json:"since"`
+	TLP        string   `json:"tlp"`
+}
+
+// SearchFilter narrows a post search. Empty fields select everything; From
+// and To bound when the post was found, as dates like 2025-01-31 or RFC 3339
+// times, To included.
+type SearchFilter struct {
+	ForumIDs   []string `json:"forum_ids"`
+	Severities []string `json:"severities"`
+	Statuses   []string `json:"statuses"`
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+}
+
+// SearchResults is one page of posts matching a search, best match first.
+type SearchResults struct {
+	Query    string      `json:"query"`
+	Total    int         `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	Hits     []SearchHit `json:"hits"`
+}
+
+// SearchHit is a post matching a search. Title and Snippet are HTML: the
+// text is escaped and the matched terms are wrapped in <mark>.
+type SearchHit struct {
+	PostID    string  `json:"post_id"`
+	ForumID   string  `json:"forum_id"`
+	ForumName string  `json:"forum_name"`
+	ThreadURL string  `json:"thread_url"`
+	Title     string  `json:"title"`
+	Author    string  `json:"author"`
+	Status    string  `json:"status"`
+	Severity  string  `json:"severity_level"`
+	CreatedAt string  `json:"created_at"`
+	Snippet   string  `json:"snippet"`
+	Score     float64 `json:"score"`
+}
@@ -0,0 +1,69 @@
+package alerts
+
+import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	KindSeizure   = "seizure"
+	KindOwnership = "ownership_change"
+	KindDeletion  = "thread_deleted"
+	KindIndicator = "indicator_match"
+)
+
+// Raise stores a new alert for the forum.
+func Raise(db *sql.DB, forumID string, kind string, message string) (models.Alert, error) {
+	alert := models.Alert{
+		AlertID:   uuid.New().String(),
+		ForumID:   forumID,
+		Kind:      kind,
+		Message:   message,
+		CreatedAt: stored.Now(),
+	}
+	_, err := db.Exec(`INSERT INTO alerts (alert_id, forum_id, kind, message, created_at) VALUES (?, ?, ?, ?, ?)`,
+		alert.AlertID, alert.ForumID, alert.Kind, alert.Message, alert.CreatedAt)
+	if err != nil {
+		logger.Error("Could not store alert", "error", err, "forum_id", forumID, "kind", kind)
+		return alert, err
+	}
+	logger.Info("Raised alert", "forum_id", forumID, "kind", kind, "message", message)
+	return alert, nil
+}
+
+// List returns alerts newest first, only the open ones unless all is set.
+func List(db *sql.DB, all bool) ([]models.Alert, error) {
+	rows, err := db.Query(`SELECT a.alert_id, COALESCE(a.forum_id, ''), COALESCE(f.forum_name, ''), a.kind, a.message, a.created_at, a.acknowledged
+		FROM alerts a LEFT JOIN forums f ON f.forum_id = a.forum_id
+		WHERE ? OR a.acknowledged = 0 ORDER BY a.created_at DESC`, all)
+	if err != nil {
+		logger.Error("Could not query alerts", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.Alert
+	for rows.Next() {
+		var alert models.Alert
+		err := rows.Scan(&alert.AlertID, &alert.ForumID, &alert.ForumName, &alert.Kind, &alert.Message, &alert.CreatedAt, &alert.Acknowledged)
+		if err != nil {
+			logger.Error("Could not scan alert row", "error", err)
+			continue
+		}
+		list = append(list, alert)
+	}
+	return list, rows.Err()
+}
+
+func Acknowledge(db *sql.DB, alertID string) error {
+	_, err := db.Exec(`UPDATE alerts SET acknowledged = 1 WHERE alert_id = ?`, alertID)
+	if err != nil {
+		logger.Error("Could not acknowledge alert", "error", err, "alert_id", alertID)
+	}
+	return err
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/export"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/serve"
+	"crypto/sha256"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Version is the path prefix of every operation.
+const Version = "/v1"
+
+//go:embed openapi.json
+var openAPI []byte
+
+var (
+	// ErrNotFound is answered with 404.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalid is answered with 400.
+	ErrInvalid = errors.New("invalid request")
+)
+
+// Backend carries out the operations of the API. The desktop app implements
+// it with the same code its bindings use.
+type Backend interface {
+	Forums() ([]models.Forum, error)
+	Forum(forumID string) (models.Forum, error)
+	CreateForum(forum models.Forum) (models.Forum, error)
+	// UpdateForum changes the fields of forum that are set.
+	UpdateForum(forum models.Forum) (models.Forum, error)
+	DeleteForum(forumID string) error
+
+	// Scrape scrapes the forum page, ExtractPosts collects the threads it
+	// lists and ScanPosts scrapes them.
+	Scrape(forumID string) error
+	ExtractPosts(forumID string) (int, error)
+	ScanPosts(forumID string) error
+
+	Posts(forumID string) ([]models.Post, error)
+	Chart(forumID string) ([]models.Chart, error)
+	ScanHistory(forumID string) ([]models.ScanRun, error)
+	Alerts(all bool) ([]models.Alert, error)
+	AcknowledgeAlert(alertID string) error
+
+	ExportSTIX(filter models.ExportFilter) (string, error)
+	ExportMISP(filter models.ExportFilter) (string, error)
+	ExportCSV(filter models.ExportFilter) (string, error)
+
+	// CAPTCHAs are answered over the API when no window is open.
+	PendingCaptchas() []models.CaptchaChallenge
+	SubmitCaptcha(id string, answer models.CaptchaAnswer) error
+
+	// Background runs a job after its request is answered, tracked so the
+	// backend can wait for it when shutting down.
+	Background(job func())
+}
+
+// Server serves the REST API. Every operation needs one of the API keys it
+// was created with, as a bearer token or in the X-API-Key header; the
+// OpenAPI document at /openapi.json does not.
+type Server struct {
+	backend Backend
+	keys    map[[32]byte]string
+	mux     *http.ServeMux
+	// operations lists the patterns of the operations, without Version.
+	operations []string
+}
+
+// NewServer returns a server for backend. keys maps key names, used in the
+// log, to API keys; a server without keys refuses every operation.
+func NewServer(backend Backend, keys map[string]string) *Server {
+	s := &Server{backend: backend, keys: make(map[[32]byte]string), mux: http.NewServeMux()}
+	for name, key := range keys {
+		s.keys[sha256.Sum256([]byte(key))] = name
+	}
+	s.register()
+	return s
+}
+
+func (s *Server) register() {
+	s.mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPI)
+	})
+
+	s.handle("GET /forums", func(r *http.Request) (int, any, error) {
+		forums, err := s.backend.Forums()
+		return http.StatusOK, nonNil(forums), err
+	})
+	s.handle("POST /forums", func(r *http.Request) (int, any, error) {
+		var forum models.Forum
+		if err := decode(r, &forum); err != nil {
+			return 0, nil, err
+		}
+		created, err := s.backend.CreateForum(forum)
+		return http.StatusCreated, created, err
+	})
+	s.handle("GET /forums/{id}", func(r *http.Request) (int, any, error) {
+		forum, err := s.backend.Forum(r.PathValue("id"))
+		return http.StatusOK, forum, err
+	})
+	s.handle("PATCH /forums/{id}", func(r *http.Request) (int, any, error) {
+		var forum models.Forum
+		if err := decode(r, &forum); err != nil {
+			return 0, nil, err
+		}
+		forum.ForumID = r.PathValue("id")
+		updated, err := s.backend.UpdateForum(forum)
+		return http.StatusOK, updated, err
+	})
+	s.handle("DELETE /forums/{id}", func(r *http.Request) (int, any, error) {
+		return http.StatusNoContent, nil, s.backend.DeleteForum(r.PathValue("id"))
+	})
+
+	s.handle("POST /forums/{id}/scrape", s.job(func(forumID string) error {
+		return s.backend.Scrape(forumID)
+	}))
+	s.handle("POST /forums/{id}/extract-posts", func(r *http.Request) (int, any, error) {
+		count, err := s.backend.ExtractPosts(r.PathValue("id"))
+		return http.StatusOK, map[string]int{"posts": count}, err
+	})
+	s.handle("POST /forums/{id}/scan-posts", s.job(func(forumID string) error {
+		return s.backend.ScanPosts(forumID)
+	}))
+
+	s.handle("GET /forums/{id}/posts", func(r *http.Request) (int, any, error) {
+		posts, err := s.backend.Posts(r.PathValue("id"))
+		return http.StatusOK, nonNil(posts), err
+	})
+	s.handle("GET /forums/{id}/chart", func(r *http.Request) (int, any, error) {
+		chart, err := s.backend.Chart(r.PathValue("id"))
+		return http.StatusOK, nonNil(chart), err
+	})
+	s.handle("GET /forums/{id}/history", func(r *http.Request) (int, any, error) {
+		runs, err := s.backend.ScanHistory(r.PathValue("id"))
+		return http.StatusOK, nonNil(runs), err
+	})
+
+	s.handle("GET /alerts", func(r *http.Request) (int, any, error) {
+		list, err := s.backend.Alerts(r.URL.Query().Get("all") == "true")
+		return http.StatusOK, nonNil(list), err
+	})
+	s.handle("POST /alerts/{id}/acknowledge", func(r *http.Request) (int, any, error) {
+		return http.StatusNoContent, nil, s.backend.AcknowledgeAlert(r.PathValue("id"))
+	})
+
+	s.export("GET /exports/stix", "application/stix+json;version=2.1", func(filter models.ExportFilter) (string, error) {
+		return s.backend.ExportSTIX(filter)
+	})
+	s.export("GET /exports/misp", "application/json", func(filter models.ExportFilter) (string, error) {
+		return s.backend.ExportMISP(filter)
+	})
+	s.export("GET /exports/csv", "text/csv; charset=utf-8", func(filter models.ExportFilter) (string, error) {
+		return s.backend.ExportCSV(filter)
+	})
+
+	s.handle("GET /captchas", func(r *http.Request) (int, any, error) {
+		return http.StatusOK, nonNil(s.backend.PendingCaptchas()), nil
+	})
+	s.handle("POST /captchas/{id}", func(r *http.Request) (int, any, error) {
+		var answer models.CaptchaAnswer
+		if err := decode(r, &answer); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusNoContent, nil, s.backend.SubmitCaptcha(r.PathValue("id"), answer)
+	})
+}
+
+// Listen serves the API on address in the background, over TLS when a
+// certificate and key file are given. Shut the returned server down to stop
+// it.
+func (s *Server) Listen(address string, certFile string, keyFile string) (*http.Server, error) {
+	return serve.Listen("API", s, address, certFile, keyFile)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type handler func(r *http.Request) (status int, body any, err error)
+
+// handle registers an authenticated operation under Version.
+func (s *Server) handle(pattern string, h handler) {
+	s.operations = append(s.operations, pattern)
+	method, path, _ := strings.Cut(pattern, " ")
+	s.mux.HandleFunc(method+" "+Version+path, func(w http.ResponseWriter, r *http.Request) {
+		name, ok := s.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="CTI-Dashboard"`)
+			writeJSON(w, http.StatusUnauthorized, errorBody{"missing or unknown API key"})
+			return
+		}
+		status, body, err := h(r)
+		if err != nil {
+			status = http.StatusInternalServerError
+			switch {
+			case errors.Is(err, ErrNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, ErrInvalid):
+				status = http.StatusBadRequest
+			default:
+				logger.Error("API operation failed", "error", err, "key", name, "method", r.Method, "path", r.URL.Path)
+			}
+			writeJSON(w, status, errorBody{err.Error()})
+			return
+		}
+		if status == http.StatusNoContent {
+			w.WriteHeader(status)
+			return
+		}
+		writeJSON(w, status, body)
+	})
+}
+
+// job runs a scrape of the forum in the background and answers 202, or
+// waits for it with ?wait=true.
+func (s *Server) job(run func(forumID string) error) handler {
+	return func(r *http.Request) (int, any, error) {
+		forumID := r.PathValue("id")
+		if _, err := s.backend.Forum(forumID); err != nil {
+			return 0, nil, err
+		}
+		if r.URL.Query().Get("wait") == "true" {
+			if err := run(forumID); err != nil {
+				return 0, nil, err
+			}
+			return http.StatusOK, map[string]string{"status": "done"}, nil
+		}
+		s.backend.Background(func() {
+			if err := run(forumID); err != nil {
+				logger.Error("Background scrape failed", "error", err, "forum_id", forumID)
+			}
+		})
+		return http.StatusAccepted, map[string]string{"status": "started"}, nil
+	}
+}
+
+// export serves an export as a file, filtered by the forum_id, post_id,
+// severity, since and tlp query parameters.
+func (s *Server) export(pattern string, contentType string, run func(models.ExportFilter) (string, error)) {
+	s.handle(pattern, func(r *http.Request) (int, any, error) {
+		query := r.URL.Query()
+		filter := models.ExportFilter{
+			ForumIDs:   list(query["forum_id"]),
+			PostIDs:    list(query["post_id"]),
+			Severities: list(query["severity"]),
+			Since:      query.Get("since"),
+			TLP:        query.Get("tlp"),
+		}
+		data, err := run(filter)
+		if errors.Is(err, export.ErrFilter) {
+			return 0, nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, raw{contentType, data}, nil
+	})
+}
+
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+	key := r.Header.Get("X-API-Key")
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		key = token
+	}
+	if key == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(key))
+	for known, name := range s.keys {
+		if subtle.ConstantTimeCompare(sum[:], known[:]) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// raw is a response body written as is.
+type raw struct {
+	contentType string
+	data        string
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	if r, ok := body.(raw); ok {
+		w.Header().Set("Content-Type", r.contentType)
+		w.WriteHeader(status)
+		w.Write([]byte(r.data))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("Could not write API response", "error", err)
+	}
+}
+
+func decode(r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return nil
+}
+
+// list splits comma-separated query values.
+func list(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// nonNil makes empty lists encode as [] rather than null.
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeBackend keeps forums in memory and fails every other operation.
+type fakeBackend struct {
+	Backend
+	forums map[string]models.Forum
+}
+
+func (b *fakeBackend) Forums() ([]models.Forum, error) {
+	var list []models.Forum
+	for _, forum := range b.forums {
+		list = append(list, forum)
+	}
+	return list, nil
+}
+
+func (b *fakeBackend) Forum(forumID string) (models.Forum, error) {
+	forum, ok := b.forums[forumID]
+	if !ok {
+		return models.Forum{}, fmt.Errorf("forum %s: %w", forumID, ErrNotFound)
+	}
+	return forum, nil
+}
+
+func (b *fakeBackend) CreateForum(forum models.Forum) (models.Forum, error) {
+	if forum.ForumName == "" {
+		return models.Forum{}, fmt.Errorf("%w: forum name cannot be empty", ErrInvalid)
+	}
+	forum.ForumID = fmt.Sprintf("f%d", len(b.forums)+1)
+	b.forums[forum.ForumID] = forum
+	return forum, nil
+}
+
+func (b *fakeBackend) DeleteForum(forumID string) error {
+	if _, err := b.Forum(forumID); err != nil {
+		return err
+	}
+	delete(b.forums, forumID)
+	return nil
+}
+
+func (b *fakeBackend) Background(job func()) {
+	go job()
+}
+
+func (b *fakeBackend) ExportSTIX(filter models.ExportFilter) (string, error) {
+	return fmt.Sprintf(`{"type":"bundle","forums":%q}`, strings.Join(filter.ForumIDs, " ")), nil
+}
+
+func (b *fakeBackend) ExportCSV(filter models.ExportFilter) (string, error) {
+	return "post_id\n" + strings.Join(filter.PostIDs, "\n") + "\n", nil
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	if err := logger.Init(filepath.Join(t.TempDir(), "api.log")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	server := httptest.NewServer(NewServer(&fakeBackend{forums: map[string]models.Forum{}}, map[string]string{"ci": "secret"}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func call(t *testing.T, server *httptest.Server, method string, path string, body string, key string) (*http.Response, string) {
+	t.Helper()
+	request, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "" {
+		request.Header.Set("Authorization", "Bearer "+key)
+	}
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response, string(data)
+}
+
+func TestAuthentication(t *testing.T) {
+	server := newTestServer(t)
+	for _, key := range []string{"", "wrong"} {
+		if response, _ := call(t, server, http.MethodGet, "/v1/forums", "", key); response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("key %q: status %d, want 401", key, response.StatusCode)
+		}
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/forums", nil)
+	request.Header.Set("X-API-Key", "secret")
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("X-API-Key: status %d, want 200", response.StatusCode)
+	}
+
+	if response, _ := call(t, server, http.MethodGet, "/openapi.json", "", ""); response.StatusCode != http.StatusOK {
+		t.Errorf("OpenAPI document: status %d, want 200 without a key", response.StatusCode)
+	}
+}
+
+func TestForums(t *testing.T) {
+	server := newTestServer(t)
+
+	response, body := call(t, server, http.MethodPost, "/v1/forums", `{"forum_name": "Bazaar", "forum_url": "http://bazaar.onion/"}`, "secret")
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", response.StatusCode, body)
+	}
+	var created models.Forum
+	if err := json.Unmarshal([]byte(body), &created); err != nil || created.ForumID == "" {
+		t.Fatalf("create: %s", body)
+	}
+
+	if response, body := call(t, server, http.MethodPost, "/v1/forums", `{"forum_url": "http://x.onion/"}`, "secret"); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("create without name: status %d: %s", response.StatusCode, body)
+	}
+	if response, body := call(t, server, http.MethodPost, "/v1/forums", `{"forum_name": "X", "unknown": 1}`, "secret"); response.StatusCode != http.StatusBadRequest {
+		t.Errorf("create with unknown field: status %d: %s", response.StatusCode, body)
+	}
+
+	if response, _ := call(t, server, http.MethodGet, "/v1/forums/"+created.ForumID, "", "secret"); response.StatusCode != http.StatusOK {
+		t.Errorf("get: status %d", response.StatusCode)
+	}
+	if response, _ := call(t, server, http.MethodDelete, "/v1/forums/"+created.ForumID, "", "secret"); response.StatusCode != http.StatusNoContent {
+		t.Errorf("delete: status %d", response.StatusCode)
+	}
+	if response, _ := call(t, server, http.MethodGet, "/v1/forums/"+created.ForumID, "", "secret"); response.StatusCode != http.StatusNotFound {
+		t.Errorf("get deleted: status %d, want 404", response.StatusCode)
+	}
+	if response, _ := call(t, server, http.MethodPost, "/v1/forums/"+created.ForumID+"/scrape", "", "secret"); response.StatusCode != http.StatusNotFound {
+		t.Errorf("scrape deleted: status %d, want 404", response.StatusCode)
+	}
+
+	response, body = call(t, server, http.MethodGet, "/v1/forums", "", "secret")
+	if response.StatusCode != http.StatusOK || strings.TrimSpace(body) != "[]" {
+		t.Errorf("list after delete: status %d: %s", response.StatusCode, body)
+	}
+}
+
+func TestExportFilter(t *testing.T) {
+	server := newTestServer(t)
+	response, body := call(t, server, http.MethodGet, "/v1/exports/stix?forum_id=a,b&forum_id=c", "", "secret")
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", response.StatusCode, body)
+	}
+	if got := response.Header.Get("Content-Type"); got != "application/stix+json;version=2.1" {
+		t.Errorf("content type %q", got)
+	}
+	if !strings.Contains(body, `"forums":"a b c"`) {
+		t.Errorf("forum filter not passed on: %s", body)
+	}
+
+	response, body = call(t, server, http.MethodGet, "/v1/exports/csv?post_id=p1&post_id=p2", "", "secret")
+	if response.StatusCode != http.StatusOK || body != "post_id\np1\np2\n" {
+		t.Fatalf("status %d: %s", response.StatusCode, body)
+	}
+	if got := response.Header.Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("content type %q", got)
+	}
+}
+
+// TestOpenAPI checks that the document describes every operation served.
+func TestOpenAPI(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPI, &doc); err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(&fakeBackend{}, nil)
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			request := httptest.NewRequest(strings.ToUpper(method), Version+strings.ReplaceAll(path, "{id}", "x"), nil)
+			if _, pattern := server.mux.Handler(request); pattern == "" {
+				t.Errorf("%s %s is documented but not served", strings.ToUpper(method), path)
+			}
+		}
+	}
+	for _, operation := range server.operations {
+		method, path, _ := strings.Cut(operation, " ")
+		if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+			t.Errorf("%s is served but not documented", operation)
+		}
+	}
+}
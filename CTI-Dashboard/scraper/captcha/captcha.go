@@ -0,0 +1,206 @@
+package captcha
+
+import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+)
+
+// DefaultWait is how long a scan waits for the analyst before giving up.
+const DefaultWait = 10 * time.Minute
+
+var (
+	ErrCancelled = errors.New("CAPTCHA was cancelled by the analyst")
+	ErrTimeout   = errors.New("no answer to the CAPTCHA in time")
+	ErrUnknown   = errors.New("no CAPTCHA is waiting with this ID")
+	// ErrNoAnalyst is returned when nobody can be asked, as in the CLI.
+	ErrNoAnalyst = errors.New("no analyst is there to answer the CAPTCHA")
+)
+
+// Found describes a CAPTCHA detected on a page.
+type Found struct {
+	// Input is the selector of the field the answer is typed into, empty for
+	// challenges solved only by clicking.
+	Input  string
+	Prompt string
+}
+
+// Words that mark challenges asking to click a part of an image.
+var clickHints = []string{"rotated", "click on the", "click the", "select the"}
+
+// Detect looks for a CAPTCHA on the page and returns nil when there is none.
+func Detect(html string) *Found {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil
+	}
+
+	var input *goquery.Selection
+	doc.Find("input").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if mentionsCaptcha(s) && s.AttrOr("type", "text") != "hidden" {
+			input = s
+			return false
+		}
+		return true
+	})
+	image := doc.Find("img, canvas, div").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return mentionsCaptcha(s)
+	}).First()
+	if input == nil && image.Length() == 0 {
+		return nil
+	}
+
+	text := strings.ToLower(doc.Find("body").Text())
+	for _, hint := range clickHints {
+		if strings.Contains(text, hint) {
+			return &Found{Input: inputSelector(input), Prompt: "Click where the CAPTCHA asks, then add any text it needs."}
+		}
+	}
+	if input == nil {
+		// An image without its own field: use the first text field of the
+		// image's form.
+		input = image.Closest("form").Find(`input[type="text"], input:not([type])`).First()
+		if input.Length() == 0 {
+			input = nil
+		}
+	}
+	return &Found{Input: inputSelector(input), Prompt: "Type the characters shown in the CAPTCHA."}
+}
+
+func mentionsCaptcha(s *goquery.Selection) bool {
+	for _, attr := range []string{"name", "id", "class", "src", "alt"} {
+		if strings.Contains(strings.ToLower(s.AttrOr(attr, "")), "captcha") {
+			return true
+		}
+	}
+	return false
+}
+
+func inputSelector(input *goquery.Selection) string {
+	if input == nil {
+		return ""
+	}
+	if name, ok := input.Attr("name"); ok && name != "" {
+		return fmt.Sprintf(`input[name=%q]`, name)
+	}
+	if id, ok := input.Attr("id"); ok && id != "" {
+		return fmt.Sprintf(`input[id=%q]`, id)
+	}
+	return ""
+}
+
+type pending struct {
+	challenge models.CaptchaChallenge
+	answer    chan models.CaptchaAnswer
+	cancel    chan struct{}
+}
+
+// Broker hands CAPTCHAs to the analyst and waits for the answers. The scan
+// that asked blocks in Ask, which pauses that forum's job only.
+type Broker struct {
+	notify  func(event string, challenge models.CaptchaChallenge)
+	wait    time.Duration
+	mu      sync.Mutex
+	pending map[string]*pending
+}
+
+// Events passed to notify.
+const (
+	EventChallenge = "captcha:challenge"
+	EventResolved  = "captcha:resolved"
+)
+
+func NewBroker(notify func(event string, challenge models.CaptchaChallenge), wait time.Duration) *Broker {
+	if wait <= 0 {
+		wait = DefaultWait
+	}
+	return &Broker{notify: notify, wait: wait, pending: make(map[string]*pending)}
+}
+
+// Ask shows the challenge to the analyst and waits for the answer.
+func (b *Broker) Ask(challenge models.CaptchaChallenge) (models.CaptchaAnswer, error) {
+	challenge.ID = uuid.New().String()
+	challenge.CreatedAt = stored.Now()
+	p := &pending{
+		challenge: challenge,
+		answer:    make(chan models.CaptchaAnswer, 1),
+		cancel:    make(chan struct{}),
+	}
+	b.mu.Lock()
+	b.pending[challenge.ID] = p
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, challenge.ID)
+		b.mu.Unlock()
+		resolved := challenge
+		resolved.Screenshot = ""
+		b.notify(EventResolved, resolved)
+	}()
+
+	logger.Info("Waiting for the analyst to solve a CAPTCHA", "forum_id", challenge.ForumID, "url", challenge.URL)
+	b.notify(EventChallenge, challenge)
+
+	timer := time.NewTimer(b.wait)
+	defer timer.Stop()
+	select {
+	case answer := <-p.answer:
+		return answer, nil
+	case <-p.cancel:
+		return models.CaptchaAnswer{}, ErrCancelled
+	case <-timer.C:
+		return models.CaptchaAnswer{}, ErrTimeout
+	}
+}
+
+// Answer resumes the scan waiting on the challenge.
+func (b *Broker) Answer(id string, answer models.CaptchaAnswer) error {
+	b.mu.Lock()
+	p, ok := b.pending[id]
+	if ok {
+		delete(b.pending, id)
+	}
+	b.mu.Unlock()
+	if !ok {
+		return ErrUnknown
+	}
+	p.answer <- answer
+	return nil
+}
+
+func (b *Broker) Cancel(id string) error {
+	b.mu.Lock()
+	p, ok := b.pending[id]
+	if ok {
+		delete(b.pending, id)
+	}
+	b.mu.Unlock()
+	if !ok {
+		return ErrUnknown
+	}
+	close(p.cancel)
+	return nil
+}
+
+// Pending lists the challenges waiting for an answer, oldest first.
+func (b *Broker) Pending() []models.CaptchaChallenge {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	challenges := make([]models.CaptchaChallenge, 0, len(b.pending))
+	for _, p := range b.pending {
+		challenges = append(challenges, p.challenge)
+	}
+	sort.Slice(challenges, func(i, j int) bool {
+		return challenges[i].CreatedAt < challenges[j].CreatedAt
+	})
+	return challenges
+}
@@ -0,0 +1,316 @@
+package changes
+
+import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/extractor"
+	"CTI-Dashboard/scraper/logger"
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Change kinds. A thread that drops off the forum page may just have been
+// pushed to a later page, so it is only reported as unlisted; a thread is
+// deleted when its own page is gone.
+const (
+	KindThreadAdded    = "thread_added"
+	KindThreadRenamed  = "thread_renamed"
+	KindThreadUnlisted = "thread_unlisted"
+	KindPostEdited     = "post_edited"
+	KindPostDeleted    = "post_deleted"
+	KindPostRestored   = "post_restored"
+)
+
+// Ref is what a change is observed on: the forum or post, the URL that was
+// scraped and the snapshots before and after.
+type Ref struct {
+	ForumID            string
+	PostID             string
+	TargetURL          string
+	SnapshotID         string
+	PreviousSnapshotID string
+}
+
+// CompareThreads compares the threads listed on a forum page with the ones
+// listed last time, records the changes and keeps the new list. The first
+// list seen for a forum is the baseline and records nothing.
+func CompareThreads(db *sql.DB, ref Ref, forumURL string, body []byte) ([]models.ChangeEvent, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		logger.Error("Could not parse forum page", "error", err, "forum_id", ref.ForumID)
+		return nil, err
+	}
+	current := extractor.ExtractThreads(doc, forumURL)
+	if len(current) == 0 {
+		// Unsupported engine or an empty page: nothing to compare.
+		return nil, nil
+	}
+
+	known, err := knownThreads(db, ref.ForumID)
+	if err != nil {
+		return nil, err
+	}
+	baseline := len(known) == 0
+	now := stored.Now()
+
+	var events []models.ChangeEvent
+	seen := make(map[string]bool)
+	for _, thread := range current {
+		if seen[thread.URL] {
+			continue
+		}
+		seen[thread.URL] = true
+		previous, ok := known[thread.URL]
+		switch {
+		case !ok:
+			_, err = db.Exec(`INSERT INTO forum_threads (forum_id, thread_url, title, first_seen, last_seen, listed) VALUES (?, ?, ?, ?, ?, 1)`,
+				ref.ForumID, thread.URL, thread.Title, now, now)
+			if !baseline {
+				events = append(events, event(ref, KindThreadAdded, thread.URL, thread.Title))
+			}
+		case previous.title != thread.Title && thread.Title != "":
+			_, err = db.Exec(`UPDATE forum_threads SET title = ?, last_seen = ?, listed = 1 WHERE forum_id = ? AND thread_url = ?`,
+				thread.Title, now, ref.ForumID, thread.URL)
+			events = append(events, event(ref, KindThreadRenamed, thread.URL, fmt.Sprintf("%q -> %q", previous.title, thread.Title)))
+		default:
+			_, err = db.Exec(`UPDATE forum_threads SET last_seen = ?, listed = 1 WHERE forum_id = ? AND thread_url = ?`,
+				now, ref.ForumID, thread.URL)
+		}
+		if err != nil {
+			logger.Error("Could not update thread list", "error", err, "thread_url", thread.URL)
+			return nil, err
+		}
+	}
+
+	for threadURL, previous := range known {
+		if seen[threadURL] || !previous.listed {
+			continue
+		}
+		_, err = db.Exec(`UPDATE forum_threads SET listed = 0 WHERE forum_id = ? AND thread_url = ?`, ref.ForumID, threadURL)
+		if err != nil {
+			logger.Error("Could not update thread list", "error", err, "thread_url", threadURL)
+			return nil, err
+		}
+		events = append(events, event(ref, KindThreadUnlisted, threadURL, previous.title))
+	}
+
+	for i := range events {
+		if err := record(db, &events[i]); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// ComparePost compares the text of the first post of a thread, as FirstPost
+// reads it, with the text from the previous scrape and records an edit. Only
+// the text counts, so markup that changes on every load is not an edit. A
+// thread that was reported deleted and is back is recorded as restored.
+func ComparePost(db *sql.DB, ref Ref, before string, after string) ([]models.ChangeEvent, error) {
+	var events []models.ChangeEvent
+	if deleted(db, ref.PostID) {
+		events = append(events, event(ref, KindPostRestored, ref.TargetURL, ""))
+	}
+	if before != "" && after != "" && before != after {
+		events = append(events, event(ref, KindPostEdited, ref.TargetURL, Diff(before, after)))
+	}
+	for i := range events {
+		if err := record(db, &events[i]); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// PostDeleted records that a thread's page is gone, unless that was already
+// the last thing recorded for it. It returns nil when nothing was recorded.
+func PostDeleted(db *sql.DB, ref Ref, reason string) (*models.ChangeEvent, error) {
+	if ref.PostID == "" || deleted(db, ref.PostID) {
+		return nil, nil
+	}
+	change := event(ref, KindPostDeleted, ref.TargetURL, reason)
+	if err := record(db, &change); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// List returns the changes of a forum detected at or after since, newest
+// first. since may be empty, a date, or a date and time (RFC 3339 or
+// "2006-01-02 15:04:05").
+func List(db *sql.DB, forumID string, since string) ([]models.ChangeEvent, error) {
+	from, err := parseSince(since)
+	if err != nil {
+		logger.Error("Could not parse since", "error", err, "since", since)
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT c.event_id, COALESCE(c.forum_id, ''), COALESCE(f.forum_name, ''), COALESCE(c.post_id, ''), c.kind,
+		c.target_url, COALESCE(c.detail, ''), COALESCE(c.snapshot_id, ''), COALESCE(c.previous_snapshot_id, ''), c.detected_at
+		FROM change_events c LEFT JOIN forums f ON f.forum_id = c.forum_id
+		WHERE c.forum_id = ? AND c.detected_at >= ? ORDER BY c.detected_at DESC`, forumID, from)
+	if err != nil {
+		logger.Error("Could not query changes", "error", err, "forum_id", forumID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.ChangeEvent
+	for rows.Next() {
+		var c models.ChangeEvent
+		err := rows.Scan(&c.EventID, &c.ForumID, &c.ForumName, &c.PostID, &c.Kind,
+			&c.TargetURL, &c.Detail, &c.SnapshotID, &c.PreviousSnapshotID, &c.DetectedAt)
+		if err != nil {
+			logger.Error("Could not scan change row", "error", err)
+			continue
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// FirstPost is the text of the opening post of a thread page, or empty when
+// the engine's markup is not recognised.
+func FirstPost(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	for _, selector := range []string{
+		"article.message--post div.bbWrapper", // XenForo
+		"div.bbWrapper",
+		"div.postbody div.content", // phpBB
+	} {
+		if post := doc.Find(selector).First(); post.Length() > 0 {
+			return Text(post)
+		}
+	}
+	return ""
+}
+
+// SnapshotText is what two snapshots of a page are diffed on: the listed
+// threads of a forum page, the first post of a thread, or the visible page
+// text when the engine's markup is not recognised.
+func SnapshotText(snapshot models.Snapshot, body []byte) string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	if snapshot.Kind == "post" {
+		if post := FirstPost(body); post != "" {
+			return post
+		}
+	} else if threads := extractor.ExtractThreads(doc, snapshot.TargetURL); len(threads) > 0 {
+		lines := make([]string, 0, len(threads))
+		for _, thread := range threads {
+			lines = append(lines, thread.Title+" <"+thread.URL+">")
+		}
+		return strings.Join(lines, "\n")
+	}
+	return Text(doc.Find("body"))
+}
+
+// Text is the visible text of a selection, one trimmed line per line of
+// markup, without blank lines.
+func Text(s *goquery.Selection) string {
+	s = s.Clone()
+	s.Find("script, style, noscript").Remove()
+	s.Find("br").ReplaceWithHtml("\n")
+	s.Find("p, div, li, tr, h1, h2, h3, h4, h5, h6, blockquote").Each(func(i int, block *goquery.Selection) {
+		block.AppendHtml("\n")
+	})
+	var lines []string
+	for _, line := range strings.Split(s.Text(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type knownThread struct {
+	title  string
+	listed bool
+}
+
+func knownThreads(db *sql.DB, forumID string) (map[string]knownThread, error) {
+	rows, err := db.Query(`SELECT thread_url, COALESCE(title, ''), listed FROM forum_threads WHERE forum_id = ?`, forumID)
+	if err != nil {
+		logger.Error("Could not query thread list", "error", err, "forum_id", forumID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	known := make(map[string]knownThread)
+	for rows.Next() {
+		var threadURL string
+		var thread knownThread
+		if err := rows.Scan(&threadURL, &thread.title, &thread.listed); err != nil {
+			logger.Error("Could not scan thread row", "error", err)
+			continue
+		}
+		known[threadURL] = thread
+	}
+	return known, rows.Err()
+}
+
+// deleted reports whether the last deletion or restore recorded for the post
+// was a deletion.
+func deleted(db *sql.DB, postID string) bool {
+	if postID == "" {
+		return false
+	}
+	var kind string
+	err := db.QueryRow(`SELECT kind FROM change_events WHERE post_id = ? AND kind IN (?, ?) ORDER BY detected_at DESC LIMIT 1`,
+		postID, KindPostDeleted, KindPostRestored).Scan(&kind)
+	return err == nil && kind == KindPostDeleted
+}
+
+func event(ref Ref, kind string, targetURL string, detail string) models.ChangeEvent {
+	return models.ChangeEvent{
+		ForumID:            ref.ForumID,
+		PostID:             ref.PostID,
+		Kind:               kind,
+		TargetURL:          targetURL,
+		Detail:             detail,
+		SnapshotID:         ref.SnapshotID,
+		PreviousSnapshotID: ref.PreviousSnapshotID,
+	}
+}
+
+func record(db *sql.DB, change *models.ChangeEvent) error {
+	change.EventID = uuid.New().String()
+	change.DetectedAt = stored.Now()
+	_, err := db.Exec(`INSERT INTO change_events (event_id, forum_id, post_id, kind, target_url, detail, snapshot_id, previous_snapshot_id, detected_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		change.EventID, stored.Nullable(change.ForumID), stored.Nullable(change.PostID), change.Kind, change.TargetURL, stored.Nullable(change.Detail),
+		stored.Nullable(change.SnapshotID), stored.Nullable(change.PreviousSnapshotID), change.DetectedAt)
+	if err != nil {
+		logger.Error("Could not record change", "error", err, "kind", change.Kind, "target", change.TargetURL)
+		return err
+	}
+	logger.Info("Detected change", "forum_id", change.ForumID, "kind", change.Kind, "target", change.TargetURL)
+	return nil
+}
+
+func parseSince(since string) (string, error) {
+	since = strings.TrimSpace(since)
+	if since == "" {
+		return "", nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, since); err == nil {
+			return stored.Time(t), nil
+		}
+	}
+	return "", fmt.Errorf("invalid time %q", since)
+}
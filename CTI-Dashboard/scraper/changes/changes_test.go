@@ -0,0 +1,181 @@
+package changes
+
+import (
+	"CTI-Dashboard/db/migrations"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func open(t *testing.T) *sql.DB {
+	t.Helper()
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "changes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Apply(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO forums (forum_id, forum_name, forum_url) VALUES ('f1', 'Exploit Bazaar', 'http://bazaar.onion/')`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		before string
+		after  string
+		want   string
+	}{
+		{"a\nb\nc", "a\nb\nc", "  a\n  b\n  c\n"},
+		{"a\nb\nc", "a\nx\nc", "  a\n- b\n+ x\n  c\n"},
+		{"a\nc", "a\nb\nc", "  a\n+ b\n  c\n"},
+		{"a\nb", "b", "- a\n  b\n"},
+		{"", "a", "+ a\n"},
+		{"a", "", "- a\n"},
+	}
+	for _, test := range tests {
+		if got := Diff(test.before, test.after); got != test.want {
+			t.Errorf("Diff(%q, %q) = %q, want %q", test.before, test.after, got, test.want)
+		}
+	}
+}
+
+// xenForo and phpBB render a forum page listing the given thread titles,
+// keyed by thread number.
+func xenForo(threads map[int]string) []byte {
+	var b strings.Builder
+	b.WriteString(`<html><body>`)
+	for _, n := range sorted(threads) {
+		b.WriteString(`<div class="structItem-title"><a href="/threads/t.` + itoa(n) + `/">` + threads[n] + `</a></div>`)
+	}
+	b.WriteString(`</body></html>`)
+	return []byte(b.String())
+}
+
+func phpBB(threads map[int]string) []byte {
+	var b strings.Builder
+	b.WriteString(`<html><body id="phpbb"><ul class="topiclist">`)
+	for _, n := range sorted(threads) {
+		b.WriteString(`<li><a href="./viewtopic.php?t=` + itoa(n) + `&amp;sid=0123abcd" class="topictitle">` + threads[n] + `</a></li>`)
+	}
+	b.WriteString(`</ul></body></html>`)
+	return []byte(b.String())
+}
+
+func sorted(threads map[int]string) []int {
+	var keys []int
+	for n := range threads {
+		keys = append(keys, n)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func itoa(n int) string {
+	return string(rune('0' + n))
+}
+
+func kinds(events []models.ChangeEvent) string {
+	var list []string
+	for _, e := range events {
+		list = append(list, e.Kind+" "+e.TargetURL)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
+
+func TestCompareThreads(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    func(map[int]string) []byte
+		forum   string
+		threads func(n int) string
+	}{
+		{"XenForo", xenForo, "http://bazaar.onion/forums/market/", func(n int) string { return "http://bazaar.onion/threads/t." + itoa(n) + "/" }},
+		{"phpBB", phpBB, "http://bazaar.onion/viewforum.php?f=2", func(n int) string { return "http://bazaar.onion/viewtopic.php?t=" + itoa(n) }},
+	}
+	for _, test := range tests {
+		db := open(t)
+		ref := Ref{ForumID: "f1", TargetURL: test.forum}
+
+		// The first list is the baseline.
+		events, err := CompareThreads(db, ref, test.forum, test.page(map[int]string{1: "Dumps", 2: "Logs"}))
+		if err != nil || len(events) != 0 {
+			t.Fatalf("%s baseline: %v, %v", test.name, events, err)
+		}
+		events, err = CompareThreads(db, ref, test.forum, test.page(map[int]string{1: "Dumps with PIN", 3: "RDP"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Join([]string{
+			KindThreadAdded + " " + test.threads(3),
+			KindThreadRenamed + " " + test.threads(1),
+			KindThreadUnlisted + " " + test.threads(2),
+		}, ", ")
+		if got := kinds(events); got != want {
+			t.Errorf("%s: got %s, want %s", test.name, got, want)
+		}
+
+		// Nothing changed: nothing is recorded, and the unlisted thread is
+		// not reported again.
+		events, err = CompareThreads(db, ref, test.forum, test.page(map[int]string{1: "Dumps with PIN", 3: "RDP"}))
+		if err != nil || len(events) != 0 {
+			t.Errorf("%s unchanged: %v, %v", test.name, events, err)
+		}
+		var recorded int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM change_events WHERE forum_id = 'f1'`).Scan(&recorded); err != nil || recorded != 3 {
+			t.Errorf("%s: %d changes recorded, want 3 (%v)", test.name, recorded, err)
+		}
+	}
+}
+
+func TestComparePost(t *testing.T) {
+	db := open(t)
+	ref := Ref{ForumID: "f1", PostID: "p1", TargetURL: "http://bazaar.onion/threads/t.1/"}
+	page := func(token string, post string) []byte {
+		return []byte(`<html><body><input name="_xfToken" value="` + token + `">
+			<article class="message message--post"><div class="bbWrapper">` + post + `</div></article></body></html>`)
+	}
+
+	// Markup that changes on every load is not an edit.
+	before, after := FirstPost(page("111", "Fresh dumps<br>PM me")), FirstPost(page("222", "Fresh   dumps<br> PM me"))
+	events, err := ComparePost(db, ref, before, after)
+	if err != nil || len(events) != 0 {
+		t.Errorf("same text: %v, %v", events, err)
+	}
+
+	after = FirstPost(page("333", "Fresh dumps<br>Sold out"))
+	events, err = ComparePost(db, ref, before, after)
+	if err != nil || len(events) != 1 || events[0].Kind != KindPostEdited {
+		t.Fatalf("edit: %v, %v", events, err)
+	}
+	if events[0].Detail != "  Fresh dumps\n- PM me\n+ Sold out\n" {
+		t.Errorf("detail %q", events[0].Detail)
+	}
+
+	if _, err := PostDeleted(db, ref, "404 Not Found"); err != nil {
+		t.Fatal(err)
+	}
+	events, err = ComparePost(db, ref, after, after)
+	if err != nil || len(events) != 1 || events[0].Kind != KindPostRestored {
+		t.Errorf("restore: %v, %v", events, err)
+	}
+
+	// A thread seen for the first time has nothing to compare with.
+	if events, err := ComparePost(db, Ref{ForumID: "f1", PostID: "p2"}, "", after); err != nil || len(events) != 0 {
+		t.Errorf("first scrape: %v, %v", events, err)
+	}
+}
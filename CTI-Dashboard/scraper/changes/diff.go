@@ -0,0 +1,77 @@
+package changes
+
+import (
+	"strings"
+)
+
+// maxDiffCells bounds the table of the line diff. Texts too long for it are
+// shown as fully replaced.
+const maxDiffCells = 4_000_000
+
+// Diff is a line diff of two texts: unchanged lines are prefixed with two
+// spaces, removed ones with "- " and added ones with "+ ".
+func Diff(before string, after string) string {
+	a, b := splitLines(before), splitLines(after)
+	var out strings.Builder
+	write := func(prefix string, line string) {
+		out.WriteString(prefix)
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			write("- ", line)
+		}
+		for _, line := range b {
+			write("+ ", line)
+		}
+		return out.String()
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			write("  ", a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			write("- ", a[i])
+			i++
+		default:
+			write("+ ", b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		write("- ", a[i])
+	}
+	for ; j < len(b); j++ {
+		write("+ ", b[j])
+	}
+	return out.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,205 @@
+package classifier
+
+import (
+	"CTI-Dashboard/scraper/captcha"
+	"bytes"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Page classes. Anything but ClassOK is not real forum content and must not
+// be stored as a snapshot.
+const (
+	ClassOK        = "ok"
+	ClassSeized    = "seized"
+	ClassBlocked   = "blocked"
+	ClassChallenge = "challenge"
+	ClassLoginWall = "login_wall"
+	ClassDown      = "down"
+	ClassNotFound  = "not_found"
+)
+
+// Rule is a signature for one class. A rule matches when any of its Titles
+// appears in the page title, or any of its Phrases in the page text if that
+// is at most MaxText characters long. The limit keeps a forum thread that
+// merely discusses a seizure or a DDoS from matching, so only phrases no
+// forum would use in its own title go in Titles.
+//
+// A ForumOnly rule is not tried on thread pages: a short thread about a
+// takedown or a members-only section reads like the banner it discusses.
+type Rule struct {
+	Name      string
+	Class     string
+	Phrases   []string
+	Titles    []string
+	MaxText   int
+	ForumOnly bool
+}
+
+// Rules are tried in order; the first match wins.
+var Rules = []Rule{
+	{
+		Name:  "seizure-banner",
+		Class: ClassSeized,
+		Phrases: []string{
+			"this hidden site has been seized", "this site has been seized", "this domain has been seized",
+			"this forum has been seized", "has been seized by", "seized as part of", "seizure notice",
+			"law enforcement action", "joint law enforcement operation",
+		},
+		Titles: []string{
+			"this hidden site has been seized", "this site has been seized", "this domain has been seized",
+			"this forum has been seized", "seizure notice",
+		},
+		MaxText:   2500,
+		ForumOnly: true,
+	},
+	{
+		Name:  "thread-gone",
+		Class: ClassNotFound,
+		Phrases: []string{
+			"the requested thread could not be found", "the requested topic does not exist",
+			"the requested post does not exist", "this thread has been deleted", "this topic has been deleted",
+			"the requested page could not be found",
+		},
+		MaxText: 5000,
+	},
+	{
+		Name:  "ddos-queue",
+		Class: ClassBlocked,
+		Phrases: []string{
+			"ddos-guard", "ddos guard", "you are in the queue", "queue position", "you have been placed in a queue",
+			"anti-ddos", "too many requests", "rate limit exceeded", "access denied", "403 forbidden",
+			"your ip has been banned", "you have been blocked",
+		},
+		Titles:    []string{"ddos-guard", "403 forbidden", "429 too many requests"},
+		MaxText:   3000,
+		ForumOnly: true,
+	},
+	{
+		Name:  "browser-challenge",
+		Class: ClassChallenge,
+		Phrases: []string{
+			"checking your browser", "just a moment...", "please enable javascript and cookies",
+			"verify you are human", "prove you are human", "complete the security check",
+		},
+		Titles:  []string{"just a moment...", "attention required!"},
+		MaxText: 3000,
+	},
+	{
+		Name:  "login-wall",
+		Class: ClassLoginWall,
+		Phrases: []string{
+			"you must be logged in", "you must be logged-in", "you must be registered",
+			"requires you to be registered and logged in", "you need to log in", "please log in to continue",
+			"login required", "members only",
+		},
+		MaxText:   3000,
+		ForumOnly: true,
+	},
+	{
+		Name:  "down-notice",
+		Class: ClassDown,
+		Phrases: []string{
+			"forum is down", "site is down", "under maintenance", "down for maintenance", "temporarily unavailable",
+			"temporarily offline", "we'll be back", "we will be back", "502 bad gateway", "503 service unavailable",
+			"504 gateway", "service unavailable", "site is offline",
+		},
+		Titles:  []string{"502 bad gateway", "503 service unavailable", "504 gateway time-out"},
+		MaxText: 2000,
+	},
+}
+
+// Result is the class of a page and the rule that decided it.
+type Result struct {
+	Class string
+	Rule  string
+}
+
+// Classify sorts a page fetched with a 200 into real content or one of the
+// junk classes. forum is false for a thread page, which only the rules that
+// are not ForumOnly are tried on.
+func Classify(body []byte, forum bool) Result {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return Result{Class: ClassOK}
+	}
+	title := strings.ToLower(strings.TrimSpace(doc.Find("title").First().Text()))
+	doc.Find("script, style, noscript").Remove()
+	text := strings.ToLower(strings.Join(strings.Fields(doc.Find("body").Text()), " "))
+
+	for _, rule := range Rules {
+		if (forum || !rule.ForumOnly) && rule.matches(title, text) {
+			return Result{Class: rule.Class, Rule: rule.Name}
+		}
+	}
+	if captcha.Detect(string(body)) != nil {
+		return Result{Class: ClassChallenge, Rule: "captcha"}
+	}
+	// A forum page that is little more than a password field is a login form.
+	if forum && doc.Find(`input[type="password"]`).Length() > 0 && len(text) < 1500 {
+		return Result{Class: ClassLoginWall, Rule: "login-form"}
+	}
+	return Result{Class: ClassOK}
+}
+
+func (r Rule) matches(title string, text string) bool {
+	for _, phrase := range r.Titles {
+		if strings.Contains(title, phrase) {
+			return true
+		}
+	}
+	if r.MaxText > 0 && len(text) > r.MaxText {
+		return false
+	}
+	for _, phrase := range r.Phrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// Title is the page title, used to notice a forum changing hands.
+func Title(body []byte) string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(doc.Find("title").First().Text())
+}
+
+// Phrases with which new owners or exit-scamming admins announce a handover.
+var handoverPhrases = []string{
+	"under new management", "new administration", "new owner", "this domain is for sale",
+	"domain for sale", "has been acquired", "moved to a new address", "is now operated by",
+}
+
+// HandoverNotice returns the handover phrase found in the page's title or
+// opening text, if any.
+func HandoverNotice(body []byte) string {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	doc.Find("script, style, noscript").Remove()
+	text := strings.ToLower(doc.Find("title").First().Text() + " " + strings.Join(strings.Fields(doc.Find("body").Text()), " "))
+	if len(text) > 2000 {
+		text = text[:2000]
+	}
+	for _, phrase := range handoverPhrases {
+		if strings.Contains(text, phrase) {
+			return phrase
+		}
+	}
+	return ""
+}
+
+// Transient reports whether a page class usually clears up on a retry.
+func Transient(class string) bool {
+	switch class {
+	case ClassBlocked, ClassChallenge, ClassDown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package classifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func page(title string, body string) []byte {
+	return []byte("<html><head><title>" + title + "</title></head><body>" + body + "</body></html>")
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  []byte
+		forum bool
+		want  string
+	}{
+		{"seizure banner", page("Seized", "<h1>This hidden site has been seized</h1><p>by the FBI as part of a joint law enforcement operation.</p>"), true, ClassSeized},
+		{"seizure title", page("This Domain Has Been Seized", strings.Repeat("<p>Europol Eurojust BKA</p>", 200)), true, ClassSeized},
+		{"thread about a seizure", page("Exploit Bazaar has been seized by the FBI", "<h1>Exploit Bazaar has been seized by the FBI</h1><p>Saw the banner this morning, after a law enforcement action.</p>"), false, ClassOK},
+		{"long forum page about a seizure", page("Carder Hub", "<p>Breach has been seized by the FBI.</p>"+strings.Repeat("<p>thread listing</p>", 300)), true, ClassOK},
+		{"ddos queue", page("Please wait", "<p>DDoS-Guard: you are in the queue, queue position 12.</p>"), true, ClassBlocked},
+		{"thread mentioning access denied", page("Access denied on checkout?", "<p>Getting access denied when I pay, anyone else?</p>"), false, ClassOK},
+		{"forum named access denied", page("Access Denied Forum", "<p>Welcome</p>"+strings.Repeat("<p>latest threads</p>", 300)), true, ClassOK},
+		{"login wall", page("Exploit Bazaar", "<p>You must be logged in to view this page.</p>"), true, ClassLoginWall},
+		{"members only thread", page("Members Only section rules", "<p>Post in members only, no leeching.</p>"), false, ClassOK},
+		{"members only forum", page("Members Only", "<p>Welcome back</p>"+strings.Repeat("<p>latest threads</p>", 300)), true, ClassOK},
+		{"login form", page("Log in", `<form><input name="login"><input type="password" name="password"></form>`), true, ClassLoginWall},
+		{"thread with a login box", page("Selling RDP", `<p>Fresh panels</p><form><input type="password"></form>`), false, ClassOK},
+		{"browser challenge thread", page("Just a moment...", "<p>Checking your browser before accessing the site.</p>"), false, ClassChallenge},
+		{"thread gone", page("Oops", "<p>The requested thread could not be found.</p>"), false, ClassNotFound},
+		{"bad gateway", page("502 Bad Gateway", "<center>nginx</center>"), false, ClassDown},
+		{"forum content", page("Exploit Bazaar", "<p>Latest threads</p>"), true, ClassOK},
+	}
+	for _, test := range tests {
+		if got := Classify(test.body, test.forum); got.Class != test.want {
+			t.Errorf("%s: got %s (rule %s), want %s", test.name, got.Class, got.Rule, test.want)
+		}
+	}
+}
+
+func TestHandoverNotice(t *testing.T) {
+	if got := HandoverNotice(page("Bazaar", "<p>The forum is under new management.</p>")); got != "under new management" {
+		t.Errorf("got %q", got)
+	}
+	body := page("Bazaar", strings.Repeat("<p>thread listing</p>", 200)+"<p>under new management</p>")
+	if got := HandoverNotice(body); got != "" {
+		t.Errorf("notice past the opening text: got %q", got)
+	}
+}
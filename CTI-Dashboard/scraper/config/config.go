@@ -3,18 +3,67 @@ package config
 import "time"
 
 type Config struct {
-	// Input
-	TargetFile string
+	// Input. DBPath is the SQLite database.
+	TargetFile string `yaml:"target_file"`
+	DBPath     string `yaml:"db_path"`
 
-	// Network
-	TorProxy   string
-	Timeout    time.Duration
-	MaxRetries int
+	// Network. Proxies lists every proxy endpoint available to the pool; when
+	// empty a single Tor endpoint at TorProxy is used.
+	Proxies       []ProxyEndpoint `yaml:"proxies,omitempty"`
+	ProxyStrategy string          `yaml:"proxy_strategy"`
+	TorProxy      string          `yaml:"tor_proxy"`
+	Timeout       time.Duration   `yaml:"timeout"`
+	MaxRetries    int             `yaml:"max_retries"`
 
-	// Output
-	OutputDir  string
-	ReportFile string
+	// Tor control port, used for NEWNYM and bootstrap status. Leave
+	// TorControl empty to disable it.
+	TorControl         string `yaml:"tor_control"`
+	TorControlPassword string `yaml:"tor_control_password"`
+	TorControlCookie   string `yaml:"tor_control_cookie"`
+
+	// Connectivity check run before scraping: torproject, bootstrap, canary
+	// or none. Results are cached for ConnectivityTTL.
+	ConnectivityCheck string        `yaml:"connectivity_check"`
+	CanaryURL         string        `yaml:"canary_url"`
+	ConnectivityTTL   time.Duration `yaml:"connectivity_ttl"`
+
+	// Headless browser used for screenshots: tabs open at once and pages
+	// rendered before Chrome is restarted.
+	BrowserTabs    int `yaml:"browser_tabs"`
+	BrowserRecycle int `yaml:"browser_recycle"`
+
+	// Output. WARCRotation is "run" or "day" to archive every fetch as WARC
+	// files under OutputDir/warc, empty to turn WARC output off.
+	OutputDir    string `yaml:"output_dir"`
+	ReportFile   string `yaml:"report_file"`
+	WARCRotation string `yaml:"warc_rotation"`
 
 	// Workers
-	Workers int
+	Workers int `yaml:"workers"`
+
+	// Embedded TAXII 2.1 server. Leave TAXIIAddress empty to disable it.
+	// TAXIIKeys maps basic-auth user names to API keys; TLS is used when a
+	// certificate and key file are set.
+	TAXIIAddress  string            `yaml:"taxii_address"`
+	TAXIIKeys     map[string]string `yaml:"taxii_keys,omitempty"`
+	TAXIICertFile string            `yaml:"taxii_cert_file"`
+	TAXIIKeyFile  string            `yaml:"taxii_key_file"`
+
+	// REST API, served alongside the window or on its own with -headless.
+	// APIKeys maps key names to API keys; APICertFile and APIKeyFile are the
+	// TLS certificate and its private key.
+	APIAddress  string            `yaml:"api_address"`
+	APIKeys     map[string]string `yaml:"api_keys,omitempty"`
+	APICertFile string            `yaml:"api_cert_file"`
+	APIKeyFile  string            `yaml:"api_key_file"`
+}
+
+// ProxyEndpoint is one proxy of the pool. Type is one of tor, i2p, socks5,
+// http or direct.
+type ProxyEndpoint struct {
+	Name     string `yaml:"name,omitempty"`
+	Type     string `yaml:"type,omitempty"`
+	Address  string `yaml:"address,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
 }
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func write(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		want    func(*Config)
+		wantErr string
+	}{
+		{name: "missing file", want: func(c *Config) {}},
+		{name: "empty file", file: "", want: func(c *Config) {}},
+		{name: "file over defaults", file: "timeout: 45s\nworkers: 2\nproxies:\n  - type: socks5\n    address: 127.0.0.1:1080\n",
+			want: func(c *Config) {
+				c.Timeout, c.Workers = 45*time.Second, 2
+				c.Proxies = []ProxyEndpoint{{Type: "socks5", Address: "127.0.0.1:1080"}}
+			}},
+		{name: "environment over file", file: "timeout: 45s\n", env: map[string]string{"CTI_TIMEOUT": "1m", "CTI_API_KEYS": "ci=secret"},
+			want: func(c *Config) { c.Timeout, c.APIKeys = time.Minute, map[string]string{"ci": "secret"} }},
+		{name: "unknown option", file: "timout: 45s\n", wantErr: "field timout not found"},
+		{name: "invalid value", file: "workers: 0\n", wantErr: "workers must be at least 1"},
+		{name: "bad environment", env: map[string]string{"CTI_WORKERS": "many"}, wantErr: "CTI_WORKERS"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "missing.yaml")
+		if test.name != "missing file" {
+			path = write(t, test.file)
+		}
+		for key, value := range test.env {
+			t.Setenv(key, value)
+		}
+		cfg, err := Load(path)
+		for key := range test.env {
+			os.Unsetenv(key)
+		}
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: err = %v, want %q", test.name, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		want := Default()
+		test.want(&want)
+		if !reflect.DeepEqual(cfg, want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, cfg, want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		env     map[string]string
+		want    func(*Config)
+		wantErr string
+	}{
+		{map[string]string{}, func(c *Config) {}, ""},
+		{map[string]string{"CTI_TOR_PROXY": "10.0.0.2:9050", "CTI_MAX_RETRIES": "5", "CTI_CONNECTIVITY_TTL": "90s"}, func(c *Config) {
+			c.TorProxy, c.MaxRetries, c.ConnectivityTTL = "10.0.0.2:9050", 5, 90*time.Second
+		}, ""},
+		{map[string]string{"CTI_TAXII_KEYS": "misp=k1, opencti=k2,broken,=k3"}, func(c *Config) {
+			c.TAXIIKeys = map[string]string{"misp": "k1", "opencti": "k2"}
+		}, ""},
+		// Unrelated variables and lower-case names are not options.
+		{map[string]string{"CTI_UNKNOWN": "x", "cti_timeout": "1s"}, func(c *Config) {}, ""},
+		{map[string]string{"CTI_TIMEOUT": "soon"}, nil, "CTI_TIMEOUT"},
+		{map[string]string{"CTI_PROXIES": "socks5://a"}, nil, "cannot be set from the environment"},
+	}
+	for _, test := range tests {
+		cfg := Default()
+		err := cfg.applyEnv(func(key string) (string, bool) {
+			value, ok := test.env[key]
+			return value, ok
+		})
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%v: err = %v, want %q", test.env, err, test.wantErr)
+			}
+			continue
+		}
+		want := Default()
+		test.want(&want)
+		if err != nil || !reflect.DeepEqual(cfg, want) {
+			t.Errorf("%v: got %+v, %v", test.env, cfg, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		change func(*Config)
+		want   string
+	}{
+		{func(c *Config) {}, ""},
+		{func(c *Config) { c.WARCRotation = "" }, ""},
+		{func(c *Config) { c.TorProxy, c.Proxies = "", []ProxyEndpoint{{Type: "direct"}} }, ""},
+		{func(c *Config) { c.DBPath = "" }, "db_path must be set"},
+		{func(c *Config) { c.Timeout = 0 }, "timeout must be positive"},
+		{func(c *Config) { c.MaxRetries = -1 }, "max_retries cannot be negative"},
+		{func(c *Config) { c.BrowserTabs = 0 }, "browser_tabs must be at least 1"},
+		{func(c *Config) { c.WARCRotation = "week" }, "warc_rotation must be run, day or empty"},
+		{func(c *Config) { c.TorProxy = "" }, "tor_proxy must be set"},
+		{func(c *Config) { c.APIAddress = "8470" }, "api_address must be host:port"},
+		{func(c *Config) { c.TAXIICertFile = "cert.pem" }, "taxii_cert_file and taxii_key_file must be set together"},
+		// Every problem is reported at once.
+		{func(c *Config) { c.Workers, c.ConnectivityTTL = 0, -time.Second }, "workers must be at least 1, got 0; connectivity_ttl cannot be negative"},
+	}
+	for i, test := range tests {
+		cfg := Default()
+		test.change(&cfg)
+		err := cfg.Validate()
+		if test.want == "" {
+			if err != nil {
+				t.Errorf("%d: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%d: err = %v, want %q", i, err, test.want)
+		}
+	}
+}
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.yaml")
+	cfg := Default()
+	cfg.Timeout = 90 * time.Second
+	cfg.APIKeys = map[string]string{"ci": "secret"}
+	cfg.Proxies = []ProxyEndpoint{{Name: "corp", Type: "http", Address: "10.0.0.3:3128", Username: "u", Password: "p"}}
+	if err := Save(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode %v, want 0600: the file holds keys and passwords", info.Mode().Perm())
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+	read, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, cfg) {
+		t.Errorf("read back %+v, want %+v", read, cfg)
+	}
+
+	// Read leaves the environment out, so Save never writes it back.
+	t.Setenv("CTI_TOR_CONTROL_PASSWORD", "from-env")
+	if read, err := Read(path); err != nil || read.TorControlPassword != "" {
+		t.Errorf("Read took %q from the environment (%v)", read.TorControlPassword, err)
+	}
+}
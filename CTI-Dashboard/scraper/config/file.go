@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override the file: every
+// option can be set as CTI_ and its key in upper case, e.g. CTI_TOR_PROXY or
+// CTI_TIMEOUT=45s. Key maps are given as comma-separated name=key pairs.
+const EnvPrefix = "CTI_"
+
+// Default is the configuration used for options the file does not set.
+func Default() Config {
+	return Config{
+		Timeout:    time.Duration(30) * time.Second,
+		MaxRetries: 3,
+		OutputDir:  "output/",
+		DBPath:     "./db/database.db",
+		TorProxy:   "127.0.0.1:9050",
+		TorControl: "127.0.0.1:9051",
+		TargetFile: "targets.yaml",
+		Workers:    5,
+
+		ConnectivityCheck: "torproject",
+		ConnectivityTTL:   time.Duration(5) * time.Minute,
+
+		BrowserTabs:    4,
+		BrowserRecycle: 50,
+
+		WARCRotation: "day",
+	}
+}
+
+// Load reads the configuration file at path over the defaults, applies the
+// environment overrides and validates the result. A missing file is not an
+// error.
+func Load(path string) (Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Read reads the configuration file at path over the defaults, without the
+// environment overrides, so secrets given in the environment are not written
+// back by Save.
+func Read(path string) (Config, error) {
+	cfg := Default()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes cfg to path, replacing the file.
+func Save(path string, cfg Config) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	// The file may hold API keys and proxy passwords.
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// Validate reports every option that is out of range.
+func (c Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	check(c.DBPath != "", "db_path must be set")
+	check(c.OutputDir != "", "output_dir must be set")
+	check(c.Timeout > 0, "timeout must be positive, got %s", c.Timeout)
+	check(c.MaxRetries >= 0, "max_retries cannot be negative, got %d", c.MaxRetries)
+	check(c.Workers > 0, "workers must be at least 1, got %d", c.Workers)
+	check(c.BrowserTabs > 0, "browser_tabs must be at least 1, got %d", c.BrowserTabs)
+	check(c.BrowserRecycle >= 0, "browser_recycle cannot be negative, got %d", c.BrowserRecycle)
+	check(c.ConnectivityTTL >= 0, "connectivity_ttl cannot be negative, got %s", c.ConnectivityTTL)
+	check(c.WARCRotation == "" || c.WARCRotation == "run" || c.WARCRotation == "day",
+		"warc_rotation must be run, day or empty, got %q", c.WARCRotation)
+	check(c.TorProxy != "" || len(c.Proxies) > 0, "tor_proxy must be set when no proxies are listed")
+	for _, address := range []struct{ key, value string }{
+		{"tor_proxy", c.TorProxy}, {"tor_control", c.TorControl}, {"taxii_address", c.TAXIIAddress}, {"api_address", c.APIAddress},
+	} {
+		if address.value != "" {
+			_, _, err := net.SplitHostPort(address.value)
+			check(err == nil, "%s must be host:port, got %q", address.key, address.value)
+		}
+	}
+	check((c.TAXIICertFile == "") == (c.TAXIIKeyFile == ""), "taxii_cert_file and taxii_key_file must be set together")
+	check((c.APICertFile == "") == (c.APIKeyFile == ""), "api_cert_file and api_key_file must be set together")
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// applyEnv sets the options named by environment variables.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	value := reflect.ValueOf(c).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		key, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("yaml"), ",")
+		env := EnvPrefix + strings.ToUpper(key)
+		raw, ok := lookup(env)
+		if key == "" || !ok {
+			continue
+		}
+		field := value.Field(i)
+		switch {
+		case field.Type() == reflect.TypeOf(time.Duration(0)):
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %w", env, err)
+			}
+			field.SetInt(int64(d))
+		case field.Kind() == reflect.String:
+			field.SetString(raw)
+		case field.Kind() == reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %w", env, err)
+			}
+			field.SetInt(int64(n))
+		case field.Type() == reflect.TypeOf(map[string]string(nil)):
+			field.Set(reflect.ValueOf(ParseKeys(raw)))
+		default:
+			return fmt.Errorf("%s: option cannot be set from the environment", env)
+		}
+	}
+	return nil
+}
+
+// ParseKeys reads keys given as comma-separated name=key pairs.
+func ParseKeys(value string) map[string]string {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && name != "" && key != "" {
+			keys[name] = key
+		}
+	}
+	return keys
+}
@@ -0,0 +1,216 @@
+package connectivity
+
+import (
+	"CTI-Dashboard/scraper/config"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/torcontrol"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	ModeTorProject = "torproject"
+	ModeBootstrap  = "bootstrap"
+	ModeCanary     = "canary"
+	ModeNone       = "none"
+)
+
+const (
+	TorProjectURL = "https://check.torproject.org/api/ip"
+	DefaultTTL    = 5 * time.Minute
+)
+
+// Status is the result of a connectivity check. IP is only known for checks
+// that go out through the exit, such as the check.torproject.org endpoint.
+type Status struct {
+	IP       string
+	IsTor    bool
+	Response string
+}
+
+// Checker decides whether scraping through client can go ahead.
+type Checker interface {
+	Check(client *http.Client) (*Status, error)
+}
+
+// New builds the checker selected by cfg.ConnectivityCheck, cached for
+// cfg.ConnectivityTTL.
+func New(cfg config.Config) (Checker, error) {
+	var checker Checker
+	switch cfg.ConnectivityCheck {
+	case "", ModeTorProject:
+		checker = TorProject{URL: TorProjectURL}
+	case ModeBootstrap:
+		if cfg.TorControl == "" {
+			return nil, errors.New("bootstrap connectivity check needs a Tor control port")
+		}
+		checker = Bootstrap{
+			Addr: cfg.TorControl,
+			Auth: torcontrol.Auth{Password: cfg.TorControlPassword, CookiePath: cfg.TorControlCookie},
+		}
+	case ModeCanary:
+		if cfg.CanaryURL == "" {
+			return nil, errors.New("canary connectivity check needs a canary URL")
+		}
+		checker = Canary{URL: cfg.CanaryURL}
+	case ModeNone:
+		return None{}, nil
+	default:
+		return nil, fmt.Errorf("unknown connectivity check %q", cfg.ConnectivityCheck)
+	}
+
+	ttl := cfg.ConnectivityTTL
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	return NewCached(checker, ttl), nil
+}
+
+// TorProject asks check.torproject.org (or a compatible endpoint) whether the
+// request arrived over Tor.
+type TorProject struct {
+	URL string
+}
+
+func (t TorProject) Check(client *http.Client) (*Status, error) {
+	resp, err := client.Get(t.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	if !status.IsTor {
+		return &status, errors.New("not connected to Tor network")
+	}
+	return &status, nil
+}
+
+// Bootstrap checks that the local Tor daemon finished bootstrapping. It never
+// leaves the machine, so it works offline and in lab setups.
+type Bootstrap struct {
+	Addr string
+	Auth torcontrol.Auth
+}
+
+func (b Bootstrap) Check(client *http.Client) (*Status, error) {
+	controller, err := torcontrol.Connect(b.Addr, b.Auth, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	defer controller.Close()
+
+	bootstrap, err := controller.Bootstrap()
+	if err != nil {
+		return nil, err
+	}
+	if bootstrap.Progress < 100 {
+		return nil, fmt.Errorf("tor is still bootstrapping: %d%% %s", bootstrap.Progress, bootstrap.Summary)
+	}
+	return &Status{IsTor: true, Response: bootstrap.Summary}, nil
+}
+
+// Canary fetches a user supplied onion address that is known to be up.
+type Canary struct {
+	URL string
+}
+
+func (c Canary) Check(client *http.Client) (*Status, error) {
+	resp, err := client.Get(c.URL)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("canary returned status: %s", resp.Status)
+	}
+	return &Status{IsTor: true, Response: resp.Status}, nil
+}
+
+// None skips the check entirely.
+type None struct{}
+
+func (None) Check(client *http.Client) (*Status, error) {
+	return &Status{}, nil
+}
+
+// Cached remembers the result of a checker per proxy endpoint for a TTL, so
+// a sweep over many forums does not repeat the check for every job. Every
+// forum has its own client, but the clients of an endpoint share its
+// connection to Tor. Failures are not cached.
+//
+// Each forum's client has its own Tor circuit, so the exit IP is only
+// reported to the check that measured it; a result from the cache has none.
+type Cached struct {
+	checker Checker
+	ttl     time.Duration
+	mu      sync.Mutex
+	results map[string]cachedStatus
+}
+
+type cachedStatus struct {
+	status    *Status
+	checkedAt time.Time
+}
+
+func NewCached(checker Checker, ttl time.Duration) *Cached {
+	return &Cached{
+		checker: checker,
+		ttl:     ttl,
+		results: make(map[string]cachedStatus),
+	}
+}
+
+// Check checks client, sharing the result with every client that does not
+// name its endpoint.
+func (c *Cached) Check(client *http.Client) (*Status, error) {
+	return c.check("", client)
+}
+
+// Endpoint returns the checker for the clients of the named proxy endpoint.
+func (c *Cached) Endpoint(name string) Checker {
+	return endpoint{cached: c, name: name}
+}
+
+// ForEndpoint returns the checker for the clients of the named proxy
+// endpoint: checker itself unless it caches results.
+func ForEndpoint(checker Checker, name string) Checker {
+	if cached, ok := checker.(*Cached); ok {
+		return cached.Endpoint(name)
+	}
+	return checker
+}
+
+type endpoint struct {
+	cached *Cached
+	name   string
+}
+
+func (e endpoint) Check(client *http.Client) (*Status, error) {
+	return e.cached.check(e.name, client)
+}
+
+func (c *Cached) check(endpoint string, client *http.Client) (*Status, error) {
+	c.mu.Lock()
+	cached, ok := c.results[endpoint]
+	c.mu.Unlock()
+	if ok && time.Since(cached.checkedAt) < c.ttl {
+		return &Status{IsTor: cached.status.IsTor, Response: cached.status.Response}, nil
+	}
+
+	status, err := c.checker.Check(client)
+	if err != nil {
+		return status, err
+	}
+	c.mu.Lock()
+	c.results[endpoint] = cachedStatus{status: status, checkedAt: time.Now()}
+	c.mu.Unlock()
+	logger.Info("Connectivity check passed", "IP", status.IP, "proxy", endpoint)
+	return status, nil
+}
@@ -0,0 +1,56 @@
+package connectivity
+
+import (
+	"CTI-Dashboard/scraper/logger"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// counter answers with a new exit IP on every check.
+type counter struct {
+	checks int
+	fail   bool
+}
+
+func (c *counter) Check(client *http.Client) (*Status, error) {
+	c.checks++
+	if c.fail {
+		return nil, errors.New("no circuit")
+	}
+	return &Status{IP: fmt.Sprintf("198.51.100.%d", c.checks), IsTor: true}, nil
+}
+
+func TestCached(t *testing.T) {
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	checker := &counter{}
+	cached := NewCached(checker, time.Minute)
+	tor := ForEndpoint(cached, "tor")
+
+	status, err := tor.Check(nil)
+	if err != nil || status.IP != "198.51.100.1" {
+		t.Fatalf("first check: %+v, %v", status, err)
+	}
+	// Another forum's client on the same endpoint: the result is shared,
+	// but not the exit its circuit used.
+	status, err = tor.Check(nil)
+	if err != nil || !status.IsTor || status.IP != "" || checker.checks != 1 {
+		t.Errorf("cached check: %+v, %v after %d checks, want no IP from one check", status, err, checker.checks)
+	}
+	if status, err := ForEndpoint(cached, "i2p").Check(nil); err != nil || status.IP != "198.51.100.2" {
+		t.Errorf("other endpoint: %+v, %v", status, err)
+	}
+
+	checker.fail = true
+	if _, err := cached.Check(nil); err == nil {
+		t.Error("failed check passed")
+	}
+	if _, err := cached.Check(nil); err == nil || checker.checks != 4 {
+		t.Errorf("failure cached: %v after %d checks", err, checker.checks)
+	}
+}
@@ -0,0 +1,246 @@
+package custody
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+)
+
+// ToolVersion is stamped into every manifest. Release builds set it with
+// -ldflags "-X CTI-Dashboard/scraper/custody.ToolVersion=<version>";
+// otherwise the VCS revision of the build is used.
+var ToolVersion = ""
+
+const (
+	toolName        = "CTI-Dashboard"
+	manifestVersion = 1
+	timeLayout      = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// Artifact kinds.
+const (
+	KindHTML       = "html"
+	KindScreenshot = "screenshot"
+	KindMHTML      = "mhtml"
+	KindPDF        = "pdf"
+	KindWARC       = "warc_record"
+)
+
+// Artifact is one file, or one record of a WARC file, captured during a run.
+type Artifact struct {
+	Kind string `json:"kind"`
+	// Path is relative to the output directory.
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	// Offset and RecordID locate a WARC record inside its file; Size is
+	// then the length of the record.
+	Offset     int64  `json:"offset,omitempty"`
+	RecordID   string `json:"record_id,omitempty"`
+	URL        string `json:"url"`
+	CapturedAt string `json:"captured_at"`
+	ExitIP     string `json:"exit_ip"`
+}
+
+// Manifest lists the artifacts of one scan run. It is written as JSON next
+// to a detached Ed25519 signature of the exact bytes written.
+type Manifest struct {
+	Version     int        `json:"version"`
+	Tool        string     `json:"tool"`
+	ToolVersion string     `json:"tool_version"`
+	RunID       string     `json:"run_id"`
+	ForumID     string     `json:"forum_id"`
+	CreatedAt   string     `json:"created_at"`
+	PublicKey   string     `json:"public_key"`
+	Artifacts   []Artifact `json:"artifacts"`
+}
+
+func NewManifest(runID string, forumID string) *Manifest {
+	return &Manifest{
+		Version:     manifestVersion,
+		Tool:        toolName,
+		ToolVersion: Version(),
+		RunID:       runID,
+		ForumID:     forumID,
+	}
+}
+
+// Add records an artifact, captured now unless its CapturedAt says when.
+func (m *Manifest) Add(artifact Artifact) {
+	if artifact.CapturedAt == "" {
+		artifact.CapturedAt = Timestamp(time.Now())
+	}
+	m.Artifacts = append(m.Artifacts, artifact)
+}
+
+// Timestamp formats a time as manifests store it, empty for the zero time.
+func Timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeLayout)
+}
+
+// Signer signs manifests with the local Ed25519 key of an output directory.
+type Signer struct {
+	outputDir string
+	key       ed25519.PrivateKey
+}
+
+// NewSigner loads the key under <output dir>/custody, creating it on first
+// use.
+func NewSigner(outputDir string) (*Signer, error) {
+	dir := filepath.Join(outputDir, "custody")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	keyPath := filepath.Join(dir, "ed25519.key")
+	data, err := os.ReadFile(keyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return createKey(outputDir, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s is not a PEM private key", keyPath)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", keyPath)
+	}
+	return &Signer{outputDir: outputDir, key: key}, nil
+}
+
+func createKey(outputDir string, dir string) (*Signer, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ed25519.key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+	der, err = x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(PublicKeyPath(outputDir), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		return nil, err
+	}
+	return &Signer{outputDir: outputDir, key: private}, nil
+}
+
+// Fingerprint is the fingerprint of the signing key.
+func (s *Signer) Fingerprint() string {
+	return Fingerprint(s.key.Public().(ed25519.PublicKey))
+}
+
+// PublicKeyPath is where the public half of an output directory's key is
+// kept, to be handed over together with the evidence. Whoever verifies it
+// must get the key, or its fingerprint, some other way too: anyone able to
+// alter the evidence can replace this file.
+func PublicKeyPath(outputDir string) string {
+	return filepath.Join(outputDir, "custody", "ed25519.pub")
+}
+
+// ManifestPath is where the manifest of a run is written.
+func ManifestPath(outputDir string, runID string) string {
+	return filepath.Join(outputDir, "manifests", runID+".json")
+}
+
+// OutputDir is the directory artifact paths are relative to.
+func (s *Signer) OutputDir() string {
+	return s.outputDir
+}
+
+// Seal writes the manifest and its signature and returns the manifest's path.
+func (s *Signer) Seal(m *Manifest) (string, error) {
+	m.CreatedAt = Timestamp(time.Now())
+	m.PublicKey = base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := ManifestPath(s.outputDir, m.RunID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, data))
+	if err := os.WriteFile(path+".sig", []byte(signature+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Relative turns a path written under the output directory into the form
+// stored in manifests.
+func (s *Signer) Relative(path string) string {
+	if rel, err := filepath.Rel(s.outputDir, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
+
+// Version is the tool version stamped into manifests.
+func Version() string {
+	if ToolVersion != "" {
+		return ToolVersion
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return info.Main.Version
+}
+
+// HashFile returns the SHA-256 and size of size bytes of a file starting at
+// offset; a negative size reads to the end.
+func HashFile(path string, offset int64, size int64) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	var reader io.Reader = file
+	if size >= 0 {
+		reader = io.LimitReader(file, size)
+	}
+	hash := sha256.New()
+	n, err := io.Copy(hash, reader)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), n, nil
+}
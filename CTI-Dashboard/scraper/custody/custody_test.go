@@ -0,0 +1,175 @@
+package custody
+
+import (
+	"CTI-Dashboard/models"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// seal writes two artifacts under a new output directory and seals the
+// manifest listing them.
+func seal(t *testing.T) (signer *Signer, path string) {
+	t.Helper()
+	dir := t.TempDir()
+	signer, err := NewSigner(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := NewManifest("run-1", "f1")
+	for name, content := range map[string]string{"html/a.html": "<p>seized</p>", "screenshots/a.png": "png"} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sum, size, err := HashFile(file, 0, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest.Add(Artifact{Kind: KindHTML, Path: signer.Relative(file), SHA256: sum, Size: size, URL: "http://bazaar.onion/"})
+	}
+	path, err = signer.Seal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, path
+}
+
+func verify(t *testing.T, signer *Signer, path string) *models.CustodyReport {
+	t.Helper()
+	key, err := PinnedKey(path, signer.Fingerprint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Verify(path, key, signer.OutputDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestSealVerify(t *testing.T) {
+	signer, path := seal(t)
+	report := verify(t, signer, path)
+	if !report.OK || !report.SignatureValid || !report.KeyMatches || len(report.Checks) != 2 {
+		t.Fatalf("got %+v, want a valid report of 2 artifacts", report)
+	}
+	if report.Fingerprint != signer.Fingerprint() || report.RunID != "run-1" {
+		t.Errorf("got %+v", report)
+	}
+
+	// The key stored with the evidence checks out the same.
+	key, err := LoadPublicKey(PublicKeyPath(signer.OutputDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Fingerprint(key) != signer.Fingerprint() {
+		t.Errorf("stored key %s, signer %s", Fingerprint(key), signer.Fingerprint())
+	}
+
+	// A second signer on the directory reuses the key.
+	again, err := NewSigner(signer.OutputDir())
+	if err != nil || again.Fingerprint() != signer.Fingerprint() {
+		t.Errorf("key not reused: %v", err)
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(dir string, path string) error
+		check  func(*models.CustodyReport) bool
+	}{
+		{"artifact modified", func(dir string, path string) error {
+			return os.WriteFile(filepath.Join(dir, "html", "a.html"), []byte("<p>open</p>"), 0644)
+		}, func(r *models.CustodyReport) bool {
+			return r.SignatureValid && status(r, "html/a.html") == StatusModified
+		}},
+		{"artifact missing", func(dir string, path string) error {
+			return os.Remove(filepath.Join(dir, "screenshots", "a.png"))
+		}, func(r *models.CustodyReport) bool {
+			return r.SignatureValid && status(r, "screenshots/a.png") == StatusMissing
+		}},
+		{"manifest edited", func(dir string, path string) error {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(path, append(data, ' '), 0644)
+		}, func(r *models.CustodyReport) bool { return !r.SignatureValid }},
+	}
+	for _, test := range tests {
+		signer, path := seal(t)
+		if err := test.tamper(signer.OutputDir(), path); err != nil {
+			t.Fatal(err)
+		}
+		report := verify(t, signer, path)
+		if report.OK || !test.check(report) {
+			t.Errorf("%s: got %+v", test.name, report)
+		}
+	}
+}
+
+func TestPinnedKey(t *testing.T) {
+	signer, path := seal(t)
+	other, err := NewSigner(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PinnedKey(path, other.Fingerprint()); !errors.Is(err, ErrUntrustedKey) {
+		t.Errorf("other fingerprint: %v, want ErrUntrustedKey", err)
+	}
+	if _, err := PinnedKey(path, ""); err == nil {
+		t.Error("no fingerprint accepted")
+	}
+
+	// A manifest re-signed with another key, together with the public key
+	// stored next to it, does not pass for the pinned one.
+	manifest := NewManifest("run-1", "f1")
+	forged, err := other.Seal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PinnedKey(forged, signer.Fingerprint()); !errors.Is(err, ErrUntrustedKey) {
+		t.Errorf("forged manifest: %v, want ErrUntrustedKey", err)
+	}
+	// Against a key it was not signed with, the signature fails.
+	key, err := LoadPublicKey(PublicKeyPath(signer.OutputDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Verify(forged, key, other.OutputDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK || report.SignatureValid || report.KeyMatches {
+		t.Errorf("forged manifest passed: %+v", report)
+	}
+}
+
+func TestCapturedAt(t *testing.T) {
+	manifest := NewManifest("run-1", "f1")
+	fetched := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	manifest.Add(Artifact{Kind: KindHTML, CapturedAt: Timestamp(fetched)})
+	manifest.Add(Artifact{Kind: KindHTML})
+	if got := manifest.Artifacts[0].CapturedAt; got != "2025-01-02T03:04:05.000Z" {
+		t.Errorf("CapturedAt %q, want the fetch time", got)
+	}
+	if manifest.Artifacts[1].CapturedAt == "" {
+		t.Error("CapturedAt not defaulted")
+	}
+}
+
+func status(report *models.CustodyReport, path string) string {
+	for _, check := range report.Checks {
+		if check.Path == path {
+			return check.Status
+		}
+	}
+	return ""
+}
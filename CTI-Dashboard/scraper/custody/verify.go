@@ -0,0 +1,152 @@
+package custody
+
+import (
+	"CTI-Dashboard/models"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Check results.
+const (
+	StatusOK       = "ok"
+	StatusModified = "modified"
+	StatusMissing  = "missing"
+)
+
+// ErrUntrustedKey is returned for a manifest signed with a key other than
+// the one the examiner pinned.
+var ErrUntrustedKey = errors.New("manifest is not signed with the pinned key")
+
+// Fingerprint identifies a public key in a form short enough to be read out
+// and compared by hand: SHA256: and the base64 of its hash, as ssh shows
+// keys.
+func Fingerprint(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// PinnedKey returns the public key a manifest names if its fingerprint is
+// the one the examiner was given apart from the evidence. The key stored
+// with the evidence could have been swapped along with it, so it is only
+// trusted that way.
+func PinnedKey(manifestPath string, fingerprint string) (ed25519.PublicKey, error) {
+	if strings.TrimSpace(fingerprint) == "" {
+		return nil, errors.New("the fingerprint of the signing key is required")
+	}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("bad manifest: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(manifest.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("bad manifest: invalid public key")
+	}
+	if got := Fingerprint(key); got != strings.TrimSpace(fingerprint) {
+		return nil, fmt.Errorf("%w: signed with %s", ErrUntrustedKey, got)
+	}
+	return key, nil
+}
+
+// LoadPublicKey reads a PEM public key as written next to the signing key.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("%s is not a PEM public key", path)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+	}
+	return key, nil
+}
+
+// Verify checks the signature of a manifest against key and every artifact
+// it lists against its recorded hash. Artifact paths are resolved against
+// outputDir.
+func Verify(manifestPath string, key ed25519.PublicKey, outputDir string) (*models.CustodyReport, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := os.ReadFile(manifestPath + ".sig")
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("bad signature file: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("bad manifest: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key")
+	}
+	report := &models.CustodyReport{
+		Manifest:       manifestPath,
+		RunID:          manifest.RunID,
+		ForumID:        manifest.ForumID,
+		CreatedAt:      manifest.CreatedAt,
+		ToolVersion:    manifest.ToolVersion,
+		PublicKey:      manifest.PublicKey,
+		Fingerprint:    Fingerprint(key),
+		SignatureValid: ed25519.Verify(key, data, signature),
+		KeyMatches:     manifest.PublicKey == base64.StdEncoding.EncodeToString(key),
+	}
+
+	report.OK = report.SignatureValid && report.KeyMatches
+	for _, artifact := range manifest.Artifacts {
+		check := models.CustodyCheck{
+			Kind:       artifact.Kind,
+			Path:       artifact.Path,
+			URL:        artifact.URL,
+			CapturedAt: artifact.CapturedAt,
+			Expected:   artifact.SHA256,
+		}
+		size := int64(-1)
+		if artifact.Kind == KindWARC {
+			size = artifact.Size
+		}
+		actual, n, err := HashFile(filepath.Join(outputDir, filepath.FromSlash(artifact.Path)), artifact.Offset, size)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			check.Status = StatusMissing
+		case err != nil:
+			check.Status = StatusMissing
+			check.Error = err.Error()
+		case actual != artifact.SHA256 || n != artifact.Size:
+			check.Status = StatusModified
+			check.Actual = actual
+		default:
+			check.Status = StatusOK
+		}
+		if check.Status != StatusOK {
+			report.OK = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+	return report, nil
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"CTI-Dashboard/models"
+	"database/sql"
+	"encoding/csv"
+	"strings"
+	"time"
+)
+
+// CSVHeader names the columns of CSV.
+var CSVHeader = []string{"forum", "forum_url", "post_id", "thread_url", "title", "author", "severity", "created", "indicators"}
+
+// CSV lists the posts the filter selects, one row each, with the indicators
+// found in the post separated by spaces. TLP does not apply.
+func CSV(db *sql.DB, filter models.ExportFilter) (string, error) {
+	forums, posts, err := load(db, filter)
+	if err != nil {
+		return "", err
+	}
+	byID := make(map[string]Forum)
+	for _, forum := range forums {
+		byID[forum.ID] = forum
+	}
+
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(CSVHeader); err != nil {
+		return "", err
+	}
+	for _, post := range posts {
+		var values []string
+		for _, indicator := range ExtractIndicators(post.Text) {
+			values = append(values, indicator.Value)
+		}
+		forum := byID[post.ForumID]
+		row := []string{
+			forum.Name,
+			forum.URL,
+			post.ID,
+			post.ThreadURL,
+			post.Title,
+			post.Author,
+			post.Severity,
+			post.Created.Format(time.RFC3339),
+			strings.Join(values, " "),
+		}
+		for i := range row {
+			row[i] = cell(row[i])
+		}
+		if err := w.Write(row); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// cell keeps a value spreadsheets would read as a formula as text. Titles and
+// authors come from the forums, so they are not trusted.
+func cell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,22 @@
+package export
+
+import "testing"
+
+func TestCell(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Selling RDP access", "Selling RDP access"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1 vouch", "'+1 vouch"},
+		{"-seller-", "'-seller-"},
+		{"@admin", "'@admin"},
+		{"\tcmd", "'\tcmd"},
+		{"\rcmd", "'\rcmd"},
+		{"", ""},
+		{"a=b", "a=b"},
+	}
+	for _, test := range tests {
+		if got := cell(test.in); got != test.want {
+			t.Errorf("cell(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package export
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Indicator types pulled from post text.
+const (
+	IndicatorURL    = "url"
+	IndicatorDomain = "domain"
+	IndicatorIPv4   = "ipv4"
+	IndicatorMD5    = "md5"
+	IndicatorSHA1   = "sha1"
+	IndicatorSHA256 = "sha256"
+)
+
+type Indicator struct {
+	Type  string
+	Value string
+}
+
+var (
+	urlPattern    = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"'\x60)\]]+`)
+	domainPattern = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9]{1,23}\b`)
+	ipv4Pattern   = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	hashPattern   = regexp.MustCompile(`(?i)\b[0-9a-f]{32}(?:[0-9a-f]{8}(?:[0-9a-f]{24})?)?\b`)
+)
+
+// Defanged forms analysts and forum users write to keep links dead.
+var refang = strings.NewReplacer(
+	"hxxps://", "https://", "hxxp://", "http://", "hXXps://", "https://", "hXXp://", "http://",
+	"[.]", ".", "(.)", ".", "{.}", ".", "[dot]", ".", "(dot)", ".", "[:]", ":",
+)
+
+// Refang turns the defanged forms of indicators in text into the real ones.
+func Refang(text string) string {
+	return refang.Replace(text)
+}
+
+// Top-level domains in the public suffix list that are more often file
+// extensions in forum posts: dump.zip and setup.py are not hosts.
+var fileExtensions = map[string]bool{
+	"zip": true, "mov": true, "md": true, "py": true, "sh": true, "pl": true, "rs": true, "ps": true, "so": true,
+}
+
+// Transaction and block IDs are 64 hex digits like a SHA-256 hash. They are
+// told apart by the words before them, and block hashes by their leading
+// zeros.
+var (
+	ledgerContext = regexp.MustCompile(`(?i)\b(?:txid|txn?|tx ?hash|transactions?|blocks?|block ?hash|blockchain)\b[^\n]{0,40}$`)
+	blockHash     = regexp.MustCompile(`^0{8}`)
+)
+
+// ExtractIndicators returns the URLs, domains, IPv4 addresses and file hashes
+// mentioned in text, each once, in order of appearance. Defanged indicators
+// are refanged.
+func ExtractIndicators(text string) []Indicator {
+	text = refang.Replace(text)
+	var found []Indicator
+	seen := make(map[Indicator]bool)
+	add := func(indicator Indicator) {
+		if !seen[indicator] {
+			seen[indicator] = true
+			found = append(found, indicator)
+		}
+	}
+
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		match = strings.TrimRight(match, ".,;:!?")
+		if u, err := url.Parse(match); err == nil && u.Host != "" {
+			add(Indicator{Type: IndicatorURL, Value: match})
+		}
+	}
+	// Hosts of URLs are covered by the URL indicators.
+	rest := urlPattern.ReplaceAllString(text, " ")
+
+	for _, match := range ipv4Pattern.FindAllString(rest, -1) {
+		ip := net.ParseIP(match)
+		if ip == nil || ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		add(Indicator{Type: IndicatorIPv4, Value: match})
+	}
+	for _, match := range domainPattern.FindAllString(rest, -1) {
+		if domain := strings.ToLower(match); isDomain(domain) && !ipv4Pattern.MatchString(domain) {
+			add(Indicator{Type: IndicatorDomain, Value: domain})
+		}
+	}
+	for _, span := range hashPattern.FindAllStringIndex(rest, -1) {
+		value := strings.ToLower(rest[span[0]:span[1]])
+		if len(value) == 64 && (blockHash.MatchString(value) || ledgerContext.MatchString(rest[max(0, span[0]-64):span[0]])) {
+			continue
+		}
+		switch len(value) {
+		case 32:
+			add(Indicator{Type: IndicatorMD5, Value: value})
+		case 40:
+			add(Indicator{Type: IndicatorSHA1, Value: value})
+		case 64:
+			add(Indicator{Type: IndicatorSHA256, Value: value})
+		}
+	}
+	return found
+}
+
+// isDomain reports whether name is a host under a public suffix, or under
+// i2p, which the list leaves out. Any other dotted word is a file name or an
+// abbreviation.
+func isDomain(name string) bool {
+	suffix, icann := publicsuffix.PublicSuffix(name)
+	if !icann && suffix != "i2p" || fileExtensions[suffix] {
+		return false
+	}
+	_, err := publicsuffix.EffectiveTLDPlusOne(name)
+	return err == nil
+}
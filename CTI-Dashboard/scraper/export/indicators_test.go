@@ -0,0 +1,88 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+)
+
+const txid = "a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d"
+
+func TestExtractIndicators(t *testing.T) {
+	tests := []struct {
+		text string
+		want []Indicator
+	}{
+		{"Panel at hxxp://panel[.]bazaar[.]onion/login, mirror evil-shop(.)ru", []Indicator{
+			{IndicatorURL, "http://panel.bazaar.onion/login"},
+			{IndicatorDomain, "evil-shop.ru"},
+		}},
+		{"C2 on 185.220.101.4 and 10.0.0.1, 127.0.0.1", []Indicator{{IndicatorIPv4, "185.220.101.4"}}},
+		{"Mirrors: Market.Onion, shop.co.uk, forum.i2p, co.uk", []Indicator{
+			{IndicatorDomain, "market.onion"},
+			{IndicatorDomain, "shop.co.uk"},
+			{IndicatorDomain, "forum.i2p"},
+		}},
+		{"Grab dump.zip, setup.py, readme.txt and config.php, e.g. see i.e. this", nil},
+		{"Loader D41D8CD98F00B204E9800998ECF8427E, sha1 da39a3ee5e6b4b0d3255bfef95601890afd80709", []Indicator{
+			{IndicatorMD5, "d41d8cd98f00b204e9800998ecf8427e"},
+			{IndicatorSHA1, "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+		}},
+		{"Payload e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", []Indicator{
+			{IndicatorSHA256, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		}},
+		{"Paid, txid: " + txid, nil},
+		{"Transaction hash for the escrow is " + txid, nil},
+		{"Block 0000000000000000000320283a032748cef8227873ff4872689bf23f1cda83a5", nil},
+		{"Not a hash: 0x" + txid, nil},
+		{"Same URL twice http://a.onion/x. http://a.onion/x", []Indicator{{IndicatorURL, "http://a.onion/x"}}},
+	}
+	for _, test := range tests {
+		if got := ExtractIndicators(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ExtractIndicators(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		indicator Indicator
+		want      string
+	}{
+		{Indicator{IndicatorURL, "http://a.onion/x?q='1'"}, `[url:value = 'http://a.onion/x?q=\'1\'']`},
+		{Indicator{IndicatorDomain, "bazaar.onion"}, "[domain-name:value = 'bazaar.onion']"},
+		{Indicator{IndicatorIPv4, "185.220.101.4"}, "[ipv4-addr:value = '185.220.101.4']"},
+		{Indicator{IndicatorMD5, "d41d8cd98f00b204e9800998ecf8427e"}, "[file:hashes.MD5 = 'd41d8cd98f00b204e9800998ecf8427e']"},
+		{Indicator{IndicatorSHA1, "da39a3ee5e6b4b0d3255bfef95601890afd80709"}, "[file:hashes.'SHA-1' = 'da39a3ee5e6b4b0d3255bfef95601890afd80709']"},
+		{Indicator{IndicatorSHA256, txid}, "[file:hashes.'SHA-256' = '" + txid + "']"},
+		{Indicator{IndicatorURL, `http://a.onion/\x`}, `[url:value = 'http://a.onion/\\x']`},
+		{Indicator{"email", "a@b.onion"}, ""},
+	}
+	for _, test := range tests {
+		if got := Pattern(test.indicator); got != test.want {
+			t.Errorf("Pattern(%v) = %s, want %s", test.indicator, got, test.want)
+		}
+	}
+}
+
+// The IDs are UUIDv5 of the object's key, so the same post exported twice,
+// or by another install, gets the same IDs. Changing them would make every
+// consumer see every object as new.
+func TestIDs(t *testing.T) {
+	tests := []struct {
+		got  string
+		want string
+	}{
+		{id("report", "p1"), "report--177a67ec-e713-503e-b0a7-f567f1427584"},
+		{id("indicator", "[domain-name:value = 'bazaar.onion']"), "indicator--3b1f37b5-baa4-524c-be2c-b46ffcb1d398"},
+		{scoID("url", "http://bazaar.onion/"), "url--4cf2e2ab-b1b0-545b-9148-e44c2929b059"},
+		{scoID("domain-name", "example.com"), "domain-name--bedb4899-d24b-5401-bc86-8f6b4cc18ec7"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("got %s, want %s", test.got, test.want)
+		}
+	}
+	if id("report", "p1") == id("report", "p2") || id("report", "p1") == id("indicator", "p1") {
+		t.Error("different objects share an ID")
+	}
+}
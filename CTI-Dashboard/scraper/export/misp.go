@@ -0,0 +1,216 @@
+package export
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/snapshots"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MISPEvent is the {"Event": {...}} document MISP imports and exports.
+type MISPEvent struct {
+	Event MISPEventBody `json:"Event"`
+}
+
+type MISPEventBody struct {
+	UUID          string          `json:"uuid"`
+	Info          string          `json:"info"`
+	Date          string          `json:"date"`
+	ThreatLevelID string          `json:"threat_level_id"`
+	Analysis      string          `json:"analysis"`
+	Distribution  string          `json:"distribution"`
+	Published     bool            `json:"published"`
+	Timestamp     string          `json:"timestamp"`
+	Orgc          *MISPOrg        `json:"Orgc,omitempty"`
+	Tag           []MISPTag       `json:"Tag,omitempty"`
+	Attribute     []MISPAttribute `json:"Attribute"`
+	Object        []MISPObject    `json:"Object,omitempty"`
+}
+
+type MISPOrg struct {
+	Name string `json:"name"`
+}
+
+type MISPTag struct {
+	Name string `json:"name"`
+}
+
+type MISPAttribute struct {
+	UUID         string    `json:"uuid"`
+	Type         string    `json:"type"`
+	Category     string    `json:"category"`
+	Value        string    `json:"value"`
+	ToIDS        bool      `json:"to_ids"`
+	Comment      string    `json:"comment,omitempty"`
+	Timestamp    string    `json:"timestamp"`
+	Distribution string    `json:"distribution"`
+	Data         string    `json:"data,omitempty"`
+	Tag          []MISPTag `json:"Tag,omitempty"`
+}
+
+// MISPObject is only read, for the attributes MISP groups into objects.
+type MISPObject struct {
+	Name      string          `json:"name"`
+	Attribute []MISPAttribute `json:"Attribute"`
+}
+
+// MISP attribute type and category of each indicator type.
+var mispTypes = map[string][2]string{
+	IndicatorURL:    {"url", "Network activity"},
+	IndicatorDomain: {"domain", "Network activity"},
+	IndicatorIPv4:   {"ip-dst", "Network activity"},
+	IndicatorMD5:    {"md5", "Payload delivery"},
+	IndicatorSHA1:   {"sha1", "Payload delivery"},
+	IndicatorSHA256: {"sha256", "Payload delivery"},
+}
+
+// MISP threat levels: 1 high, 2 medium, 3 low, 4 undefined.
+var threatLevels = map[string]int{"high": 1, "medium": 2, "low": 3}
+
+// SeverityTag is the galaxy tag attributes of a post of the given severity
+// carry.
+func SeverityTag(severity string) string {
+	return fmt.Sprintf(`misp-galaxy:cti-dashboard-severity="%s"`, severity)
+}
+
+// MISP builds one MISP event of the posts the filter selects: the indicators
+// found in each post, a link to the thread and its screenshot, if one was
+// captured, as attachment. Attributes are tagged with the severity of their
+// post and the event with the TLP of the filter.
+func MISP(db *sql.DB, filter models.ExportFilter) (*MISPEvent, error) {
+	tlp := strings.ToLower(strings.TrimPrefix(strings.ToUpper(filter.TLP), "TLP:"))
+	if tlp == "" {
+		tlp = "amber"
+	}
+	if _, ok := tlpMarkings[tlp]; !ok && tlp != "clear" {
+		return nil, fmt.Errorf("%w: unknown TLP %q", ErrFilter, filter.TLP)
+	}
+
+	forums, posts, err := load(db, filter)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string)
+	for _, forum := range forums {
+		names[forum.ID] = forum.Name
+	}
+
+	now := time.Now().UTC()
+	event := MISPEventBody{
+		UUID:          uuid.New().String(),
+		Info:          eventInfo(forums, len(posts)),
+		Date:          now.Format("2006-01-02"),
+		ThreatLevelID: "4",
+		Analysis:      "0",
+		Distribution:  "0",
+		Timestamp:     strconv.FormatInt(now.Unix(), 10),
+		Orgc:          &MISPOrg{Name: "CTI-Dashboard"},
+		Tag:           []MISPTag{{Name: "tlp:" + tlp}},
+		Attribute:     []MISPAttribute{},
+	}
+
+	threatLevel := 4
+	severities := make(map[string]bool)
+	for _, post := range posts {
+		if level, ok := threatLevels[post.Severity]; ok {
+			threatLevel = min(threatLevel, level)
+			if !severities[post.Severity] {
+				severities[post.Severity] = true
+				event.Tag = append(event.Tag, MISPTag{Name: SeverityTag(post.Severity)})
+			}
+		}
+		event.Attribute = append(event.Attribute, postAttributes(db, post, names[post.ForumID])...)
+	}
+	event.ThreatLevelID = strconv.Itoa(threatLevel)
+	return &MISPEvent{Event: event}, nil
+}
+
+// MISPJSON is MISP encoded as indented JSON.
+func MISPJSON(db *sql.DB, filter models.ExportFilter) (string, error) {
+	event, err := MISP(db, filter)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func postAttributes(db *sql.DB, post Post, forumName string) []MISPAttribute {
+	comment := post.Title
+	if forumName != "" {
+		comment = forumName + ": " + comment
+	}
+	var tags []MISPTag
+	if _, ok := threatLevels[post.Severity]; ok {
+		tags = []MISPTag{{Name: SeverityTag(post.Severity)}}
+	}
+	// MISP keeps the attribute with the newer timestamp, so the tags and
+	// comment of a changed post replace the old ones.
+	timestamp := strconv.FormatInt(post.Modified.Unix(), 10)
+	attribute := func(kind string, category string, value string, toIDS bool) MISPAttribute {
+		return MISPAttribute{
+			UUID:         uuid.NewSHA1(namespace, []byte("misp:"+post.ID+":"+kind+":"+value)).String(),
+			Type:         kind,
+			Category:     category,
+			Value:        value,
+			ToIDS:        toIDS,
+			Comment:      comment,
+			Timestamp:    timestamp,
+			Distribution: "5", // inherit the event's
+			Tag:          tags,
+		}
+	}
+
+	list := []MISPAttribute{attribute("link", "External analysis", post.ThreadURL, false)}
+	for _, indicator := range ExtractIndicators(post.Text) {
+		kind := mispTypes[indicator.Type]
+		list = append(list, attribute(kind[0], kind[1], indicator.Value, true))
+	}
+
+	snapshot, err := latestScreenshot(db, post.ThreadURL)
+	if err != nil {
+		return list
+	}
+	data, err := os.ReadFile(snapshot.ScreenshotPath)
+	if err != nil {
+		logger.Error("Could not read screenshot for MISP export", "error", err, "path", snapshot.ScreenshotPath)
+		return list
+	}
+	screenshot := attribute("attachment", "External analysis", filepath.Base(snapshot.ScreenshotPath), false)
+	screenshot.Data = base64.StdEncoding.EncodeToString(data)
+	return append(list, screenshot)
+}
+
+// latestScreenshot is the newest snapshot of a thread that has a screenshot.
+func latestScreenshot(db *sql.DB, threadURL string) (models.Snapshot, error) {
+	list, err := snapshots.ListURL(db, threadURL)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+	for _, snapshot := range list {
+		if snapshot.ScreenshotPath != "" {
+			return snapshot, nil
+		}
+	}
+	return models.Snapshot{}, sql.ErrNoRows
+}
+
+func eventInfo(forums []Forum, posts int) string {
+	if len(forums) == 1 {
+		return fmt.Sprintf("CTI-Dashboard: %d posts from %s", posts, forums[0].Name)
+	}
+	return fmt.Sprintf("CTI-Dashboard: %d posts from %d forums", posts, len(forums))
+}
@@ -0,0 +1,177 @@
+package export
+
+import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/search"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// ErrFilter marks an export filter that cannot be applied.
+var ErrFilter = errors.New("invalid export filter")
+
+// Forum is a forum as it goes into an export.
+type Forum struct {
+	ID          string
+	Name        string
+	URL         string
+	Description string
+	Engine      string
+	Status      string
+	Created     time.Time
+}
+
+// Post is a scraped thread as it goes into an export, with the title, author
+// and text stored by the scrape, read from its stored page where the columns
+// are empty.
+type Post struct {
+	ID        string
+	ForumID   string
+	ThreadURL string
+	Title     string
+	Author    string
+	Text      string
+	Severity  string
+	// Created is when the thread link was found, Scraped when its page was
+	// last fetched; Created for posts scraped before snapshots were kept.
+	// Modified is when its title, author, text or severity last changed;
+	// Scraped for posts that have not changed since that was kept.
+	Created  time.Time
+	Scraped  time.Time
+	Modified time.Time
+}
+
+// load reads the forums and scraped posts the filter selects, posts oldest
+// first.
+func load(db *sql.DB, filter models.ExportFilter) ([]Forum, []Post, error) {
+	var where []string
+	var args []any
+	if len(filter.ForumIDs) > 0 {
+		where = append(where, "forum_id IN ("+placeholders(len(filter.ForumIDs))+")")
+		for _, id := range filter.ForumIDs {
+			args = append(args, id)
+		}
+	}
+	forumWhere := ""
+	if len(where) > 0 {
+		forumWhere = " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := db.Query(`SELECT forum_id, forum_name, forum_url, COALESCE(forum_description, ''), COALESCE(forum_engine, ''),
+		COALESCE(status, ''), COALESCE(created_at, '') FROM forums`+forumWhere, args...)
+	if err != nil {
+		logger.Error("Could not query forums for export", "error", err)
+		return nil, nil, err
+	}
+	var forums []Forum
+	for rows.Next() {
+		var f Forum
+		var created string
+		if err := rows.Scan(&f.ID, &f.Name, &f.URL, &f.Description, &f.Engine, &f.Status, &created); err != nil {
+			logger.Error("Could not scan forum row", "error", err)
+			continue
+		}
+		f.Created = parseTime(created)
+		forums = append(forums, f)
+	}
+	rows.Close()
+
+	where = append(where, "content IS NOT NULL", "content != ''")
+	if len(filter.PostIDs) > 0 {
+		where = append(where, "post_id IN ("+placeholders(len(filter.PostIDs))+")")
+		for _, id := range filter.PostIDs {
+			args = append(args, id)
+		}
+	}
+	if len(filter.Severities) > 0 {
+		where = append(where, "severity_level IN ("+placeholders(len(filter.Severities))+")")
+		for _, severity := range filter.Severities {
+			args = append(args, severity)
+		}
+	}
+	if filter.Since != "" {
+		since, err := parseFilterTime(filter.Since)
+		if err != nil {
+			return nil, nil, err
+		}
+		where = append(where, "created_at >= ?")
+		args = append(args, since)
+	}
+	rows, err = db.Query(`SELECT post_id, forum_id, thread_url, COALESCE(title, ''), COALESCE(author, ''),
+		COALESCE(body_text, ''), COALESCE(replies, ''), content,
+		COALESCE(severity_level, 'unassigned'), COALESCE(created_at, ''),
+		COALESCE((SELECT MAX(s.fetched_at) FROM snapshots s WHERE s.post_id = posts.post_id), created_at, ''),
+		COALESCE(modified_at, '')
+		FROM posts WHERE `+strings.Join(where, " AND ")+` ORDER BY created_at, post_id`, args...)
+	if err != nil {
+		logger.Error("Could not query posts for export", "error", err)
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var posts []Post
+	for rows.Next() {
+		var p Post
+		var page search.Page
+		var content, created, scraped, modified string
+		err := rows.Scan(&p.ID, &p.ForumID, &p.ThreadURL, &p.Title, &p.Author, &page.Body, &page.Replies, &content,
+			&p.Severity, &created, &scraped, &modified)
+		if err != nil {
+			logger.Error("Could not scan post row", "error", err)
+			continue
+		}
+		p.Created = parseTime(created)
+		p.Scraped = parseTime(scraped)
+		p.Modified = parseTime(modified)
+		if p.Modified.IsZero() {
+			p.Modified = p.Scraped
+		}
+		if page.Body == "" {
+			page = search.Read(content)
+		}
+		p.Title = firstOf(p.Title, page.Title, page.PageTitle)
+		p.Author = firstOf(p.Author, page.Author)
+		p.Text = strings.TrimSpace(page.Body + "\n\n" + page.Replies)
+		posts = append(posts, p)
+	}
+	return forums, posts, rows.Err()
+}
+
+// firstOf returns the first value that is not empty.
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// parseTime reads a time stored by SQLite; the zero time if it cannot.
+func parseTime(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, stored.TimeLayout, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+func parseFilterTime(value string) (string, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format("2006-01-02 15:04:05"), nil
+		}
+	}
+	return "", fmt.Errorf("%w: invalid time %q", ErrFilter, value)
+}
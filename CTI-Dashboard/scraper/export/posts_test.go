@@ -0,0 +1,68 @@
+package export
+
+import (
+	"CTI-Dashboard/db/migrations"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const thread = `<html><head><title>Bazaar</title></head><body>
+<h1 class="p-title-value">Selling RDP access</h1>
+<article class="message message--post" data-author="seller"><div class="bbWrapper">Fresh panels</div></article>
+<article class="message message--post" data-author="buyer"><div class="bbWrapper">Vouch</div></article>
+</body></html>`
+
+func setup(t *testing.T, statements ...string) *sql.DB {
+	t.Helper()
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "export.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Apply(db); err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestLoad(t *testing.T) {
+	db := setup(t,
+		`INSERT INTO forums (forum_id, forum_name, forum_url) VALUES ('f1', 'Exploit Bazaar', 'http://bazaar.onion/')`,
+		// The stored text is what the scrape read; it wins over the page.
+		`INSERT INTO posts (post_id, forum_id, thread_url, content, title, author, body_text, replies, created_at) VALUES
+			('p1', 'f1', 'http://bazaar.onion/threads/1/', '`+thread+`', 'RDP access (listing)', 'seller', 'Stored panels', 'Stored vouch', '2025-01-01 10:00:00')`,
+		// Scraped before the text was kept.
+		`INSERT INTO posts (post_id, forum_id, thread_url, content, created_at) VALUES
+			('p2', 'f1', 'http://bazaar.onion/threads/2/', '`+thread+`', '2025-01-02 10:00:00')`,
+	)
+	forums, posts, err := load(db, models.ExportFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forums) != 1 || len(posts) != 2 {
+		t.Fatalf("got %d forums and %d posts", len(forums), len(posts))
+	}
+	want := []Post{
+		{Title: "RDP access (listing)", Author: "seller", Text: "Stored panels\n\nStored vouch"},
+		{Title: "Selling RDP access", Author: "seller", Text: "Fresh panels\n\nVouch"},
+	}
+	for i, p := range posts {
+		if p.Title != want[i].Title || p.Author != want[i].Author || p.Text != want[i].Text {
+			t.Errorf("%s: got %q by %q: %q, want %q by %q: %q", p.ID, p.Title, p.Author, p.Text, want[i].Title, want[i].Author, want[i].Text)
+		}
+	}
+}
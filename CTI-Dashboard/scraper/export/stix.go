@@ -0,0 +1,373 @@
+package export
+
+import (
+	"CTI-Dashboard/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// namespace makes the IDs of exported objects deterministic: the same forum,
+// post, author or indicator gets the same ID in every export, so consumers
+// update objects instead of piling up duplicates.
+var namespace = uuid.MustParse("6f1c3c2e-0c1d-4b59-9a8e-2b7f3f1e6a40")
+
+// scoNamespace is the namespace STIX 2.1 defines for cyber observable IDs.
+var scoNamespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+const stixTime = "2006-01-02T15:04:05.000Z"
+
+// Creation times of the objects every export carries.
+var (
+	markingsCreated = time.Date(2017, 1, 20, 0, 0, 0, 0, time.UTC)
+	producerCreated = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// TLP 1.0 marking definitions as predefined by STIX 2.1.
+var tlpMarkings = map[string]Object{
+	"white": tlpMarking("white", "613f2e26-407d-48c7-9eca-b8e91df99dc9"),
+	"green": tlpMarking("green", "34098fce-860f-48ae-8e50-ebd3cc5e41da"),
+	"amber": tlpMarking("amber", "f88d31f6-486f-44da-b317-01333bde0b82"),
+	"red":   tlpMarking("red", "5e57c739-391a-4eb3-b6be-7d15ca92d5ed"),
+}
+
+// Object is a STIX 2.1 object. Only the properties of the object types this
+// exporter writes are present.
+type Object struct {
+	Type               string              `json:"type"`
+	SpecVersion        string              `json:"spec_version,omitempty"`
+	ID                 string              `json:"id"`
+	Created            string              `json:"created,omitempty"`
+	Modified           string              `json:"modified,omitempty"`
+	CreatedByRef       string              `json:"created_by_ref,omitempty"`
+	Name               string              `json:"name,omitempty"`
+	Description        string              `json:"description,omitempty"`
+	IdentityClass      string              `json:"identity_class,omitempty"`
+	InfrastructureType []string            `json:"infrastructure_types,omitempty"`
+	ThreatActorTypes   []string            `json:"threat_actor_types,omitempty"`
+	ReportTypes        []string            `json:"report_types,omitempty"`
+	Published          string              `json:"published,omitempty"`
+	ObjectRefs         []string            `json:"object_refs,omitempty"`
+	Pattern            string              `json:"pattern,omitempty"`
+	PatternType        string              `json:"pattern_type,omitempty"`
+	ValidFrom          string              `json:"valid_from,omitempty"`
+	RelationshipType   string              `json:"relationship_type,omitempty"`
+	SourceRef          string              `json:"source_ref,omitempty"`
+	TargetRef          string              `json:"target_ref,omitempty"`
+	Value              string              `json:"value,omitempty"`
+	DefinitionType     string              `json:"definition_type,omitempty"`
+	Definition         map[string]string   `json:"definition,omitempty"`
+	Labels             []string            `json:"labels,omitempty"`
+	ExternalReferences []ExternalReference `json:"external_references,omitempty"`
+	ObjectMarkingRefs  []string            `json:"object_marking_refs,omitempty"`
+}
+
+type ExternalReference struct {
+	SourceName string `json:"source_name"`
+	URL        string `json:"url,omitempty"`
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+type Bundle struct {
+	Type    string   `json:"type"`
+	ID      string   `json:"id"`
+	Objects []Object `json:"objects"`
+}
+
+// Entry is an object of an export with the time it entered the data: when
+// the forum it came from was added, or the latest scrape of its post.
+type Entry struct {
+	Object Object
+	Added  time.Time
+}
+
+// STIX builds a STIX 2.1 bundle of the forums and posts the filter selects.
+// Forums become infrastructure, posts reports, authors threat actors, and
+// the indicators found in post text indicators with STIX patterns.
+func STIX(db *sql.DB, filter models.ExportFilter) (*Bundle, error) {
+	entries, err := Entries(db, filter)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &Bundle{Type: "bundle", ID: "bundle--" + uuid.New().String()}
+	for _, entry := range entries {
+		bundle.Objects = append(bundle.Objects, entry.Object)
+	}
+	return bundle, nil
+}
+
+// Entries returns the objects STIX bundles, oldest first and by ID within
+// the same time.
+func Entries(db *sql.DB, filter models.ExportFilter) ([]Entry, error) {
+	tlp := strings.ToLower(strings.TrimPrefix(strings.ToUpper(filter.TLP), "TLP:"))
+	if tlp == "" {
+		tlp = "amber"
+	}
+	if tlp == "clear" {
+		tlp = "white"
+	}
+	marking, ok := tlpMarkings[tlp]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown TLP %q", ErrFilter, filter.TLP)
+	}
+
+	forums, posts, err := load(db, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &builder{marking: marking.ID, objects: make(map[string]Entry)}
+	b.add(marking, markingsCreated)
+	producer := Object{
+		Type:          "identity",
+		SpecVersion:   "2.1",
+		ID:            id("identity", "CTI-Dashboard"),
+		Created:       stamp(producerCreated),
+		Modified:      stamp(producerCreated),
+		Name:          "CTI-Dashboard",
+		IdentityClass: "system",
+	}
+	b.add(producer, producerCreated)
+	b.producer = producer.ID
+
+	infrastructure := make(map[string]string)
+	for _, forum := range forums {
+		b.added = forum.Created
+		infrastructure[forum.ID] = b.forum(forum)
+	}
+	for _, post := range posts {
+		b.added = post.Scraped
+		if post.Modified.After(b.added) {
+			b.added = post.Modified
+		}
+		b.post(post, infrastructure[post.ForumID])
+	}
+
+	entries := make([]Entry, 0, len(b.objects))
+	for _, entry := range b.objects {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Added.Equal(entries[j].Added) {
+			return entries[i].Added.Before(entries[j].Added)
+		}
+		return entries[i].Object.ID < entries[j].Object.ID
+	})
+	return entries, nil
+}
+
+// STIXJSON is STIX encoded as indented JSON.
+func STIXJSON(db *sql.DB, filter models.ExportFilter) (string, error) {
+	bundle, err := STIX(db, filter)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+type builder struct {
+	producer string
+	marking  string
+	objects  map[string]Entry
+	// added is when the forum or post being built entered the data: the
+	// forum when it was added, the post when it was last scraped or
+	// changed.
+	added time.Time
+}
+
+// add keeps the first version of every object; later ones with the same ID
+// are the same forum, actor or indicator seen again, and only move the
+// time it was added back if they were seen before.
+func (b *builder) add(object Object, added time.Time) string {
+	entry, ok := b.objects[object.ID]
+	if !ok {
+		b.objects[object.ID] = Entry{Object: object, Added: added}
+	} else if added.Before(entry.Added) {
+		entry.Added = added
+		b.objects[object.ID] = entry
+	}
+	return object.ID
+}
+
+// sdo fills in the properties every domain and relationship object shares
+// and adds the object with the time the current forum or post was added.
+// An object is not modified after it was created unless Modified is set.
+func (b *builder) sdo(object Object, created time.Time) string {
+	object.SpecVersion = "2.1"
+	object.Created = stamp(created)
+	if object.Modified == "" {
+		object.Modified = object.Created
+	}
+	object.CreatedByRef = b.producer
+	object.ObjectMarkingRefs = []string{b.marking}
+	return b.add(object, b.added)
+}
+
+func (b *builder) forum(forum Forum) string {
+	description := forum.Description
+	if forum.Engine != "" {
+		description = strings.TrimSpace(description + "\n\nForum engine: " + forum.Engine)
+	}
+	infrastructure := b.sdo(Object{
+		Type:               "infrastructure",
+		ID:                 id("infrastructure", forum.ID),
+		Name:               forum.Name,
+		Description:        description,
+		InfrastructureType: []string{"unknown"},
+		Labels:             labels("status", forum.Status),
+		ExternalReferences: []ExternalReference{{SourceName: "forum", URL: forum.URL, ExternalID: forum.ID}},
+	}, forum.Created)
+	if forum.URL != "" {
+		address := b.add(Object{
+			Type:              "url",
+			SpecVersion:       "2.1",
+			ID:                scoID("url", forum.URL),
+			Value:             forum.URL,
+			ObjectMarkingRefs: []string{b.marking},
+		}, forum.Created)
+		b.relate(infrastructure, "consists-of", address, forum.Created)
+	}
+	return infrastructure
+}
+
+func (b *builder) post(post Post, infrastructure string) {
+	refs := []string{}
+	if infrastructure != "" {
+		refs = append(refs, infrastructure)
+	}
+
+	actor := ""
+	if post.Author != "" {
+		actor = b.sdo(Object{
+			Type:             "threat-actor",
+			ID:               id("threat-actor", post.ForumID+"/"+strings.ToLower(post.Author)),
+			Name:             post.Author,
+			ThreatActorTypes: []string{"unknown"},
+		}, post.Created)
+		refs = append(refs, actor)
+		if infrastructure != "" {
+			refs = append(refs, b.relate(actor, "uses", infrastructure, post.Created))
+		}
+	}
+
+	for _, indicator := range ExtractIndicators(post.Text) {
+		pattern := Pattern(indicator)
+		object := b.sdo(Object{
+			Type:        "indicator",
+			ID:          id("indicator", pattern),
+			Name:        indicator.Value,
+			Pattern:     pattern,
+			PatternType: "stix",
+			ValidFrom:   stamp(post.Created),
+			Labels:      []string{indicator.Type},
+		}, post.Created)
+		refs = append(refs, object)
+		if actor != "" {
+			refs = append(refs, b.relate(object, "indicates", actor, post.Created))
+		}
+	}
+
+	// A report must refer to something; without a forum or author that is
+	// the producer.
+	if len(refs) == 0 {
+		refs = append(refs, b.producer)
+	}
+	title := post.Title
+	if title == "" {
+		title = post.ThreadURL
+	}
+	// The report carries the post's text and severity, so it is a new
+	// version whenever they change.
+	modified := post.Created
+	if post.Modified.After(modified) {
+		modified = post.Modified
+	}
+	b.sdo(Object{
+		Type:               "report",
+		ID:                 id("report", post.ID),
+		Name:               title,
+		Description:        post.Text,
+		ReportTypes:        []string{"threat-actor"},
+		Published:          stamp(post.Created),
+		Modified:           stamp(modified),
+		ObjectRefs:         refs,
+		Labels:             labels("severity", post.Severity),
+		ExternalReferences: []ExternalReference{{SourceName: "thread", URL: post.ThreadURL, ExternalID: post.ID}},
+	}, post.Created)
+}
+
+func (b *builder) relate(source string, kind string, target string, created time.Time) string {
+	return b.sdo(Object{
+		Type:             "relationship",
+		ID:               id("relationship", source+" "+kind+" "+target),
+		RelationshipType: kind,
+		SourceRef:        source,
+		TargetRef:        target,
+	}, created)
+}
+
+// Pattern is the STIX pattern matching an indicator.
+func Pattern(indicator Indicator) string {
+	value := strings.ReplaceAll(strings.ReplaceAll(indicator.Value, `\`, `\\`), `'`, `\'`)
+	switch indicator.Type {
+	case IndicatorURL:
+		return fmt.Sprintf("[url:value = '%s']", value)
+	case IndicatorDomain:
+		return fmt.Sprintf("[domain-name:value = '%s']", value)
+	case IndicatorIPv4:
+		return fmt.Sprintf("[ipv4-addr:value = '%s']", value)
+	case IndicatorMD5:
+		return fmt.Sprintf("[file:hashes.MD5 = '%s']", value)
+	case IndicatorSHA1:
+		return fmt.Sprintf("[file:hashes.'SHA-1' = '%s']", value)
+	case IndicatorSHA256:
+		return fmt.Sprintf("[file:hashes.'SHA-256' = '%s']", value)
+	}
+	return ""
+}
+
+func id(kind string, key string) string {
+	return kind + "--" + uuid.NewSHA1(namespace, []byte(kind+":"+key)).String()
+}
+
+// scoID is the ID STIX 2.1 prescribes for an observable identified by its
+// value.
+func scoID(kind string, value string) string {
+	key, _ := json.Marshal(map[string]string{"value": value})
+	return kind + "--" + uuid.NewSHA1(scoNamespace, key).String()
+}
+
+func tlpMarking(level string, markingID string) Object {
+	return Object{
+		Type:           "marking-definition",
+		SpecVersion:    "2.1",
+		ID:             "marking-definition--" + markingID,
+		Created:        stamp(markingsCreated),
+		Name:           "TLP:" + strings.ToUpper(level),
+		DefinitionType: "tlp",
+		Definition:     map[string]string{"tlp": level},
+	}
+}
+
+func labels(name string, value string) []string {
+	if value == "" || value == "unknown" || value == "unassigned" {
+		return nil
+	}
+	return []string{name + ":" + value}
+}
+
+func stamp(t time.Time) string {
+	if t.IsZero() {
+		t = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t.UTC().Format(stixTime)
+}
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"CTI-Dashboard/models"
+	"testing"
+)
+
+func report(t *testing.T, entries []Entry) Entry {
+	t.Helper()
+	for _, entry := range entries {
+		if entry.Object.Type == "report" {
+			return entry
+		}
+	}
+	t.Fatal("no report")
+	return Entry{}
+}
+
+func TestReportModified(t *testing.T) {
+	db := setup(t,
+		`INSERT INTO forums (forum_id, forum_name, forum_url) VALUES ('f1', 'Exploit Bazaar', 'http://bazaar.onion/')`,
+		`INSERT INTO posts (post_id, forum_id, thread_url, content, title, author, body_text, severity_level, created_at) VALUES
+			('p1', 'f1', 'http://bazaar.onion/threads/1/', '`+thread+`', 'Selling RDP access', 'seller', 'Fresh panels', 'low', '2025-01-01 10:00:00')`,
+	)
+	entries, err := Entries(db, models.ExportFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := report(t, entries)
+	if before.Object.Modified != before.Object.Created {
+		t.Errorf("unchanged report: modified %s, created %s", before.Object.Modified, before.Object.Created)
+	}
+
+	// Rescored: a new version of the same report, added again.
+	if _, err := db.Exec(`UPDATE posts SET severity_level = 'high' WHERE post_id = 'p1'`); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = Entries(db, models.ExportFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := report(t, entries)
+	if after.Object.ID != before.Object.ID || after.Object.Modified <= before.Object.Modified || !after.Added.After(before.Added) {
+		t.Errorf("rescored report: got %s modified %s added %v, was modified %s added %v",
+			after.Object.ID, after.Object.Modified, after.Added, before.Object.Modified, before.Added)
+	}
+
+	// Scraped again without a change: the same version.
+	if _, err := db.Exec(`UPDATE posts SET severity_level = 'high', body_text = 'Fresh panels' WHERE post_id = 'p1'`); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = Entries(db, models.ExportFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := report(t, entries); again.Object.Modified != after.Object.Modified {
+		t.Errorf("unchanged post: modified %s, was %s", again.Object.Modified, after.Object.Modified)
+	}
+}
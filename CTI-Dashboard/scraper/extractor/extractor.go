@@ -84,10 +84,25 @@ func extractor_XF(doc *goquery.Document, db *sql.DB, forum_id string) (string, e
 
 func ExtractThreadLinks(doc *goquery.Document, baseURL string) []string {
 	var links []string
+	for _, thread := range ExtractThreads(doc, baseURL) {
+		links = append(links, thread.URL)
+	}
+	return links
+}
+
+// Thread is a thread as listed on a forum page.
+type Thread struct {
+	URL   string
+	Title string
+}
+
+// ExtractThreads returns the threads listed on a XenForo or phpBB forum page.
+func ExtractThreads(doc *goquery.Document, baseURL string) []Thread {
+	var threads []Thread
 	parsedURL, err := url.Parse(baseURL)
 	if err != nil {
 		logger.Error("Could not parse base URL", "url", baseURL, "error", err)
-		return links
+		return threads
 	}
 	rootURL := parsedURL.Scheme + "://" + parsedURL.Host
 	doc.Find("div.structItem-title a").Each(func(i int, s *goquery.Selection) {
@@ -97,12 +112,30 @@ func ExtractThreadLinks(doc *goquery.Document, baseURL string) []string {
 			if threadIndex != -1 {
 				correctedHref := href[threadIndex:]
 				fullURL := rootURL + correctedHref
-				links = append(links, fullURL)
+				threads = append(threads, Thread{URL: fullURL, Title: strings.TrimSpace(s.Text())})
 			}
 		}
 	})
 
-	return links
+	// phpBB links topics relative to the page, with the visitor's session
+	// ID, which is dropped so the URL stays the same from scan to scan.
+	doc.Find("a.topictitle").Each(func(i int, s *goquery.Selection) {
+		href, exists := s.Attr("href")
+		if !exists {
+			return
+		}
+		topic, err := parsedURL.Parse(href)
+		if err != nil || !strings.HasSuffix(topic.Path, "viewtopic.php") {
+			return
+		}
+		query := topic.Query()
+		query.Del("sid")
+		topic.RawQuery = query.Encode()
+		topic.Fragment = ""
+		threads = append(threads, Thread{URL: topic.String(), Title: strings.TrimSpace(s.Text())})
+	})
+
+	return threads
 }
 
 func ProcessExtractedLinks(forumID string, links []string, db *sql.DB) error {
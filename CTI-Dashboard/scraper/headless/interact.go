@@ -0,0 +1,39 @@
+package headless
+
+import (
+	"context"
+
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// Point is a position on the page in CSS pixels.
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Screenshot captures the visible part of the page, which is what the
+// analyst needs to see to answer a challenge.
+func Screenshot(ctx context.Context) ([]byte, error) {
+	var buf []byte
+	err := chromedp.Run(ctx, chromedp.CaptureScreenshot(&buf))
+	return buf, err
+}
+
+// Answer clicks the points in order and then, if text is given, types it
+// into input and presses Enter so the form is submitted as a user would.
+// It waits for the page to be ready again afterwards.
+func Answer(ctx context.Context, ready Readiness, clicks []Point, input string, text string) error {
+	var actions []chromedp.Action
+	for _, p := range clicks {
+		actions = append(actions, chromedp.MouseClickXY(p.X, p.Y))
+	}
+	if text != "" && input != "" {
+		actions = append(actions,
+			chromedp.SetValue(input, "", chromedp.ByQuery),
+			chromedp.SendKeys(input, text+kb.Enter, chromedp.ByQuery),
+		)
+	}
+	return Interact(ctx, ready, actions...)
+}
@@ -0,0 +1,219 @@
+package headless
+
+import (
+	"CTI-Dashboard/scraper/logger"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	DefaultTabs         = 4
+	DefaultRecycleAfter = 50
+)
+
+var ErrClosed = errors.New("browser pool is closed")
+
+type Config struct {
+	// MaxTabs bounds the number of tabs open at the same time.
+	MaxTabs int
+	// RecycleAfter restarts Chrome after this many pages to keep its memory
+	// in check.
+	RecycleAfter int
+}
+
+// Pool keeps one long-lived Chrome process and hands out tabs from it. Every
+// tab lives in its own browser context with its own proxy, so tabs of
+// different forums share neither cookies nor Tor circuits.
+type Pool struct {
+	cfg     Config
+	tabs    chan struct{}
+	mu      sync.Mutex
+	current *instance
+	// draining holds retired instances waiting for their last tab.
+	draining map[*instance]bool
+	closed   bool
+}
+
+// instance is one Chrome process.
+type instance struct {
+	allocCancel context.CancelFunc
+	ctx         context.Context
+	cancel      context.CancelFunc
+	pages       int
+	active      int
+	retired     bool
+}
+
+func NewPool(cfg Config) *Pool {
+	if cfg.MaxTabs <= 0 {
+		cfg.MaxTabs = DefaultTabs
+	}
+	if cfg.RecycleAfter <= 0 {
+		cfg.RecycleAfter = DefaultRecycleAfter
+	}
+	return &Pool{
+		cfg:      cfg,
+		tabs:     make(chan struct{}, cfg.MaxTabs),
+		draining: make(map[*instance]bool),
+	}
+}
+
+func allocatorOptions() []chromedp.ExecAllocatorOption {
+	return append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", true),
+		chromedp.Flag("disable-gpu", true),
+		chromedp.Flag("disable-extensions", true),
+		chromedp.Flag("disable-blink-features", "AutomationControlled"),
+		chromedp.Flag("no-first-run", true),
+		chromedp.Flag("no-default-browser-check", true),
+		chromedp.Flag("ignore-certificate-errors", true),
+		chromedp.NoSandbox,
+		chromedp.WindowSize(1920, 1080),
+	)
+}
+
+func launch() (*instance, error) {
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), allocatorOptions()...)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		allocCancel()
+		return nil, err
+	}
+	logger.Info("Launched headless browser")
+	return &instance{allocCancel: allocCancel, ctx: ctx, cancel: cancel}, nil
+}
+
+func (i *instance) close() {
+	i.cancel()
+	i.allocCancel()
+}
+
+// alive reports whether the Chrome process still answers.
+func (i *instance) alive() bool {
+	if i.ctx.Err() != nil {
+		return false
+	}
+	c := chromedp.FromContext(i.ctx)
+	if c == nil || c.Browser == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(i.ctx, 5*time.Second)
+	defer cancel()
+	_, _, _, _, _, err := browser.GetVersion().Do(cdp.WithExecutor(ctx, c.Browser))
+	return err == nil
+}
+
+// retire stops handing out tabs from the instance and closes it once its
+// last tab is released.
+func (p *Pool) retire(i *instance) {
+	i.retired = true
+	if i.active == 0 {
+		i.close()
+	} else {
+		p.draining[i] = true
+	}
+	if p.current == i {
+		p.current = nil
+	}
+}
+
+// Tab opens a tab that talks through proxyServer, empty for a direct
+// connection. The tab must be closed with the returned release func. A
+// crashed browser is replaced transparently.
+func (p *Pool) Tab(ctx context.Context, proxyServer string) (context.Context, func(), error) {
+	select {
+	case p.tabs <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	// The liveness check waits on Chrome, so it is made without the lock;
+	// tabs being released meanwhile must not wait for it.
+	p.mu.Lock()
+	current := p.current
+	p.mu.Unlock()
+	alive := current != nil && current.alive()
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.tabs
+		return nil, nil, ErrClosed
+	}
+	// Another caller may have replaced the instance in the meantime.
+	if p.current != nil && p.current == current && (p.current.pages >= p.cfg.RecycleAfter || !alive) {
+		logger.Info("Recycling headless browser", "pages", p.current.pages)
+		p.retire(p.current)
+	}
+	if p.current == nil {
+		inst, err := launch()
+		if err != nil {
+			p.mu.Unlock()
+			<-p.tabs
+			return nil, nil, err
+		}
+		p.current = inst
+	}
+	inst := p.current
+	inst.pages++
+	inst.active++
+	p.mu.Unlock()
+
+	tabCtx, tabCancel := chromedp.NewContext(inst.ctx, chromedp.WithNewBrowserContext(
+		func(params *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+			if proxyServer == "" {
+				return params
+			}
+			return params.WithProxyServer(proxyServer)
+		},
+	))
+	// Tie the tab to the caller's context as well as to the browser.
+	stop := context.AfterFunc(ctx, tabCancel)
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			stop()
+			tabCancel()
+			p.mu.Lock()
+			inst.active--
+			if inst.retired && inst.active == 0 {
+				inst.close()
+				delete(p.draining, inst)
+			}
+			p.mu.Unlock()
+			<-p.tabs
+		})
+	}
+	// Create the target now, with the tab's own context, so callers can run
+	// later actions under shorter timeouts without closing the tab.
+	if err := chromedp.Run(tabCtx); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return tabCtx, release, nil
+}
+
+// Close shuts Chrome down, retired instances included. Tabs still open are
+// cancelled.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.current != nil {
+		p.current.close()
+		p.current = nil
+	}
+	for inst := range p.draining {
+		inst.close()
+		delete(p.draining, inst)
+	}
+}
@@ -0,0 +1,140 @@
+package headless
+
+import (
+	"CTI-Dashboard/scraper/logger"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	ReadyNetworkIdle = "network-idle"
+	ReadySelector    = "selector"
+	ReadyMaxWait     = "max-wait"
+)
+
+const (
+	DefaultMaxWait = 25 * time.Second
+	// The network counts as idle once no request has been in flight for
+	// this long.
+	idleQuiet = 500 * time.Millisecond
+)
+
+// Readiness decides when a page has finished rendering and can be captured.
+// Whatever the strategy, waiting never exceeds MaxWait.
+type Readiness struct {
+	Strategy string
+	Selector string
+	MaxWait  time.Duration
+}
+
+func (r Readiness) Validate() error {
+	switch r.Strategy {
+	case "", ReadyNetworkIdle, ReadyMaxWait:
+		return nil
+	case ReadySelector:
+		if r.Selector == "" {
+			return errors.New("selector readiness needs a selector")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown readiness strategy %q", r.Strategy)
+}
+
+// Budget is the longest Navigate waits beyond the page load itself.
+func (r Readiness) Budget() time.Duration {
+	if r.MaxWait <= 0 {
+		return DefaultMaxWait
+	}
+	return r.MaxWait
+}
+
+// Navigate loads targetURL in the tab and waits until the page is ready.
+// Running out of MaxWait is not an error; the page is used as it is.
+func Navigate(ctx context.Context, targetURL string, ready Readiness) error {
+	return Interact(ctx, ready, network.Enable(), chromedp.Navigate(targetURL))
+}
+
+// Interact runs actions that may load a new page, such as submitting a form,
+// and waits until the page is ready again.
+func Interact(ctx context.Context, ready Readiness, actions ...chromedp.Action) error {
+	tracker := &idleTracker{inFlight: make(map[network.RequestID]struct{}), last: time.Now()}
+	if ready.Strategy == "" || ready.Strategy == ReadyNetworkIdle {
+		chromedp.ListenTarget(ctx, tracker.handle)
+	}
+
+	actions = append(actions, chromedp.WaitReady(`body`, chromedp.ByQuery))
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return err
+	}
+
+	switch ready.Strategy {
+	case ReadyMaxWait:
+		return chromedp.Run(ctx, chromedp.Sleep(ready.Budget()))
+	case ReadySelector:
+		waitCtx, cancel := context.WithTimeout(ctx, ready.Budget())
+		defer cancel()
+		err := chromedp.Run(waitCtx, chromedp.WaitVisible(ready.Selector, chromedp.ByQuery))
+		if err != nil && ctx.Err() == nil {
+			logger.Info("Selector did not appear before max wait", "selector", ready.Selector)
+			return nil
+		}
+		return err
+	default:
+		return tracker.wait(ctx, ready.Budget())
+	}
+}
+
+// idleTracker counts requests in flight from network events.
+type idleTracker struct {
+	mu       sync.Mutex
+	inFlight map[network.RequestID]struct{}
+	last     time.Time
+}
+
+func (t *idleTracker) handle(ev any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch ev := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		t.inFlight[ev.RequestID] = struct{}{}
+	case *network.EventLoadingFinished:
+		delete(t.inFlight, ev.RequestID)
+	case *network.EventLoadingFailed:
+		delete(t.inFlight, ev.RequestID)
+	default:
+		return
+	}
+	t.last = time.Now()
+}
+
+func (t *idleTracker) idle() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.inFlight) == 0 && time.Since(t.last) >= idleQuiet
+}
+
+func (t *idleTracker) wait(ctx context.Context, maxWait time.Duration) error {
+	deadline := time.NewTimer(maxWait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if t.idle() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			logger.Info("Network did not go idle before max wait")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package headless
+
+import (
+	"CTI-Dashboard/scraper/logger"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+)
+
+// Page is a page as the browser rendered it.
+type Page struct {
+	Status     int
+	StatusText string
+	URL        string
+	HTML       string
+	Screenshot []byte
+	// MHTML is a single-file archive of the page, PDF a print of it with
+	// working links and searchable text.
+	MHTML []byte
+	PDF   []byte
+}
+
+type RenderOptions struct {
+	Readiness Readiness
+	// Cookies, when set, are loaded into the tab before navigating and
+	// updated with whatever the site set, under CookieKey.
+	Cookies    *CookieJar
+	CookieKey  string
+	HTML       bool
+	Screenshot bool
+	MHTML      bool
+	PDF        bool
+}
+
+// Render loads targetURL in the tab, waits for it to be ready and returns
+// the rendered DOM, a full-page screenshot and the MHTML and PDF captures
+// asked for. The MHTML and PDF captures are best-effort: one that fails is
+// logged and left out.
+func Render(ctx context.Context, targetURL string, opts RenderOptions) (*Page, error) {
+	var mu sync.Mutex
+	responses := make(map[string]*network.Response)
+	chromedp.ListenTarget(ctx, func(ev any) {
+		if ev, ok := ev.(*network.EventResponseReceived); ok && ev.Type == network.ResourceTypeDocument {
+			mu.Lock()
+			responses[string(ev.FrameID)] = ev.Response
+			mu.Unlock()
+		}
+	})
+
+	if opts.Cookies != nil {
+		if cookies := opts.Cookies.Get(opts.CookieKey); len(cookies) > 0 {
+			if err := chromedp.Run(ctx, network.SetCookies(cookies)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := Navigate(ctx, targetURL, opts.Readiness); err != nil {
+		return nil, err
+	}
+
+	result := &Page{}
+	var frameTree *page.FrameTree
+	actions := []chromedp.Action{
+		chromedp.Location(&result.URL),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			frameTree, err = page.GetFrameTree().Do(ctx)
+			return err
+		}),
+	}
+	if opts.HTML {
+		actions = append(actions, chromedp.OuterHTML("html", &result.HTML, chromedp.ByQuery))
+	}
+	if opts.Screenshot {
+		actions = append(actions, chromedp.FullScreenshot(&result.Screenshot, 90))
+	}
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return nil, err
+	}
+	if opts.MHTML {
+		err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			data, err := page.CaptureSnapshot().WithFormat(page.CaptureSnapshotFormatMhtml).Do(ctx)
+			result.MHTML = []byte(data)
+			return err
+		}))
+		if err != nil {
+			logger.Error("Could not capture MHTML", "error", err, "target", targetURL)
+			result.MHTML = nil
+		}
+	}
+	if opts.PDF {
+		err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			data, _, err := page.PrintToPDF().WithPrintBackground(true).Do(ctx)
+			result.PDF = data
+			return err
+		}))
+		if err != nil {
+			logger.Error("Could not capture PDF", "error", err, "target", targetURL)
+			result.PDF = nil
+		}
+	}
+
+	mu.Lock()
+	if response, ok := responses[string(frameTree.Frame.ID)]; ok {
+		result.Status = int(response.Status)
+		result.StatusText = response.StatusText
+	}
+	mu.Unlock()
+
+	if opts.Cookies != nil {
+		if err := SaveCookies(ctx, opts.Cookies, opts.CookieKey); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// SaveCookies stores the cookies of the tab's browser context in the jar.
+func SaveCookies(ctx context.Context, jar *CookieJar, key string) error {
+	c := chromedp.FromContext(ctx)
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		cookies, err := storage.GetCookies().WithBrowserContextID(c.BrowserContextID).Do(ctx)
+		if err != nil {
+			return err
+		}
+		jar.Set(key, cookies)
+		return nil
+	}))
+}
+
+// CookieJar keeps browser cookies per key (usually a forum ID) so that
+// challenge and session cookies survive from one tab to the next.
+type CookieJar struct {
+	mu      sync.Mutex
+	cookies map[string][]*network.CookieParam
+}
+
+func NewCookieJar() *CookieJar {
+	return &CookieJar{cookies: make(map[string][]*network.CookieParam)}
+}
+
+func (j *CookieJar) Get(key string) []*network.CookieParam {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.cookies[key]
+}
+
+func (j *CookieJar) Set(key string, cookies []*network.Cookie) {
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		param := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+			SameSite: c.SameSite,
+		}
+		if !c.Session && c.Expires > 0 {
+			expires := cdp.TimeSinceEpoch(time.Unix(int64(c.Expires), 0))
+			param.Expires = &expires
+		}
+		params = append(params, param)
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cookies[key] = params
+}
+
+// Import merges cookies from an HTTP cookie jar, e.g. a session obtained by
+// logging in with the HTTP client, into the cookies kept under key.
+func (j *CookieJar) Import(key string, cookies map[*url.URL][]*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	merged := make(map[string]*network.CookieParam)
+	var order []string
+	add := func(param *network.CookieParam) {
+		id := param.Domain + "|" + param.Path + "|" + param.Name
+		if _, ok := merged[id]; !ok {
+			order = append(order, id)
+		}
+		merged[id] = param
+	}
+	for _, param := range j.cookies[key] {
+		add(param)
+	}
+	for u, list := range cookies {
+		for _, c := range list {
+			param := &network.CookieParam{
+				Name:     c.Name,
+				Value:    c.Value,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				Secure:   c.Secure,
+				HTTPOnly: c.HttpOnly,
+			}
+			if param.Domain == "" {
+				param.Domain = u.Hostname()
+			}
+			if param.Path == "" {
+				param.Path = "/"
+			}
+			if !c.Expires.IsZero() {
+				expires := cdp.TimeSinceEpoch(c.Expires)
+				param.Expires = &expires
+			}
+			add(param)
+		}
+	}
+	params := make([]*network.CookieParam, 0, len(order))
+	for _, id := range order {
+		params = append(params, merged[id])
+	}
+	j.cookies[key] = params
+}
+
+// Export returns the cookies kept under key for an HTTP cookie jar.
+func (j *CookieJar) Export(key string) map[*url.URL][]*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cookies := make(map[*url.URL][]*http.Cookie)
+	byHost := make(map[string]*url.URL)
+	for _, param := range j.cookies[key] {
+		host := strings.TrimPrefix(param.Domain, ".")
+		if host == "" {
+			continue
+		}
+		u, ok := byHost[host]
+		if !ok {
+			u = &url.URL{Scheme: "http", Host: host, Path: "/"}
+			byHost[host] = u
+		}
+		c := &http.Cookie{
+			Name:     param.Name,
+			Value:    param.Value,
+			Path:     param.Path,
+			Secure:   param.Secure,
+			HttpOnly: param.HTTPOnly,
+		}
+		if strings.HasPrefix(param.Domain, ".") {
+			c.Domain = param.Domain
+		}
+		if param.Expires != nil {
+			c.Expires = param.Expires.Time()
+		}
+		cookies[u] = append(cookies[u], c)
+	}
+	return cookies
+}
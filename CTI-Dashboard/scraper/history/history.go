@@ -0,0 +1,286 @@
+package history
+
+import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type Kind string
+
+const (
+	KindForum Kind = "forum"
+	KindPost  Kind = "post"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailed  = "failed"
+)
+
+// Error classes stored with every failed run.
+const (
+	ClassTimeout    = "timeout"
+	ClassRefused    = "connection_refused"
+	ClassDNS        = "dns"
+	ClassProxy      = "proxy"
+	ClassNetwork    = "network"
+	ClassTor        = "tor_check"
+	ClassHTTPStatus = "http_status"
+	ClassReadBody   = "read_body"
+	ClassScreenshot = "screenshot"
+	ClassWrite      = "write"
+	ClassAuth       = "auth"
+	ClassCaptcha    = "captcha"
+	ClassUnknown    = "unknown"
+)
+
+// ClassError tags an error with the stage of the scrape it came from.
+type ClassError struct {
+	Class string
+	Err   error
+}
+
+func (e *ClassError) Error() string { return e.Err.Error() }
+func (e *ClassError) Unwrap() error { return e.Err }
+
+func WithClass(class string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ClassError{Class: class, Err: err}
+}
+
+// Run is a single scrape attempt of one target, recorded in scan_runs.
+type Run struct {
+	RunID      string
+	ForumID    string
+	Target     string
+	Kind       Kind
+	StartedAt  time.Time
+	HTTPStatus int
+	Bytes      int64
+	ExitIP     string
+	// PageClass is the classifier's verdict on the page, if one was fetched.
+	PageClass string
+	Attempts  []models.ScanAttempt
+}
+
+func Start(forumID string, target string, kind Kind) *Run {
+	return &Run{
+		RunID:     uuid.New().String(),
+		ForumID:   forumID,
+		Target:    target,
+		Kind:      kind,
+		StartedAt: time.Now().UTC(),
+	}
+}
+
+// Finish stores the run with the outcome derived from err.
+func (r *Run) Finish(db *sql.DB, err error) {
+	if db == nil {
+		return
+	}
+	finished := time.Now().UTC()
+	outcome := OutcomeSuccess
+	var errClass, errText sql.NullString
+	if err != nil {
+		outcome = OutcomeFailed
+		errClass = sql.NullString{String: Classify(err), Valid: true}
+		errText = sql.NullString{String: err.Error(), Valid: true}
+	}
+	var forumID sql.NullString
+	if r.ForumID != "" {
+		forumID = sql.NullString{String: r.ForumID, Valid: true}
+	}
+
+	var pageClass, attempts sql.NullString
+	if r.PageClass != "" {
+		pageClass = sql.NullString{String: r.PageClass, Valid: true}
+	}
+	if len(r.Attempts) > 0 {
+		if data, err := json.Marshal(r.Attempts); err == nil {
+			attempts = sql.NullString{String: string(data), Valid: true}
+		}
+	}
+
+	_, dbErr := db.Exec(`INSERT INTO scan_runs (run_id, forum_id, target_url, kind, started_at, finished_at, duration_ms, outcome, http_status, bytes, error_class, error, exit_ip, page_class, attempts)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.RunID, forumID, r.Target, string(r.Kind),
+		stored.Time(r.StartedAt), stored.Time(finished), finished.Sub(r.StartedAt).Milliseconds(),
+		outcome, r.HTTPStatus, r.Bytes, errClass, errText, r.ExitIP, pageClass, attempts,
+	)
+	if dbErr != nil {
+		logger.Error("Could not record scan run", "error", dbErr, "target", r.Target)
+	}
+}
+
+// Classify maps an error to one of the Class* constants. Errors tagged with
+// WithClass keep their class, anything else is treated as a network error.
+func Classify(err error) string {
+	if err == nil {
+		return ""
+	}
+	var classErr *ClassError
+	if errors.As(err, &classErr) {
+		return classErr.Class
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ClassTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ClassRefused
+	case errors.As(err, &dnsErr):
+		return ClassDNS
+	case strings.Contains(strings.ToLower(err.Error()), "socks"):
+		return ClassProxy
+	case errors.As(err, &netErr):
+		return ClassNetwork
+	}
+	return ClassUnknown
+}
+
+func GetHistory(db *sql.DB, forumID string, limit int) ([]models.ScanRun, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	statement, err := db.Prepare(`SELECT run_id, forum_id, target_url, kind, started_at, finished_at, duration_ms, outcome, http_status, bytes, error_class, error, exit_ip, page_class, attempts
+		FROM scan_runs WHERE forum_id = ? ORDER BY started_at DESC LIMIT ?`)
+	if err != nil {
+		logger.Error("Could not prepare statement", "error", err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(forumID, limit)
+	if err != nil {
+		logger.Error("Could not query scan runs", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []models.ScanRun
+	for rows.Next() {
+		var run models.ScanRun
+		var finishedAt, errClass, errText, exitIP, pageClass, attempts sql.NullString
+		err := rows.Scan(&run.RunID, &run.ForumID, &run.TargetURL, &run.Kind, &run.StartedAt, &finishedAt,
+			&run.DurationMs, &run.Outcome, &run.HTTPStatus, &run.Bytes, &errClass, &errText, &exitIP, &pageClass, &attempts)
+		if err != nil {
+			logger.Error("Could not scan run row", "error", err)
+			continue
+		}
+		run.FinishedAt = finishedAt.String
+		run.ErrorClass = errClass.String
+		run.Error = errText.String
+		run.ExitIP = exitIP.String
+		run.PageClass = pageClass.String
+		if attempts.Valid {
+			if err := json.Unmarshal([]byte(attempts.String), &run.Attempts); err != nil {
+				logger.Error("Could not decode attempt log", "error", err, "run_id", run.RunID)
+			}
+		}
+		runs = append(runs, run)
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("Error during rows iteration", "error", err)
+		return nil, err
+	}
+	return runs, nil
+}
+
+// GetHealth derives uptime and availability figures from the forum's scan runs.
+// Uptime covers every recorded run, availability only the last 7 and 30 days.
+func GetHealth(db *sql.DB, forumID string) (models.ForumHealth, error) {
+	health := models.ForumHealth{ForumID: forumID}
+	rows, err := db.Query(`SELECT started_at, outcome, duration_ms, error_class FROM scan_runs
+		WHERE forum_id = ? AND kind = ? ORDER BY started_at DESC`, forumID, string(KindForum))
+	if err != nil {
+		logger.Error("Could not query scan runs", "error", err)
+		return health, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	var total7, ok7, total30, ok30 int
+	var durationSum int64
+	countingFailures := true
+	for rows.Next() {
+		var startedAt time.Time
+		var outcome string
+		var duration int64
+		var errClass sql.NullString
+		if err := rows.Scan(&startedAt, &outcome, &duration, &errClass); err != nil {
+			logger.Error("Could not scan run row", "error", err)
+			continue
+		}
+		health.TotalRuns++
+		durationSum += duration
+		success := outcome == OutcomeSuccess
+		if success {
+			health.SuccessfulRuns++
+			countingFailures = false
+			if health.LastSuccess == "" {
+				health.LastSuccess = startedAt.Format(time.RFC3339)
+			}
+		} else {
+			health.FailedRuns++
+			if countingFailures {
+				health.ConsecutiveFailures++
+			}
+			if health.LastFailure == "" {
+				health.LastFailure = startedAt.Format(time.RFC3339)
+				health.LastErrorClass = errClass.String
+			}
+		}
+
+		age := now.Sub(startedAt)
+		if age <= 30*24*time.Hour {
+			total30++
+			if success {
+				ok30++
+			}
+		}
+		if age <= 7*24*time.Hour {
+			total7++
+			if success {
+				ok7++
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("Error during rows iteration", "error", err)
+		return health, err
+	}
+
+	health.Uptime = ratio(health.SuccessfulRuns, health.TotalRuns)
+	health.Availability7d = ratio(ok7, total7)
+	health.Availability30d = ratio(ok30, total30)
+	if health.TotalRuns > 0 {
+		health.AvgDurationMs = durationSum / int64(health.TotalRuns)
+	}
+	return health, nil
+}
+
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
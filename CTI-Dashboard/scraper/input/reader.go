@@ -2,20 +2,164 @@ package input
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
-func ReadTargets(filePath string) ([]string, error) {
-	file, err := os.Open(filePath)
+// Target is a forum listed in a targets file.
+type Target struct {
+	Name        string `yaml:"name"`
+	URL         string `yaml:"url"`
+	Description string `yaml:"description"`
+	// Engine is a hint at the forum software, kept until a scrape
+	// identifies it.
+	Engine   string   `yaml:"engine"`
+	Tags     []string `yaml:"tags"`
+	Schedule string   `yaml:"schedule"`
+	// Row is the line the target starts on.
+	Row int `yaml:"-"`
+}
+
+// ReadTargets reads a targets file. Files ending in .yaml or .yml hold a
+// list of targets, either at the top or under a targets key; an entry may
+// also be a bare URL. Files ending in .csv have a header row naming the
+// columns name, url, description, engine, tags and schedule, with tags
+// separated by semicolons. Any other file lists one URL per line, optionally
+// followed by the name of the forum; blank lines and lines starting with #
+// are skipped.
+func ReadTargets(filePath string) ([]Target, error) {
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return readYAML(data)
+	case ".csv":
+		return readCSV(data)
+	}
+	return readLines(data)
+}
+
+func readYAML(data []byte) ([]Target, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	list := root.Content[0]
+	if list.Kind == yaml.MappingNode {
+		list = nil
+		for i := 0; i+1 < len(root.Content[0].Content); i += 2 {
+			if root.Content[0].Content[i].Value == "targets" {
+				list = root.Content[0].Content[i+1]
+			}
+		}
+		if list == nil {
+			return nil, errors.New("no targets list in file")
+		}
+	}
+	if list.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: targets must be a list", list.Line)
+	}
+
+	var targets []Target
+	for _, item := range list.Content {
+		var target Target
+		if item.Kind == yaml.ScalarNode {
+			target.URL = item.Value
+		} else if err := item.Decode(&target); err != nil {
+			return nil, fmt.Errorf("line %d: %w", item.Line, err)
+		}
+		target.Row = item.Line
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+var csvColumns = []string{"name", "url", "description", "engine", "tags", "schedule"}
+
+func readCSV(data []byte) ([]Target, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, known := range csvColumns {
+			if name == known {
+				columns[name] = i
+			}
+		}
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, errors.New("CSV header has no url column")
+	}
+
+	var targets []Target
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		line, _ := reader.FieldPos(0)
+		target := Target{
+			Name:        field("name"),
+			URL:         field("url"),
+			Description: field("description"),
+			Engine:      field("engine"),
+			Schedule:    field("schedule"),
+			Row:         line,
+		}
+		for _, tag := range strings.Split(field("tags"), ";") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				target.Tags = append(target.Tags, tag)
+			}
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
 
-	var targets []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		targets = append(targets, scanner.Text())
+func readLines(data []byte) ([]Target, error) {
+	var targets []Target
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		targets = append(targets, Target{URL: fields[0], Name: strings.Join(fields[1:], " "), Row: line})
 	}
 	if err := scanner.Err(); err != nil {
 		return nil, err
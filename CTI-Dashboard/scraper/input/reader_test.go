@@ -0,0 +1,99 @@
+package input
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const duckduckgo = "duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczad"
+
+func TestCheckURL(t *testing.T) {
+	for _, test := range []struct {
+		url  string
+		want error
+	}{
+		{"http://" + duckduckgo + ".onion/", nil},
+		{"https://forum." + duckduckgo + ".onion/threads/", nil},
+		{"https://example.com/forum/", nil},
+		{"http://duckduckgogg42xjoc72x3sjasowoarfbgcmvfimaftt6twagswzczae.onion/", ErrOnionChecksum},
+		{"http://3g2upl4pq6kufc4m.onion/", ErrOnionV2},
+	} {
+		if err := CheckURL(test.url); !errors.Is(err, test.want) {
+			t.Errorf("CheckURL(%q) = %v, want %v", test.url, err, test.want)
+		}
+	}
+	for _, url := range []string{"ftp://example.com/", "forum.onion", "http://short.onion/"} {
+		if err := CheckURL(url); err == nil {
+			t.Errorf("CheckURL(%q) accepted", url)
+		}
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	want := "http://" + duckduckgo + ".onion/forums"
+	for _, url := range []string{
+		"http://" + duckduckgo + ".onion/forums/",
+		"HTTP://" + duckduckgo + ".ONION:80/forums#top",
+	} {
+		if got := NormalizeURL(url); got != want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestReadTargets(t *testing.T) {
+	want := []Target{
+		{Name: "Bazaar", URL: "http://bazaar.example/", Description: "market", Engine: "XenForo", Tags: []string{"market", "carding"}, Schedule: "6h"},
+		{URL: "http://plain.example/"},
+	}
+	files := map[string]string{
+		"targets.yaml": `targets:
+  - name: Bazaar
+    url: http://bazaar.example/
+    description: market
+    engine: XenForo
+    tags: [market, carding]
+    schedule: 6h
+  - http://plain.example/
+`,
+		"targets.csv": `url,name,description,engine,tags,schedule
+http://bazaar.example/,Bazaar,market,XenForo,market;carding,6h
+http://plain.example/,,,,,
+`,
+		"targets.txt": `# forums
+http://bazaar.example/ Bazaar
+
+http://plain.example/
+`,
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		targets, err := ReadTargets(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(targets) != len(want) {
+			t.Fatalf("%s: %d targets, want %d", name, len(targets), len(want))
+		}
+		for i, target := range targets {
+			if target.Row == 0 {
+				t.Errorf("%s: target %d has no row", name, i)
+			}
+			expected := want[i]
+			if name == "targets.txt" {
+				expected = Target{Name: want[i].Name, URL: want[i].URL}
+			}
+			target.Row = 0
+			if !reflect.DeepEqual(target, expected) {
+				t.Errorf("%s: target %d = %+v, want %+v", name, i, target, expected)
+			}
+		}
+	}
+}
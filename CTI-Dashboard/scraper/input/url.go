@@ -0,0 +1,84 @@
+package input
+
+import (
+	"crypto/sha3"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrOnionV2 is returned for 16-character onion addresses, which Tor no
+	// longer resolves.
+	ErrOnionV2 = errors.New("v2 onion addresses are no longer reachable")
+	// ErrOnionChecksum is returned for v3 onion addresses whose checksum or
+	// version byte is wrong, typically a typo.
+	ErrOnionChecksum = errors.New("onion address checksum does not match")
+)
+
+// CheckURL checks that a target is an http or https URL and, for .onion
+// hosts, a well-formed v3 onion address.
+func CheckURL(raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("URL scheme must be http or https: %q", raw)
+	}
+	if target.Hostname() == "" {
+		return fmt.Errorf("URL has no host: %q", raw)
+	}
+	if host := strings.ToLower(target.Hostname()); strings.HasSuffix(host, ".onion") {
+		labels := strings.Split(strings.TrimSuffix(host, ".onion"), ".")
+		return CheckOnion(labels[len(labels)-1])
+	}
+	return nil
+}
+
+// CheckOnion checks a v3 onion address, without the .onion suffix: 56 base32
+// characters encoding the service's public key, a checksum and version 3.
+func CheckOnion(address string) error {
+	address = strings.ToLower(address)
+	if len(address) == 16 {
+		return ErrOnionV2
+	}
+	if len(address) != 56 {
+		return fmt.Errorf("onion address must have 56 characters, %q has %d", address, len(address))
+	}
+	decoded, err := base32.StdEncoding.DecodeString(strings.ToUpper(address))
+	if err != nil {
+		return fmt.Errorf("onion address is not base32: %q", address)
+	}
+	// onion_address = base32(PUBKEY | CHECKSUM | VERSION), with
+	// CHECKSUM = SHA3-256(".onion checksum" | PUBKEY | VERSION)[:2]
+	pubkey, checksum, version := decoded[:32], decoded[32:34], decoded[34]
+	if version != 3 {
+		return ErrOnionChecksum
+	}
+	sum := sha3.Sum256(append(append([]byte(".onion checksum"), pubkey...), version))
+	if sum[0] != checksum[0] || sum[1] != checksum[1] {
+		return ErrOnionChecksum
+	}
+	return nil
+}
+
+// NormalizeURL is the form URLs are compared in to find duplicates: scheme
+// and host in lower case, without default port, trailing slash or fragment.
+func NormalizeURL(raw string) string {
+	target, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return strings.TrimSpace(raw)
+	}
+	target.Scheme = strings.ToLower(target.Scheme)
+	target.Host = strings.ToLower(target.Host)
+	if port := target.Port(); (target.Scheme == "http" && port == "80") || (target.Scheme == "https" && port == "443") {
+		target.Host = target.Hostname()
+	}
+	target.Path = strings.TrimRight(target.Path, "/")
+	target.RawPath = ""
+	target.Fragment = ""
+	return target.String()
+}
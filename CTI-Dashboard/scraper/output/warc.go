@@ -0,0 +1,406 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WARC file rotation: one file per scan run, or one per UTC day that every
+// run of the day is appended to. The GUI, the headless server and the CLI
+// may archive into the same directory at once, so each process appends to
+// its own day file: offsets are taken from the file's size, which another
+// writer could change between the Stat and the Write.
+const (
+	RotateRun = "run"
+	RotateDay = "day"
+)
+
+const warcVersion = "WARC/1.1"
+
+// WARCWriter archives every fetch as WARC 1.1 records in gzip-compressed
+// files. Each record is its own gzip member, so a record can be read back
+// from its offset without decompressing the whole file.
+type WARCWriter struct {
+	dir      string
+	rotation string
+	mu       sync.Mutex
+}
+
+// Capture is one fetch of a page, as it goes into the archive.
+type Capture struct {
+	RunID     string
+	TargetURI string
+	Date      time.Time
+	// Request is the request that was sent. When nil, as for pages rendered
+	// in the browser, a plain GET of TargetURI is recorded.
+	Request *http.Request
+	// Proto and StatusText make up the response's status line, e.g.
+	// "HTTP/1.1" and "200 OK".
+	Proto      string
+	StatusText string
+	Header     http.Header
+	Body       []byte
+	// Screenshot is stored as a resource record when set.
+	Screenshot []byte
+	// Metadata is written as a metadata record about the response.
+	Metadata []Field
+}
+
+// Field is one named value of a metadata record.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// WARCRecord locates a record written to a WARC file.
+type WARCRecord struct {
+	RecordID      string
+	Type          string
+	TargetURI     string
+	Date          string
+	ContentType   string
+	PayloadDigest string
+	// SHA256 is the hex digest of the record's gzip member as written.
+	SHA256 string
+	File   string
+	Offset int64
+	Length int64
+}
+
+// ArchivedRecord is a record read back from a WARC file.
+type ArchivedRecord struct {
+	Header textproto.MIMEHeader
+	Block  []byte
+}
+
+func NewWARCWriter(dir string, rotation string) (*WARCWriter, error) {
+	if rotation != RotateRun && rotation != RotateDay {
+		return nil, fmt.Errorf("unknown WARC rotation %q", rotation)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &WARCWriter{dir: dir, rotation: rotation}, nil
+}
+
+// EnableWARC makes the writer archive fetches as WARC files under
+// <output dir>/warc, rotated per run or per day.
+func (w *Writer) EnableWARC(rotation string) error {
+	warc, err := NewWARCWriter(filepath.Join(w.outputDir, "warc"), rotation)
+	if err != nil {
+		return err
+	}
+	w.warc = warc
+	return nil
+}
+
+// WARC returns the WARC writer, nil when WARC output is off.
+func (w *Writer) WARC() *WARCWriter {
+	if w == nil {
+		return nil
+	}
+	return w.warc
+}
+
+// WriteCapture appends the request, response, metadata and screenshot
+// records of a fetch and returns where they were written.
+func (w *WARCWriter) WriteCapture(c Capture) ([]WARCRecord, error) {
+	if c.Date.IsZero() {
+		c.Date = time.Now()
+	}
+	date := c.Date.UTC().Format(time.RFC3339Nano)
+
+	response := newRecord("response", c.TargetURI, date, "application/http;msgtype=response", responseBlock(c))
+	response.payload = c.Body
+	request := newRecord("request", c.TargetURI, date, "application/http;msgtype=request", requestBlock(c))
+	request.concurrentTo = response.id
+	records := []*record{response, request}
+	if len(c.Metadata) > 0 {
+		var block bytes.Buffer
+		for _, field := range c.Metadata {
+			fmt.Fprintf(&block, "%s: %s\r\n", field.Name, oneLine(field.Value))
+		}
+		metadata := newRecord("metadata", c.TargetURI, date, "application/warc-fields", block.Bytes())
+		metadata.concurrentTo = response.id
+		records = append(records, metadata)
+	}
+	if len(c.Screenshot) > 0 {
+		shot := newRecord("resource", "urn:screenshot:"+c.TargetURI, date, "image/png", c.Screenshot)
+		shot.concurrentTo = response.id
+		records = append(records, shot)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	path := w.path(c.RunID, c.Date)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size()
+	if offset == 0 {
+		fields := "software: CTI-Dashboard\r\nformat: WARC File Format 1.1\r\n" +
+			"conformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n"
+		warcinfo := newRecord("warcinfo", "", date, "application/warc-fields", []byte(fields))
+		warcinfo.filename = filepath.Base(path)
+		records = append([]*record{warcinfo}, records...)
+	}
+
+	var written []WARCRecord
+	for _, r := range records {
+		data, err := r.gzip()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write(data); err != nil {
+			return nil, err
+		}
+		if r.typ != "warcinfo" {
+			written = append(written, WARCRecord{
+				RecordID:      r.id,
+				Type:          r.typ,
+				TargetURI:     r.target,
+				Date:          date,
+				ContentType:   r.contentType,
+				PayloadDigest: r.payloadDigest(),
+				SHA256:        hexDigest(data),
+				File:          path,
+				Offset:        offset,
+				Length:        int64(len(data)),
+			})
+		}
+		offset += int64(len(data))
+	}
+	if err := file.Sync(); err != nil {
+		return nil, err
+	}
+	return written, nil
+}
+
+func (w *WARCWriter) path(runID string, date time.Time) string {
+	day := date.UTC().Format("20060102")
+	if w.rotation == RotateRun && runID != "" {
+		return filepath.Join(w.dir, "cti-"+day+"-"+runID+".warc.gz")
+	}
+	return filepath.Join(w.dir, "cti-"+day+"-p"+strconv.Itoa(os.Getpid())+".warc.gz")
+}
+
+// ReadWARCRecord reads the record starting at offset in a WARC file.
+func ReadWARCRecord(path string, offset int64) (*ArchivedRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	gz.Multistream(false)
+
+	reader := bufio.NewReader(gz)
+	version, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(version) != warcVersion {
+		return nil, fmt.Errorf("not a WARC 1.1 record: %q", strings.TrimSpace(version))
+	}
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad record length: %w", err)
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(reader, block); err != nil {
+		return nil, err
+	}
+	return &ArchivedRecord{Header: header, Block: block}, nil
+}
+
+// ReplayResponse parses the HTTP response stored in a response record.
+func ReplayResponse(path string, offset int64) (*http.Response, []byte, error) {
+	rec, err := ReadWARCRecord(path, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rec.Header.Get("WARC-Type") != "response" {
+		return nil, nil, errors.New("record is not a response")
+	}
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rec.Block)), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, body, nil
+}
+
+type record struct {
+	id           string
+	typ          string
+	target       string
+	date         string
+	contentType  string
+	concurrentTo string
+	filename     string
+	block        []byte
+	// payload is the entity body inside an HTTP block, digested separately.
+	payload []byte
+}
+
+func newRecord(typ string, target string, date string, contentType string, block []byte) *record {
+	return &record{
+		id:          "<urn:uuid:" + uuid.New().String() + ">",
+		typ:         typ,
+		target:      target,
+		date:        date,
+		contentType: contentType,
+		block:       block,
+	}
+}
+
+func (r *record) payloadDigest() string {
+	if r.payload == nil {
+		if r.typ != "resource" {
+			return ""
+		}
+		return digest(r.block)
+	}
+	return digest(r.payload)
+}
+
+func (r *record) gzip() ([]byte, error) {
+	var head bytes.Buffer
+	head.WriteString(warcVersion + "\r\n")
+	fmt.Fprintf(&head, "WARC-Type: %s\r\n", r.typ)
+	fmt.Fprintf(&head, "WARC-Record-ID: %s\r\n", r.id)
+	fmt.Fprintf(&head, "WARC-Date: %s\r\n", r.date)
+	if r.target != "" {
+		fmt.Fprintf(&head, "WARC-Target-URI: %s\r\n", r.target)
+	}
+	if r.filename != "" {
+		fmt.Fprintf(&head, "WARC-Filename: %s\r\n", r.filename)
+	}
+	if r.concurrentTo != "" {
+		fmt.Fprintf(&head, "WARC-Concurrent-To: %s\r\n", r.concurrentTo)
+	}
+	fmt.Fprintf(&head, "WARC-Block-Digest: %s\r\n", digest(r.block))
+	if payload := r.payloadDigest(); payload != "" {
+		fmt.Fprintf(&head, "WARC-Payload-Digest: %s\r\n", payload)
+	}
+	fmt.Fprintf(&head, "Content-Type: %s\r\n", r.contentType)
+	fmt.Fprintf(&head, "Content-Length: %d\r\n", len(r.block))
+	head.WriteString("\r\n")
+
+	var out bytes.Buffer
+	gz := gzip.NewWriter(&out)
+	for _, part := range [][]byte{head.Bytes(), r.block, []byte("\r\n\r\n")} {
+		if _, err := gz.Write(part); err != nil {
+			return nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// requestBlock is the HTTP request as sent. Cookie values are redacted so
+// session tokens do not end up in archives handed to third parties.
+func requestBlock(c Capture) []byte {
+	var block bytes.Buffer
+	req := c.Request
+	if req == nil {
+		req, _ = http.NewRequest(http.MethodGet, c.TargetURI, nil)
+	}
+	if req == nil {
+		fmt.Fprintf(&block, "GET %s HTTP/1.1\r\n\r\n", c.TargetURI)
+		return block.Bytes()
+	}
+	fmt.Fprintf(&block, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+	fmt.Fprintf(&block, "Host: %s\r\n", req.URL.Host)
+	header := req.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	if header.Get("Cookie") != "" {
+		header.Set("Cookie", "[redacted]")
+	}
+	header.Write(&block)
+	block.WriteString("\r\n")
+	return block.Bytes()
+}
+
+// responseBlock is the HTTP response with the body as it was read. The
+// client has already undone any transfer or content encoding, so those
+// headers are dropped and the length is that of the stored body.
+func responseBlock(c Capture) []byte {
+	var block bytes.Buffer
+	proto := c.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	status := c.StatusText
+	if status == "" {
+		status = "200 OK"
+	}
+	fmt.Fprintf(&block, "%s %s\r\n", proto, status)
+	header := c.Header.Clone()
+	if header == nil {
+		header = http.Header{"Content-Type": {"text/html; charset=utf-8"}}
+	}
+	header.Del("Transfer-Encoding")
+	header.Del("Content-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(c.Body)))
+	header.Write(&block)
+	block.WriteString("\r\n")
+	block.Write(c.Body)
+	return block.Bytes()
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+func hexDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func oneLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,90 @@
+package output
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWARCRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	warc, err := NewWARCWriter(dir, RotateDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ := http.NewRequest(http.MethodGet, "http://bazaar.onion/threads/1/?page=2", nil)
+	request.Header.Set("Cookie", "xf_session=secret")
+	date := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	body := []byte("<html><body>Fresh dumps</body></html>")
+
+	var offsets []int64
+	for i := 0; i < 2; i++ {
+		records, err := warc.WriteCapture(Capture{
+			RunID:      "run-" + strconv.Itoa(i),
+			TargetURI:  "http://bazaar.onion/threads/1/?page=2",
+			Date:       date,
+			Request:    request,
+			Proto:      "HTTP/1.1",
+			StatusText: "200 OK",
+			Header:     http.Header{"Content-Type": {"text/html"}, "Content-Encoding": {"gzip"}},
+			Body:       body,
+			Screenshot: []byte("\x89PNG"),
+			Metadata:   []Field{{Name: "run-id", Value: "run-" + strconv.Itoa(i)}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		types := ""
+		for _, r := range records {
+			types += r.Type + " "
+		}
+		if types != "response request metadata resource " {
+			t.Fatalf("records %q", types)
+		}
+		offsets = append(offsets, records[0].Offset)
+
+		// Each record is read back from its own offset.
+		for _, r := range records {
+			rec, err := ReadWARCRecord(r.File, r.Offset)
+			if err != nil {
+				t.Fatalf("%s at %d: %v", r.Type, r.Offset, err)
+			}
+			if rec.Header.Get("WARC-Type") != r.Type || rec.Header.Get("WARC-Record-ID") != r.RecordID {
+				t.Errorf("read %v, want %s %s", rec.Header, r.Type, r.RecordID)
+			}
+		}
+		request, err := ReadWARCRecord(records[1].File, records[1].Offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(request.Block); !strings.Contains(got, "Cookie: [redacted]") || strings.Contains(got, "secret") {
+			t.Errorf("request block %q does not redact the cookie", got)
+		}
+	}
+	// The second capture is appended after the first, warcinfo written once.
+	if offsets[0] == 0 || offsets[1] <= offsets[0] {
+		t.Errorf("offsets %v", offsets)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.warc.gz"))
+	if want := filepath.Join(dir, "cti-20250102-p"+strconv.Itoa(os.Getpid())+".warc.gz"); len(files) != 1 || files[0] != want {
+		t.Errorf("files %v, want %s", files, want)
+	}
+
+	response, replayed, err := ReplayResponse(files[0], offsets[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusOK || string(replayed) != string(body) {
+		t.Errorf("replayed %d %q", response.StatusCode, replayed)
+	}
+	if response.Header.Get("Content-Encoding") != "" || response.ContentLength != int64(len(body)) {
+		t.Errorf("replayed header %v", response.Header)
+	}
+	if _, _, err := ReplayResponse(files[0], 0); err == nil {
+		t.Error("replayed the warcinfo record as a response")
+	}
+}
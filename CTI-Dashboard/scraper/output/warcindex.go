@@ -0,0 +1,73 @@
+package output
+
+import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const warcColumns = `record_id, COALESCE(forum_id, ''), COALESCE(post_id, ''), COALESCE(run_id, ''), record_type, target_uri,
+	warc_date, COALESCE(content_type, ''), COALESCE(payload_digest, ''), warc_file, record_offset, record_length`
+
+// IndexWARC stores where the records of a capture were written, so a stored
+// response can be found and replayed without scanning the WARC files.
+func IndexWARC(db *sql.DB, forumID string, postID string, runID string, records []WARCRecord) error {
+	for _, r := range records {
+		_, err := db.Exec(`INSERT INTO warc_records (record_id, forum_id, post_id, run_id, record_type, target_uri,
+			warc_date, content_type, payload_digest, warc_file, record_offset, record_length)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			r.RecordID, stored.Nullable(forumID), stored.Nullable(postID), stored.Nullable(runID), r.Type, r.TargetURI,
+			r.Date, r.ContentType, stored.Nullable(r.PayloadDigest), r.File, r.Offset, r.Length,
+		)
+		if err != nil {
+			logger.Error("Could not index WARC record", "error", err, "record_id", r.RecordID)
+			return err
+		}
+	}
+	return nil
+}
+
+// WARCRecords returns the indexed records of a forum, its posts included,
+// newest first.
+func WARCRecords(db *sql.DB, forumID string, limit int) ([]models.WARCRecord, error) {
+	if limit <= 0 {
+		limit = 500
+	}
+	return queryWARC(db, `SELECT `+warcColumns+` FROM warc_records WHERE forum_id = ? ORDER BY warc_date DESC LIMIT ?`, forumID, limit)
+}
+
+func WARCRecordByID(db *sql.DB, recordID string) (models.WARCRecord, error) {
+	list, err := queryWARC(db, `SELECT `+warcColumns+` FROM warc_records WHERE record_id = ?`, recordID)
+	if err != nil {
+		return models.WARCRecord{}, err
+	}
+	if len(list) == 0 {
+		return models.WARCRecord{}, sql.ErrNoRows
+	}
+	return list[0], nil
+}
+
+func queryWARC(db *sql.DB, query string, args ...any) ([]models.WARCRecord, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		logger.Error("Could not query WARC records", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.WARCRecord
+	for rows.Next() {
+		var r models.WARCRecord
+		err := rows.Scan(&r.RecordID, &r.ForumID, &r.PostID, &r.RunID, &r.Type, &r.TargetURI,
+			&r.Date, &r.ContentType, &r.PayloadDigest, &r.File, &r.Offset, &r.Length)
+		if err != nil {
+			logger.Error("Could not scan WARC record row", "error", err)
+			continue
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
@@ -1,12 +1,24 @@
 package output
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"os"
 	"path/filepath"
 )
 
 type Writer struct {
 	outputDir string
+	warc      *WARCWriter
+}
+
+// Blob is a file stored under the SHA-256 of its content. Identical content
+// is stored once, and a later scrape never overwrites an earlier one.
+type Blob struct {
+	SHA256 string
+	Path   string
+	Size   int64
 }
 
 func NewWriter(outputDir string) (*Writer, error) {
@@ -19,20 +31,78 @@ func NewWriter(outputDir string) (*Writer, error) {
 	return &Writer{outputDir: outputDir}, nil
 }
 
-func (w *Writer) WriteResult(target string, body []byte, screenshot []byte) (paths []string, err error) {
-	safeTarget := filepath.Base(target)
+// WriteResult stores the page and its screenshot. The screenshot blob is
+// empty when there is no screenshot, as for posts.
+func (w *Writer) WriteResult(body []byte, screenshot []byte) (html Blob, shot Blob, err error) {
+	html, err = w.Store("html", ".html", body)
+	if err != nil {
+		return Blob{}, Blob{}, err
+	}
+	if screenshot == nil {
+		return html, Blob{}, nil
+	}
+	shot, err = w.Store("screenshots", ".png", screenshot)
+	if err != nil {
+		return Blob{}, Blob{}, err
+	}
+	return html, shot, nil
+}
 
-	htmlDir := filepath.Join(w.outputDir, "html")
-	htmlPath := filepath.Join(htmlDir, safeTarget+".html")
-	if err := os.WriteFile(htmlPath, body, 0644); err != nil {
-		return nil, err
+// WriteCaptures stores the MHTML and PDF captures of a page. A blob is empty
+// when its capture was not taken.
+func (w *Writer) WriteCaptures(mhtml []byte, pdf []byte) (mhtmlBlob Blob, pdfBlob Blob, err error) {
+	if mhtml != nil {
+		if mhtmlBlob, err = w.Store("mhtml", ".mhtml", mhtml); err != nil {
+			return Blob{}, Blob{}, err
+		}
 	}
-	screenshotDir := filepath.Join(w.outputDir, "screenshots")
-	screenshotPath := filepath.Join(screenshotDir, safeTarget+".png")
-	if err := os.WriteFile(screenshotPath, screenshot, 0644); err != nil {
-		return nil, err
+	if pdf != nil {
+		if pdfBlob, err = w.Store("pdf", ".pdf", pdf); err != nil {
+			return Blob{}, Blob{}, err
+		}
 	}
+	return mhtmlBlob, pdfBlob, nil
+}
 
-	paths = []string{htmlPath, screenshotPath}
-	return paths, nil
+// Store writes data to <dir>/<first two hex digits>/<sha256><ext>.
+func (w *Writer) Store(dir string, ext string, data []byte) (Blob, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	blob := Blob{
+		SHA256: hash,
+		Path:   filepath.Join(w.outputDir, dir, hash[:2], hash+ext),
+		Size:   int64(len(data)),
+	}
+	if _, err := os.Stat(blob.Path); err == nil {
+		return blob, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return Blob{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(blob.Path), 0755); err != nil {
+		return Blob{}, err
+	}
+	// Write to a temporary file first so a crash never leaves a truncated
+	// file under a hash it does not match. Each writer has its own, as
+	// scans running at once may store the same blob.
+	tmp, err := os.CreateTemp(filepath.Dir(blob.Path), hash+"-*.tmp")
+	if err != nil {
+		return Blob{}, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return Blob{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Blob{}, err
+	}
+	// CreateTemp makes the file private to the user; blobs are shared like
+	// the rest of the output.
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return Blob{}, err
+	}
+	if err := os.Rename(tmp.Name(), blob.Path); err != nil {
+		return Blob{}, err
+	}
+	return blob, nil
 }
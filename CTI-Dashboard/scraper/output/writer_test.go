@@ -0,0 +1,47 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestStoreConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("<html>same page</html>"), 4096)
+
+	var wg sync.WaitGroup
+	blobs := make([]Blob, 16)
+	errs := make([]error, len(blobs))
+	for i := range blobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			blobs[i], errs[i] = w.Store("html", ".html", data)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("writer %d: %v", i, err)
+		}
+		if blobs[i] != blobs[0] {
+			t.Errorf("writer %d stored %+v, want %+v", i, blobs[i], blobs[0])
+		}
+	}
+
+	stored, err := os.ReadFile(blobs[0].Path)
+	if err != nil || !bytes.Equal(stored, data) {
+		t.Errorf("stored %d bytes, want %d (%v)", len(stored), len(data), err)
+	}
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(blobs[0].Path), "*"))
+	if len(files) != 1 {
+		t.Errorf("files %v, want the blob alone", files)
+	}
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"CTI-Dashboard/scraper/logger"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// connectProxy serves CONNECT for the user analyst with password hunter2.
+func connectProxy(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := parseProxyAuth(r); !ok || user != "analyst" || pass != "hunter2" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		pipe(conn, upstream)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func parseProxyAuth(r *http.Request) (string, string, bool) {
+	request := &http.Request{Header: http.Header{"Authorization": r.Header["Proxy-Authorization"]}}
+	return request.BasicAuth()
+}
+
+func TestRelayThroughHTTPProxy(t *testing.T) {
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "forum index")
+	}))
+	defer site.Close()
+	upstream := connectProxy(t)
+
+	tests := []struct {
+		password string
+		ok       bool
+	}{
+		{"hunter2", true},
+		{"wrong", false},
+	}
+	for _, test := range tests {
+		r, err := startRelay(connectDialer{
+			address:  upstream.Listener.Addr().String(),
+			username: "analyst",
+			password: test.password,
+			timeout:  5 * time.Second,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The browser's view: an unauthenticated SOCKS5 proxy.
+		dialer, err := proxy.SOCKS5("tcp", r.Addr(), nil, proxy.Direct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &http.Client{Transport: &http.Transport{Dial: dialer.Dial}, Timeout: 5 * time.Second}
+		response, err := client.Get(site.URL)
+		if !test.ok {
+			if err == nil {
+				response.Body.Close()
+				t.Errorf("password %s: reached the site", test.password)
+			}
+			r.Close()
+			continue
+		}
+		if err != nil {
+			t.Fatalf("password %s: %v", test.password, err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		if string(body) != "forum index" {
+			t.Errorf("got %q", body)
+		}
+		r.Close()
+	}
+}
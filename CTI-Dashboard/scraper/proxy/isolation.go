@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"CTI-Dashboard/scraper/config"
+	"CTI-Dashboard/scraper/logger"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"golang.org/x/net/proxy"
+)
+
+// Identity is the client and browser proxy a forum uses on one endpoint of
+// the pool. On Tor endpoints it is isolated: Tor builds a separate circuit
+// for every distinct SOCKS username/password pair when IsolateSOCKSAuth is
+// set (the default), so requests and screenshots of one forum never share a
+// circuit with another forum.
+type Identity struct {
+	ForumID  string
+	Endpoint config.ProxyEndpoint
+	Username string
+	Password string
+	Client   *http.Client
+	// BrowserProxy is the proxy URL for chromedp, empty for direct
+	// connections. Chrome cannot be given proxy credentials, so
+	// authenticated endpoints point at a local relay that adds them.
+	BrowserProxy string
+
+	relay *relay
+}
+
+type Isolator struct {
+	cfg        config.Config
+	mu         sync.Mutex
+	identities map[string]*Identity
+}
+
+func NewIsolator(cfg config.Config) *Isolator {
+	return &Isolator{
+		cfg:        cfg,
+		identities: make(map[string]*Identity),
+	}
+}
+
+// Credentials derives the SOCKS username and password of a forum. They are
+// stable across restarts so a forum keeps its identity until Tor rotates it.
+func Credentials(forumID string) (string, string) {
+	userSum := sha256.Sum256([]byte("cti-dashboard/user/" + forumID))
+	passSum := sha256.Sum256([]byte("cti-dashboard/pass/" + forumID))
+	return "cti-" + hex.EncodeToString(userSum[:8]), hex.EncodeToString(passSum[:16])
+}
+
+// Identity returns the forum's identity on the endpoint, creating the client
+// and browser relay on first use.
+func (i *Isolator) Identity(forumID string, endpoint config.ProxyEndpoint) (*Identity, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	key := endpoint.Name + "/" + forumID
+	if identity, ok := i.identities[key]; ok {
+		return identity, nil
+	}
+
+	identity := &Identity{ForumID: forumID, Endpoint: endpoint}
+	switch endpoint.Type {
+	case TypeTor, TypeSOCKS5:
+		var auth *proxy.Auth
+		if endpoint.Type == TypeTor {
+			identity.Username, identity.Password = Credentials(forumID)
+		} else {
+			identity.Username, identity.Password = endpoint.Username, endpoint.Password
+		}
+		if identity.Username != "" {
+			auth = &proxy.Auth{User: identity.Username, Password: identity.Password}
+		}
+		dialer, err := proxy.SOCKS5("tcp", endpoint.Address, auth, proxy.Direct)
+		if err != nil {
+			return nil, err
+		}
+		identity.Client = &http.Client{
+			Transport: &http.Transport{Dial: dialer.Dial},
+			Timeout:   i.cfg.Timeout,
+		}
+		if auth == nil {
+			identity.BrowserProxy = "socks5://" + endpoint.Address
+			break
+		}
+		r, err := startRelay(dialer)
+		if err != nil {
+			return nil, err
+		}
+		identity.relay = r
+		identity.BrowserProxy = "socks5://" + r.Addr()
+	case TypeHTTP, TypeI2P:
+		// I2P endpoints are the router's HTTP proxy, usually on port 4444.
+		proxyURL := &url.URL{Scheme: "http", Host: endpoint.Address}
+		if endpoint.Username != "" {
+			proxyURL.User = url.UserPassword(endpoint.Username, endpoint.Password)
+		}
+		identity.Client = &http.Client{
+			Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+			Timeout:   i.cfg.Timeout,
+		}
+		if endpoint.Username == "" {
+			identity.BrowserProxy = "http://" + endpoint.Address
+			break
+		}
+		r, err := startRelay(connectDialer{
+			address:  endpoint.Address,
+			username: endpoint.Username,
+			password: endpoint.Password,
+			timeout:  i.cfg.Timeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		identity.relay = r
+		identity.BrowserProxy = "socks5://" + r.Addr()
+	case TypeDirect:
+		identity.Client = &http.Client{Timeout: i.cfg.Timeout}
+	default:
+		return nil, fmt.Errorf("unknown proxy type %q", endpoint.Type)
+	}
+
+	i.identities[key] = identity
+	logger.Info("Created proxy identity", "forum_id", forumID, "proxy", endpoint.Name, "browser_proxy", identity.BrowserProxy)
+	return identity, nil
+}
+
+// Close stops every browser relay.
+func (i *Isolator) Close() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for key, identity := range i.identities {
+		if identity.relay != nil {
+			identity.relay.Close()
+		}
+		delete(i.identities, key)
+	}
+}
+
+// relay is a minimal unauthenticated SOCKS5 server on localhost that forwards
+// CONNECT requests through an authenticated upstream dialer.
+type relay struct {
+	listener net.Listener
+	dialer   proxy.Dialer
+}
+
+func startRelay(dialer proxy.Dialer) (*relay, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	r := &relay{listener: listener, dialer: dialer}
+	go r.serve()
+	return r, nil
+}
+
+func (r *relay) Addr() string {
+	return r.listener.Addr().String()
+}
+
+func (r *relay) Close() error {
+	return r.listener.Close()
+}
+
+func (r *relay) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		go r.handle(conn)
+	}
+}
+
+func (r *relay) handle(conn net.Conn) {
+	defer conn.Close()
+
+	target, err := readConnectRequest(conn)
+	if err != nil {
+		logger.Error("Invalid SOCKS request on relay", "error", err)
+		return
+	}
+	upstream, err := r.dialer.Dial("tcp", target)
+	if err != nil {
+		logger.Error("Relay could not reach target", "target", target, "error", err)
+		conn.Write([]byte{socksVersion, replyFailure, 0, atypIPv4, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	if _, err := conn.Write([]byte{socksVersion, replySuccess, 0, atypIPv4, 0, 0, 0, 0, 0, 0}); err != nil {
+		return
+	}
+	pipe(conn, upstream)
+}
@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/config"
+	"CTI-Dashboard/scraper/logger"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	TypeTor    = "tor"
+	TypeI2P    = "i2p"
+	TypeSOCKS5 = "socks5"
+	TypeHTTP   = "http"
+	TypeDirect = "direct"
+)
+
+func ValidType(proxyType string) bool {
+	switch proxyType {
+	case TypeTor, TypeI2P, TypeSOCKS5, TypeHTTP, TypeDirect:
+		return true
+	}
+	return false
+}
+
+const (
+	StrategyRoundRobin  = "round-robin"
+	StrategyLeastLoaded = "least-loaded"
+	StrategySticky      = "sticky"
+)
+
+const (
+	// An endpoint is marked unhealthy after this many failures in a row and
+	// is skipped until the cooldown has passed.
+	maxFailures = 3
+	cooldown    = 2 * time.Minute
+)
+
+type member struct {
+	endpoint       config.ProxyEndpoint
+	inFlight       int
+	failures       int
+	unhealthyUntil time.Time
+}
+
+func (m *member) healthy(now time.Time) bool {
+	return now.After(m.unhealthyUntil)
+}
+
+// Pool hands out proxy endpoints of a requested type according to the
+// configured strategy and fails over away from unhealthy endpoints.
+type Pool struct {
+	strategy string
+	mu       sync.Mutex
+	members  []*member
+	next     map[string]int
+	sticky   map[string]*member
+}
+
+// Lease is an endpoint handed out by the pool. Release must be called once
+// the request is done.
+type Lease struct {
+	Endpoint config.ProxyEndpoint
+	pool     *Pool
+	member   *member
+	once     sync.Once
+}
+
+// NewPool builds the pool from cfg.Proxies, or from cfg.TorProxy alone when
+// no endpoints are configured. A direct endpoint is always available.
+func NewPool(cfg config.Config) (*Pool, error) {
+	strategy := cfg.ProxyStrategy
+	switch strategy {
+	case "":
+		strategy = StrategyRoundRobin
+	case StrategyRoundRobin, StrategyLeastLoaded, StrategySticky:
+	default:
+		return nil, fmt.Errorf("unknown proxy strategy %q", strategy)
+	}
+
+	endpoints := cfg.Proxies
+	if len(endpoints) == 0 {
+		torProxy := cfg.TorProxy
+		if torProxy == "" {
+			torProxy = "127.0.0.1:9050"
+		}
+		endpoints = []config.ProxyEndpoint{{Type: TypeTor, Address: torProxy}}
+	}
+
+	pool := &Pool{
+		strategy: strategy,
+		next:     make(map[string]int),
+		sticky:   make(map[string]*member),
+	}
+	hasDirect := false
+	for _, endpoint := range endpoints {
+		switch endpoint.Type {
+		case TypeTor, TypeI2P, TypeSOCKS5, TypeHTTP:
+			if endpoint.Address == "" {
+				return nil, fmt.Errorf("proxy endpoint %q has no address", endpoint.Name)
+			}
+		case TypeDirect:
+			hasDirect = true
+		default:
+			return nil, fmt.Errorf("unknown proxy type %q", endpoint.Type)
+		}
+		if endpoint.Name == "" {
+			endpoint.Name = endpoint.Type + "@" + endpoint.Address
+		}
+		pool.members = append(pool.members, &member{endpoint: endpoint})
+	}
+	if !hasDirect {
+		pool.members = append(pool.members, &member{endpoint: config.ProxyEndpoint{Name: TypeDirect, Type: TypeDirect}})
+	}
+	return pool, nil
+}
+
+// Acquire returns a healthy endpoint of the given type for the forum. The
+// endpoints named in exclude are skipped, so a scan can fail over from the
+// ones it has tried.
+func (p *Pool) Acquire(forumID string, proxyType string, exclude ...string) (*Lease, error) {
+	if proxyType == "" {
+		proxyType = TypeTor
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var candidates []*member
+	for _, m := range p.members {
+		if m.endpoint.Type == proxyType && m.healthy(now) && !slices.Contains(exclude, m.endpoint.Name) {
+			candidates = append(candidates, m)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no healthy %s proxy available", proxyType)
+	}
+
+	var chosen *member
+	switch p.strategy {
+	case StrategySticky:
+		if m, ok := p.sticky[forumID]; ok && slices.Contains(candidates, m) {
+			chosen = m
+		} else {
+			chosen = p.roundRobin(proxyType, candidates)
+			p.sticky[forumID] = chosen
+		}
+	case StrategyLeastLoaded:
+		chosen = candidates[0]
+		for _, m := range candidates[1:] {
+			if m.inFlight < chosen.inFlight || (m.inFlight == chosen.inFlight && m.failures < chosen.failures) {
+				chosen = m
+			}
+		}
+	default:
+		chosen = p.roundRobin(proxyType, candidates)
+	}
+
+	chosen.inFlight++
+	return &Lease{Endpoint: chosen.endpoint, pool: p, member: chosen}, nil
+}
+
+func (p *Pool) roundRobin(proxyType string, candidates []*member) *member {
+	i := p.next[proxyType] % len(candidates)
+	p.next[proxyType] = i + 1
+	return candidates[i]
+}
+
+// Release returns the endpoint to the pool. proxyFault reports whether the
+// request failed because of the proxy itself rather than the target site.
+func (l *Lease) Release(proxyFault bool) {
+	l.once.Do(func() {
+		l.pool.mu.Lock()
+		defer l.pool.mu.Unlock()
+
+		m := l.member
+		m.inFlight--
+		if !proxyFault {
+			m.failures = 0
+			return
+		}
+		m.failures++
+		if m.failures >= maxFailures {
+			m.unhealthyUntil = time.Now().Add(cooldown)
+			logger.Error("Proxy marked unhealthy", "proxy", m.endpoint.Name, "failures", m.failures)
+		}
+	})
+}
+
+// Status is the pool's view of every endpoint.
+func (p *Pool) Status() []models.ProxyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var statuses []models.ProxyStatus
+	for _, m := range p.members {
+		statuses = append(statuses, models.ProxyStatus{
+			Name:     m.endpoint.Name,
+			Type:     m.endpoint.Type,
+			Address:  m.endpoint.Address,
+			InFlight: m.inFlight,
+			Failures: m.failures,
+			Healthy:  m.healthy(now),
+		})
+	}
+	return statuses
+}
@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"CTI-Dashboard/scraper/config"
+	"CTI-Dashboard/scraper/logger"
+	"testing"
+	"time"
+)
+
+func testPool(t *testing.T, strategy string) *Pool {
+	t.Helper()
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	pool, err := NewPool(config.Config{
+		ProxyStrategy: strategy,
+		Proxies: []config.ProxyEndpoint{
+			{Name: "a", Type: TypeTor, Address: "127.0.0.1:9050"},
+			{Name: "b", Type: TypeTor, Address: "127.0.0.1:9052"},
+			{Name: "c", Type: TypeTor, Address: "127.0.0.1:9054"},
+			{Name: "i2p", Type: TypeI2P, Address: "127.0.0.1:4444"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+// acquire returns the name of the endpoint leased and the lease.
+func acquire(t *testing.T, pool *Pool, forumID string, exclude ...string) (string, *Lease) {
+	t.Helper()
+	lease, err := pool.Acquire(forumID, TypeTor, exclude...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lease.Endpoint.Name, lease
+}
+
+func TestNewPool(t *testing.T) {
+	pool, err := NewPool(config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, status := range pool.Status() {
+		names = append(names, status.Name)
+	}
+	if len(names) != 2 || names[0] != "tor@127.0.0.1:9050" || names[1] != TypeDirect {
+		t.Errorf("default endpoints %v, want the Tor proxy and direct", names)
+	}
+	if _, err := NewPool(config.Config{ProxyStrategy: "random"}); err == nil {
+		t.Error("unknown strategy accepted")
+	}
+	if _, err := NewPool(config.Config{Proxies: []config.ProxyEndpoint{{Type: TypeSOCKS5}}}); err == nil {
+		t.Error("endpoint without an address accepted")
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	pool := testPool(t, StrategyRoundRobin)
+	var got string
+	for i := 0; i < 4; i++ {
+		name, lease := acquire(t, pool, "f1")
+		lease.Release(false)
+		got += name
+	}
+	if got != "abca" {
+		t.Errorf("leased %s, want abca", got)
+	}
+	if lease, err := pool.Acquire("f1", TypeI2P); err != nil || lease.Endpoint.Name != "i2p" {
+		t.Errorf("i2p lease: %+v, %v", lease, err)
+	}
+	if _, err := pool.Acquire("f1", TypeSOCKS5); err == nil {
+		t.Error("leased a type the pool does not have")
+	}
+}
+
+func TestLeastLoaded(t *testing.T) {
+	pool := testPool(t, StrategyLeastLoaded)
+	first, held := acquire(t, pool, "f1")
+	second, _ := acquire(t, pool, "f2")
+	third, _ := acquire(t, pool, "f3")
+	if first != "a" || second != "b" || third != "c" {
+		t.Errorf("leased %s %s %s, want each endpoint once", first, second, third)
+	}
+	held.Release(false)
+	if name, _ := acquire(t, pool, "f4"); name != "a" {
+		t.Errorf("leased %s, want a, the only idle endpoint", name)
+	}
+}
+
+func TestSticky(t *testing.T) {
+	pool := testPool(t, StrategySticky)
+	f1, lease := acquire(t, pool, "f1")
+	lease.Release(false)
+	f2, lease := acquire(t, pool, "f2")
+	lease.Release(false)
+	if f1 == f2 {
+		t.Errorf("f1 and f2 both on %s", f1)
+	}
+	for i := 0; i < 3; i++ {
+		if name, lease := acquire(t, pool, "f1"); name != f1 {
+			t.Errorf("f1 moved from %s to %s", f1, name)
+		} else {
+			lease.Release(false)
+		}
+	}
+	// Failing over moves the forum for good.
+	moved, lease := acquire(t, pool, "f1", f1)
+	lease.Release(false)
+	if moved == f1 {
+		t.Fatalf("excluded %s was leased", f1)
+	}
+	if name, _ := acquire(t, pool, "f1"); name != moved {
+		t.Errorf("f1 on %s after failing over to %s", name, moved)
+	}
+}
+
+func TestUnhealthy(t *testing.T) {
+	pool := testPool(t, StrategyRoundRobin)
+	fail := func(want string) {
+		t.Helper()
+		for {
+			name, lease := acquire(t, pool, "f1")
+			if name == want {
+				lease.Release(true)
+				return
+			}
+			lease.Release(false)
+		}
+	}
+	for i := 0; i < maxFailures-1; i++ {
+		fail("a")
+	}
+	// A success in between resets the count.
+	for {
+		name, lease := acquire(t, pool, "f1")
+		lease.Release(false)
+		if name == "a" {
+			break
+		}
+	}
+	for i := 0; i < maxFailures; i++ {
+		fail("a")
+	}
+	for i := 0; i < 6; i++ {
+		if name, lease := acquire(t, pool, "f1"); name == "a" {
+			t.Fatal("leased the unhealthy endpoint")
+		} else {
+			lease.Release(false)
+		}
+	}
+	for _, status := range pool.Status() {
+		if status.Name == "a" && (status.Healthy || status.Failures != maxFailures || status.InFlight != 0) {
+			t.Errorf("status %+v, want unhealthy with nothing in flight", status)
+		}
+	}
+
+	// Past the cooldown it is tried again.
+	pool.members[0].unhealthyUntil = time.Now().Add(-time.Second)
+	seen := false
+	for i := 0; i < 3; i++ {
+		name, lease := acquire(t, pool, "f1")
+		lease.Release(false)
+		seen = seen || name == "a"
+	}
+	if !seen {
+		t.Error("endpoint not used again after the cooldown")
+	}
+
+	// With every endpoint of the type excluded or unhealthy nothing is leased.
+	if _, err := pool.Acquire("f1", TypeTor, "a", "b", "c"); err == nil {
+		t.Error("leased an excluded endpoint")
+	}
+}
+
+func TestReleaseOnce(t *testing.T) {
+	pool := testPool(t, StrategyRoundRobin)
+	_, lease := acquire(t, pool, "f1")
+	lease.Release(true)
+	lease.Release(true)
+	if status := pool.Status()[0]; status.InFlight != 0 || status.Failures != 1 {
+		t.Errorf("status %+v after releasing twice, want one release counted", status)
+	}
+}
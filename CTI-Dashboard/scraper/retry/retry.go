@@ -0,0 +1,187 @@
+package retry
+
+import (
+	"CTI-Dashboard/scraper/classifier"
+	"CTI-Dashboard/scraper/history"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultAttempts      = 3
+	DefaultBaseDelay     = 2 * time.Second
+	DefaultMaxDelay      = time.Minute
+	DefaultMaxRetryAfter = 5 * time.Minute
+)
+
+// Policy decides whether a failed attempt is tried again and after how long.
+type Policy struct {
+	MaxAttempts int
+	// Backoff doubles from BaseDelay up to MaxDelay. Half of each delay is
+	// random so forums retried together spread out.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// A Retry-After longer than MaxRetryAfter is not waited for.
+	MaxRetryAfter time.Duration
+}
+
+func New(maxAttempts int) Policy {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultAttempts
+	}
+	return Policy{
+		MaxAttempts:   maxAttempts,
+		BaseDelay:     DefaultBaseDelay,
+		MaxDelay:      DefaultMaxDelay,
+		MaxRetryAfter: DefaultMaxRetryAfter,
+	}
+}
+
+// Decision is the policy's verdict on one failed attempt.
+type Decision struct {
+	Retry bool
+	Delay time.Duration
+	// Renew reports whether a new Tor circuit is likely to help.
+	Renew  bool
+	Reason string
+}
+
+// StatusError is a response with a status other than 200.
+type StatusError struct {
+	Status int
+	Text   string
+	Header http.Header
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status: %s", e.Text)
+}
+
+// Status returns the error for an unexpected status, classed as http_status.
+func Status(status int, text string, header http.Header) error {
+	return history.WithClass(history.ClassHTTPStatus, &StatusError{Status: status, Text: text, Header: header})
+}
+
+type againError struct{ err error }
+
+func (e *againError) Error() string { return e.err.Error() }
+func (e *againError) Unwrap() error { return e.err }
+
+// Again marks err as fixed already, e.g. by logging in again, so the next
+// attempt starts right away.
+func Again(err error) error {
+	return &againError{err: err}
+}
+
+// Statuses worth another attempt. Onion services answer 502/504 from the
+// Tor side when the circuit to the service breaks.
+var retryableStatus = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooEarly:            true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// Error classes worth another attempt, and those where a new circuit helps.
+var (
+	retryableClass = map[string]bool{
+		history.ClassTimeout:    true,
+		history.ClassRefused:    true,
+		history.ClassProxy:      true,
+		history.ClassNetwork:    true,
+		history.ClassReadBody:   true,
+		history.ClassScreenshot: true,
+		history.ClassUnknown:    true,
+	}
+	renewClass = map[string]bool{
+		history.ClassTimeout:    true,
+		history.ClassRefused:    true,
+		history.ClassProxy:      true,
+		history.ClassNetwork:    true,
+		classifier.ClassBlocked: true,
+	}
+)
+
+// Decide looks at the error of attempt (counted from 1).
+func (p Policy) Decide(attempt int, err error) Decision {
+	d := p.decide(attempt, err)
+	if d.Retry && attempt >= p.MaxAttempts {
+		return Decision{Reason: fmt.Sprintf("giving up after %d attempts: %s", attempt, d.Reason)}
+	}
+	return d
+}
+
+func (p Policy) decide(attempt int, err error) Decision {
+	var again *againError
+	if errors.As(err, &again) {
+		return Decision{Retry: true, Reason: "retrying immediately"}
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if !retryableStatus[statusErr.Status] {
+			return Decision{Reason: fmt.Sprintf("status %d is terminal", statusErr.Status)}
+		}
+		renew := statusErr.Status == http.StatusTooManyRequests || statusErr.Status == http.StatusServiceUnavailable
+		if wait, ok := RetryAfter(statusErr.Header, time.Now()); ok {
+			if wait > p.MaxRetryAfter {
+				return Decision{Reason: fmt.Sprintf("Retry-After of %s is too long", wait)}
+			}
+			return Decision{Retry: true, Delay: wait, Renew: renew, Reason: "honoring Retry-After"}
+		}
+		return Decision{Retry: true, Delay: p.Backoff(attempt), Renew: renew, Reason: fmt.Sprintf("status %d", statusErr.Status)}
+	}
+
+	class := history.Classify(err)
+	if retryableClass[class] || classifier.Transient(class) {
+		return Decision{Retry: true, Delay: p.Backoff(attempt), Renew: renewClass[class], Reason: class}
+	}
+	return Decision{Reason: class + " is terminal"}
+}
+
+// Backoff is the delay before the attempt after attempt: exponential with
+// equal jitter.
+func (p Policy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// RetryAfter reads the Retry-After header, given in seconds or as a date.
+func RetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	when, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := when.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
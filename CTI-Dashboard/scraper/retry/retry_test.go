@@ -0,0 +1,105 @@
+package retry
+
+import (
+	"CTI-Dashboard/scraper/classifier"
+	"CTI-Dashboard/scraper/history"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"-5", 0, false},
+		{"Thu, 02 Jan 2025 10:00:30 GMT", 30 * time.Second, true},
+		{"Thu, 02 Jan 2025 09:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, test := range tests {
+		header := http.Header{}
+		if test.value != "" {
+			header.Set("Retry-After", test.value)
+		}
+		got, ok := RetryAfter(header, now)
+		if got != test.want || ok != test.ok {
+			t.Errorf("RetryAfter(%q) = %s, %v, want %s, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestDecide(t *testing.T) {
+	policy := New(3)
+	retryAfter := func(value string) http.Header {
+		return http.Header{"Retry-After": []string{value}}
+	}
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		retry   bool
+		renew   bool
+		delay   time.Duration // exact when set, else at most the first backoff
+	}{
+		{"not found", 1, Status(http.StatusNotFound, "404 Not Found", nil), false, false, 0},
+		{"forbidden", 1, Status(http.StatusForbidden, "403 Forbidden", nil), false, false, 0},
+		{"bad gateway", 1, Status(http.StatusBadGateway, "502 Bad Gateway", nil), true, false, 0},
+		{"rate limited", 1, Status(http.StatusTooManyRequests, "429 Too Many Requests", retryAfter("7")), true, true, 7 * time.Second},
+		{"Retry-After too long", 1, Status(http.StatusServiceUnavailable, "503 Service Unavailable", retryAfter("3600")), false, false, 0},
+		{"timeout", 1, history.WithClass(history.ClassTimeout, errors.New("i/o timeout")), true, true, 0},
+		{"blocked page", 1, history.WithClass(classifier.ClassBlocked, errors.New("queue")), true, true, 0},
+		{"challenge page", 1, history.WithClass(classifier.ClassChallenge, errors.New("challenge")), true, false, 0},
+		{"seized page", 1, history.WithClass(classifier.ClassSeized, errors.New("seized")), false, false, 0},
+		{"login failed", 1, history.WithClass(history.ClassAuth, errors.New("bad password")), false, false, 0},
+		{"logged in again", 1, Again(errors.New("session expired")), true, false, 0},
+		{"last attempt", 3, Status(http.StatusBadGateway, "502 Bad Gateway", nil), false, false, 0},
+	}
+	for _, test := range tests {
+		d := policy.Decide(test.attempt, test.err)
+		if d.Retry != test.retry || d.Renew != test.renew || d.Reason == "" {
+			t.Errorf("%s: got %+v, want retry %v, renew %v", test.name, d, test.retry, test.renew)
+			continue
+		}
+		if !d.Retry {
+			continue
+		}
+		if test.delay != 0 && d.Delay != test.delay || test.delay == 0 && d.Delay > policy.BaseDelay {
+			t.Errorf("%s: delay %s, want %s", test.name, d.Delay, test.delay)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		attempt int
+		full    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{40, 10 * time.Second},
+	}
+	for _, test := range tests {
+		for i := 0; i < 50; i++ {
+			// Equal jitter: between half the delay and all of it.
+			if got := policy.Backoff(test.attempt); got < test.full/2 || got > test.full {
+				t.Errorf("Backoff(%d) = %s, want %s to %s", test.attempt, got, test.full/2, test.full)
+				break
+			}
+		}
+	}
+	if got := (Policy{}).Backoff(3); got != 0 {
+		t.Errorf("Backoff without delays = %s", got)
+	}
+}
@@ -0,0 +1,86 @@
+package scanner
+
+import (
+	"CTI-Dashboard/scraper/custody"
+	"CTI-Dashboard/scraper/history"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/output"
+	"strconv"
+	"strings"
+)
+
+// archive writes a fetched page to the WARC files, junk pages and failed
+// attempts included: a seizure banner or a block page is evidence too.
+func (s *Scanner) archive(opts Options, run *history.Run, page *page, attempt int, err error) {
+	warc := opts.Writer.WARC()
+	if warc == nil {
+		return
+	}
+	target := run.Target
+	if page.url != "" {
+		target = page.url
+	}
+	fetchMode := opts.FetchMode
+	if fetchMode == "" {
+		fetchMode = FetchHTTP
+	}
+	metadata := []output.Field{
+		{Name: "run-id", Value: run.RunID},
+		{Name: "forum-id", Value: opts.ForumID},
+		{Name: "requested-uri", Value: run.Target},
+		{Name: "attempt", Value: strconv.Itoa(attempt)},
+		{Name: "fetch-mode", Value: fetchMode},
+		{Name: "exit-ip", Value: run.ExitIP},
+	}
+	if len(page.redirects) > 0 {
+		metadata = append(metadata, output.Field{Name: "redirects", Value: strings.Join(page.redirects, "; ")})
+	}
+	if run.PageClass != "" {
+		metadata = append(metadata, output.Field{Name: "page-class", Value: run.PageClass})
+	}
+	if err != nil {
+		metadata = append(metadata, output.Field{Name: "error", Value: err.Error()})
+	}
+
+	records, werr := warc.WriteCapture(output.Capture{
+		RunID:      run.RunID,
+		TargetURI:  target,
+		Date:       page.fetchedAt,
+		Request:    page.request,
+		Proto:      page.proto,
+		StatusText: page.statusText,
+		Header:     page.header,
+		Body:       page.body,
+		Screenshot: page.screenshot,
+		Metadata:   metadata,
+	})
+	if werr != nil {
+		logger.Error("Could not write WARC records", "error", werr, "target", target)
+		return
+	}
+	for _, record := range records {
+		s.addArtifact(opts, run, custody.Artifact{
+			Kind:       custody.KindWARC,
+			Path:       record.File,
+			SHA256:     record.SHA256,
+			Size:       record.Length,
+			Offset:     record.Offset,
+			RecordID:   record.RecordID,
+			URL:        record.TargetURI,
+			CapturedAt: custody.Timestamp(page.fetchedAt),
+		})
+	}
+	if opts.DB == nil {
+		return
+	}
+	var postID string
+	if run.Kind == history.KindPost {
+		post, _ := lookupPost(opts, run.Target)
+		postID = post.PostID
+	}
+	// The records are in the archive and the manifest either way; without
+	// the index they are only missing from the WARC view and replay.
+	if err := output.IndexWARC(opts.DB, opts.ForumID, postID, run.RunID, records); err != nil {
+		logger.Error("Could not index the WARC records of a capture", "error", err, "target", target, "run_id", run.RunID)
+	}
+}
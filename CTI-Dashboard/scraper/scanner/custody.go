@@ -0,0 +1,56 @@
+package scanner
+
+import (
+	"CTI-Dashboard/scraper/custody"
+	"CTI-Dashboard/scraper/history"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/output"
+)
+
+// collect adds a blob written for the run to its manifest, captured when the
+// page was fetched or, for screenshots and the other captures, rendered.
+func (s *Scanner) collect(opts Options, run *history.Run, kind string, page *page, blob output.Blob) {
+	if blob.Path == "" {
+		return
+	}
+	url := run.Target
+	if page.url != "" {
+		url = page.url
+	}
+	captured := page.renderedAt
+	if kind == custody.KindHTML {
+		captured = page.fetchedAt
+	}
+	s.addArtifact(opts, run, custody.Artifact{
+		Kind:       kind,
+		Path:       blob.Path,
+		SHA256:     blob.SHA256,
+		Size:       blob.Size,
+		URL:        url,
+		CapturedAt: custody.Timestamp(captured),
+	})
+}
+
+func (s *Scanner) addArtifact(opts Options, run *history.Run, artifact custody.Artifact) {
+	if opts.Custody == nil || s.manifest == nil {
+		return
+	}
+	artifact.Path = opts.Custody.Relative(artifact.Path)
+	artifact.ExitIP = run.ExitIP
+	s.manifest.Add(artifact)
+}
+
+// seal signs and writes the manifest of the run that just finished.
+func (s *Scanner) seal(opts Options) {
+	manifest := s.manifest
+	s.manifest = nil
+	if opts.Custody == nil || manifest == nil || len(manifest.Artifacts) == 0 {
+		return
+	}
+	path, err := opts.Custody.Seal(manifest)
+	if err != nil {
+		logger.Error("Could not write chain-of-custody manifest", "error", err, "run_id", manifest.RunID)
+		return
+	}
+	logger.Info("Signed chain-of-custody manifest", "run_id", manifest.RunID, "path", path, "artifacts", len(manifest.Artifacts))
+}
@@ -0,0 +1,245 @@
+package scanner
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/captcha"
+	"CTI-Dashboard/scraper/headless"
+	"CTI-Dashboard/scraper/history"
+	"CTI-Dashboard/scraper/logger"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// FetchHTTP gets pages with the plain HTTP client.
+	FetchHTTP = "http"
+	// FetchBrowser renders pages in the headless browser, for sites that
+	// build their thread lists with JavaScript or sit behind a challenge page.
+	FetchBrowser = "browser"
+)
+
+// Capture formats saved next to the screenshot of a forum page.
+const (
+	// CaptureMHTML is a single-file archive of the page, links and all.
+	CaptureMHTML = "mhtml"
+	// CapturePDF is a print of the page with searchable text.
+	CapturePDF = "pdf"
+)
+
+// maxCaptchaAnswers bounds how often the analyst is asked about one page.
+const maxCaptchaAnswers = 3
+
+// page is the outcome of fetching a target once.
+type page struct {
+	status     int
+	statusText string
+	proto      string
+	header     http.Header
+	// request is the request the HTTP client sent for the final hop, nil
+	// for pages rendered in the browser.
+	request *http.Request
+	// url is where the page was finally served from, redirects the hops
+	// that led there.
+	url        string
+	redirects  []string
+	body       []byte
+	screenshot []byte
+	mhtml      []byte
+	pdf        []byte
+	// fetchedAt is when the body was received, renderedAt when the
+	// screenshot and other captures were taken.
+	fetchedAt  time.Time
+	renderedAt time.Time
+}
+
+// fetch gets the target with the forum's fetch mode. In browser mode the body
+// is the rendered DOM and, when asked for, the screenshot comes from the same
+// page load. A CAPTCHA met over HTTP is solved in the browser and the
+// resulting cookies are handed back to the HTTP client.
+func (s *Scanner) fetch(target string, opts Options, screenshot bool) (*page, error) {
+	if opts.FetchMode == FetchBrowser {
+		return s.fetchBrowser(target, opts, screenshot)
+	}
+
+	response, err := opts.Client.Get(target)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	p := &page{
+		status:     response.StatusCode,
+		statusText: response.Status,
+		proto:      response.Proto,
+		header:     response.Header,
+		request:    response.Request,
+		url:        response.Request.URL.String(),
+		redirects:  redirects(response),
+		fetchedAt:  time.Now(),
+	}
+	body, err := io.ReadAll(response.Body)
+	p.body = body
+	if err != nil {
+		return p, history.WithClass(history.ClassReadBody, err)
+	}
+
+	if opts.Captcha != nil && captcha.Detect(string(body)) != nil {
+		logger.Info("CAPTCHA page, opening it in the browser", "target", target)
+		p, err = s.fetchBrowser(target, opts, screenshot)
+		if err == nil && opts.Client.Jar != nil && opts.Cookies != nil {
+			for u, cookies := range opts.Cookies.Export(opts.ForumID) {
+				opts.Client.Jar.SetCookies(u, cookies)
+			}
+		}
+		return p, err
+	}
+	return p, nil
+}
+
+func (s *Scanner) fetchBrowser(target string, opts Options, screenshot bool) (*page, error) {
+	rendered, err := s.render(target, opts, captures(opts, headless.RenderOptions{HTML: true, Screenshot: screenshot}))
+	if err != nil {
+		return nil, err
+	}
+	status := rendered.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	p := &page{
+		status:     status,
+		statusText: fmt.Sprintf("%d %s", status, rendered.StatusText),
+		url:        rendered.URL,
+		body:       []byte(rendered.HTML),
+		screenshot: rendered.Screenshot,
+		mhtml:      rendered.MHTML,
+		pdf:        rendered.PDF,
+		fetchedAt:  time.Now(),
+	}
+	p.renderedAt = p.fetchedAt
+	if rendered.URL != "" && rendered.URL != target {
+		p.redirects = []string{target + " -> " + rendered.URL}
+	}
+	return p, nil
+}
+
+// captures adds the forum's extra capture formats to a render that takes a
+// screenshot.
+func captures(opts Options, renderOpts headless.RenderOptions) headless.RenderOptions {
+	if !renderOpts.Screenshot {
+		return renderOpts
+	}
+	for _, format := range opts.CaptureFormats {
+		switch format {
+		case CaptureMHTML:
+			renderOpts.MHTML = true
+		case CapturePDF:
+			renderOpts.PDF = true
+		}
+	}
+	return renderOpts
+}
+
+// redirects lists the hops that led to the response, oldest first.
+func redirects(response *http.Response) []string {
+	var hops []string
+	for req := response.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		hop := fmt.Sprintf("%d %s -> %s", req.Response.StatusCode, req.Response.Request.URL, req.URL)
+		hops = append([]string{hop}, hops...)
+	}
+	return hops
+}
+
+// render loads the target in a tab of the browser pool using the forum's
+// proxy, readiness strategy and cookies.
+func (s *Scanner) render(target string, opts Options, renderOpts headless.RenderOptions) (*headless.Page, error) {
+	pool := opts.Browser
+	if pool == nil {
+		pool = headless.NewPool(headless.Config{MaxTabs: 1})
+		defer pool.Close()
+	}
+
+	// The tab may outlive a single page load while the analyst answers a
+	// CAPTCHA, so only the browser actions are put under a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tab, release, err := pool.Tab(ctx, opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	renderOpts.Readiness = opts.Readiness
+	renderOpts.Cookies = opts.Cookies
+	renderOpts.CookieKey = opts.ForumID
+	rendered, err := s.renderOnce(tab, target, opts, renderOpts)
+	if err != nil || opts.Captcha == nil || !renderOpts.HTML {
+		return rendered, err
+	}
+
+	for answers := 0; ; answers++ {
+		found := captcha.Detect(rendered.HTML)
+		if found == nil {
+			return rendered, nil
+		}
+		if answers == maxCaptchaAnswers {
+			return nil, history.WithClass(history.ClassCaptcha, fmt.Errorf("CAPTCHA still shown after %d answers", answers))
+		}
+		if err := s.solveCaptcha(tab, opts, rendered.URL, found, answers+1); err != nil {
+			return nil, err
+		}
+		rendered, err = s.renderOnce(tab, target, opts, renderOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *Scanner) renderOnce(tab context.Context, target string, opts Options, renderOpts headless.RenderOptions) (*headless.Page, error) {
+	ctx, cancel := context.WithTimeout(tab, opts.Timeout+opts.Readiness.Budget())
+	defer cancel()
+	return headless.Render(ctx, target, renderOpts)
+}
+
+// solveCaptcha shows the CAPTCHA to the analyst, waits for the answer and
+// submits it in the same tab, so the cookies it earns stay with the forum.
+func (s *Scanner) solveCaptcha(tab context.Context, opts Options, pageURL string, found *captcha.Found, attempt int) error {
+	shotCtx, cancel := context.WithTimeout(tab, opts.Timeout)
+	screenshot, err := headless.Screenshot(shotCtx)
+	cancel()
+	if err != nil {
+		return history.WithClass(history.ClassScreenshot, err)
+	}
+
+	answer, err := opts.Captcha(models.CaptchaChallenge{
+		ForumID:    opts.ForumID,
+		ForumName:  opts.TargetName,
+		URL:        pageURL,
+		Screenshot: "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot),
+		Prompt:     found.Prompt,
+		Attempt:    attempt,
+	})
+	if err != nil {
+		logger.Error("CAPTCHA was not answered", "error", err, "target", pageURL)
+		return history.WithClass(history.ClassCaptcha, err)
+	}
+
+	clicks := make([]headless.Point, 0, len(answer.Clicks))
+	for _, c := range answer.Clicks {
+		clicks = append(clicks, headless.Point{X: c.X, Y: c.Y})
+	}
+	ctx, cancel := context.WithTimeout(tab, opts.Timeout+opts.Readiness.Budget())
+	defer cancel()
+	if err := headless.Answer(ctx, opts.Readiness, clicks, found.Input, answer.Text); err != nil {
+		return history.WithClass(history.ClassCaptcha, err)
+	}
+	if opts.Cookies != nil {
+		if err := headless.SaveCookies(ctx, opts.Cookies, opts.ForumID); err != nil {
+			return err
+		}
+	}
+	logger.Info("Submitted CAPTCHA answer", "target", pageURL, "attempt", attempt)
+	return nil
+}
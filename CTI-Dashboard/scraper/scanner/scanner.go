@@ -1,20 +1,27 @@
 package scanner
 
 import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/changes"
+	"CTI-Dashboard/scraper/classifier"
+	"CTI-Dashboard/scraper/connectivity"
+	"CTI-Dashboard/scraper/custody"
+	"CTI-Dashboard/scraper/headless"
+	"CTI-Dashboard/scraper/history"
 	"CTI-Dashboard/scraper/logger"
 	"CTI-Dashboard/scraper/output"
+	"CTI-Dashboard/scraper/retry"
+	"CTI-Dashboard/scraper/search"
+	"CTI-Dashboard/scraper/session"
 	"CTI-Dashboard/scraper/severity"
-	"context"
+	"CTI-Dashboard/scraper/snapshots"
 	"database/sql"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/chromedp/chromedp"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -23,21 +30,55 @@ type Scanner struct {
 	Writer  *output.Writer
 	Timeout time.Duration
 	Proxy   string
+	// manifest collects the artifacts of the current run.
+	manifest *custody.Manifest
+	// exitIP is the exit the connectivity check of this scan saw through
+	// Client, until the Tor identity is renewed.
+	exitIP string
 }
 type Options struct {
-	Targets    []string
-	Client     *http.Client
-	Writer     *output.Writer
-	Timeout    time.Duration
+	Targets []string
+	Client  *http.Client
+	Writer  *output.Writer
+	Timeout time.Duration
+	// Retries is how many attempts a page gets, retry.DefaultAttempts when
+	// zero. A page is always attempted at least once.
 	Retries    int
 	TargetName string
-	Proxy      string
-	DB         *sql.DB
-}
-type TorStatus struct {
-	IP       string
-	IsTor    bool
-	Response string
+	ForumID    string
+	// Proxy is the browser proxy URL for screenshots, empty for direct.
+	Proxy string
+	DB    *sql.DB
+	// Browser provides tabs for screenshots. When nil a browser is launched
+	// for the capture and closed afterwards.
+	Browser   *headless.Pool
+	Readiness headless.Readiness
+	// FetchMode is FetchHTTP (default) or FetchBrowser. Cookies keeps the
+	// forum's browser cookies between requests.
+	FetchMode string
+	Cookies   *headless.CookieJar
+	// CaptureFormats are CaptureMHTML and/or CapturePDF, saved alongside
+	// the screenshot of forum pages.
+	CaptureFormats []string
+	// NewIdentity, when set, is called before retrying a failed attempt so
+	// the next one goes out over a fresh Tor circuit.
+	NewIdentity func() error
+	// Reauthenticate, when set, logs in again after a page shows the session
+	// has expired. The attempt is then repeated.
+	Reauthenticate func() error
+	// Captcha, when set, hands a CAPTCHA to the analyst and blocks until it
+	// is answered. The answer is submitted in the same browser tab.
+	Captcha func(models.CaptchaChallenge) (models.CaptchaAnswer, error)
+	// Retry decides how failed attempts are retried. Defaults to
+	// retry.New(Retries).
+	Retry retry.Policy
+	// Alert, when set, is told about every alert the scan raises.
+	Alert func(models.Alert)
+	// Custody, when set, signs a manifest of the artifacts of every run.
+	Custody *custody.Signer
+	// Connectivity is checked before scraping. Defaults to the
+	// check.torproject.org endpoint without caching.
+	Connectivity connectivity.Checker
 }
 
 func NewScanner(client *http.Client, writer *output.Writer, timeout time.Duration, proxy string) *Scanner {
@@ -51,177 +92,325 @@ func NewScanner(client *http.Client, writer *output.Writer, timeout time.Duratio
 
 func Run(opts Options) error {
 	scanner := NewScanner(opts.Client, opts.Writer, opts.Timeout, opts.Proxy)
-	response, err := scanner.checkTorStatus()
-	if err != nil {
-		logger.Error("Failed to check Tor status: ", "error", err)
-		return err
-	} else {
-		if response.IsTor {
-			logger.Info("Connected to Tor network", "IP", response.IP)
-		} else {
-			logger.Error("Not connected to Tor network")
+	torErr := scanner.checkConnectivity(opts)
+	for _, target := range opts.Targets {
+		run := history.Start(opts.ForumID, target, history.KindForum)
+		if torErr != nil {
+			run.Finish(opts.DB, torErr)
+			return torErr
+		}
+		run.ExitIP = scanner.exitIP
+		scanner.manifest = custody.NewManifest(run.RunID, opts.ForumID)
+		err := scanner.scrapeForum(target, opts, run)
+		run.Finish(opts.DB, err)
+		scanner.seal(opts)
+		if err != nil {
 			return err
 		}
 	}
-	for _, target := range opts.Targets {
-		fmt.Printf("Scanning target: %s  (Name: %s)\n", target, opts.TargetName)
-		for i := 0; i < opts.Retries; i++ {
-			fmt.Printf("Scraping - Attempt %d/3\n", i+1)
-			response, err := opts.Client.Get(target)
+	return nil
+}
+
+func (s *Scanner) scrapeForum(target string, opts Options, run *history.Run) error {
+	logger.Info("Scanning target", "target", target, "name", opts.TargetName)
+	var screenShot []byte
+	page, err := s.attempts(target, opts, run, func() (*page, error) {
+		page, err := s.fetch(target, opts, true)
+		if err != nil {
+			return page, err
+		}
+		if err := s.check(target, opts, run, page); err != nil {
+			return page, err
+		}
+		if page.screenshot == nil {
+			captured, err := s.CaptureScreenshot(target, opts)
 			if err != nil {
-				logger.Error("Request failed", "error", err, "target", target, "attempt", i+1)
-				time.Sleep(time.Duration(i+1) * 2 * time.Second)
-				if i == opts.Retries-1 {
-					return err
-				}
-				continue
+				return page, history.WithClass(history.ClassScreenshot, err)
 			}
-			if response.StatusCode == http.StatusOK {
-				body, err := io.ReadAll(response.Body)
-				if err != nil {
-					logger.Error("Failed to read response body", "error", err, "target", target)
-					fmt.Println(body)
-					response.Body.Close()
-					if i == opts.Retries-1 {
-						return err
-					}
-					continue
-				}
+			page.screenshot, page.mhtml, page.pdf = captured.Screenshot, captured.MHTML, captured.PDF
+			page.renderedAt = time.Now()
+		}
+		screenShot = page.screenshot
+		return page, nil
+	})
+	if err != nil {
+		if page != nil && isPageClass(run.PageClass) {
+			updateForumStatus(opts, forumRef{"forum_url", target}, run.PageClass, page.body)
+		}
+		return err
+	}
 
-				screenShot, err := scanner.CaptureScreenshot(target, opts)
-				if err != nil {
-					logger.Error("Screenshot capture failed", "error", err, "target", target)
-					response.Body.Close()
-					if i == opts.Retries-1 {
-						return err
-					}
-					continue
-				}
-				response.Body.Close()
-				paths, err := opts.Writer.WriteResult(target, body, screenShot)
-				if err != nil {
-					logger.Error("Failed to write result", "error", err, "target", target)
-					if i == opts.Retries-1 {
-						return err
-					}
-					continue
-				}
-				logger.Info("Successfully scraped target", "target", target)
-				UpdateLastScan(target, opts.TargetName, paths, opts.DB, body)
-				break
-			}
-			response.Body.Close()
-			return fmt.Errorf("request failed with status: %s", response.Status)
+	html, shot, err := opts.Writer.WriteResult(page.body, screenShot)
+	if err != nil {
+		logger.Error("Failed to write result", "error", err, "target", target)
+		return history.WithClass(history.ClassWrite, err)
+	}
+	mhtml, pdf, err := opts.Writer.WriteCaptures(page.mhtml, page.pdf)
+	if err != nil {
+		logger.Error("Failed to write captures", "error", err, "target", target)
+		return history.WithClass(history.ClassWrite, err)
+	}
+	logger.Info("Successfully scraped target", "target", target)
+	s.collect(opts, run, custody.KindHTML, page, html)
+	s.collect(opts, run, custody.KindScreenshot, page, shot)
+	s.collect(opts, run, custody.KindMHTML, page, mhtml)
+	s.collect(opts, run, custody.KindPDF, page, pdf)
+	ref := recordSnapshot(opts, run, models.Snapshot{
+		ForumID:     opts.ForumID,
+		MHTMLSHA256: mhtml.SHA256,
+		MHTMLPath:   mhtml.Path,
+		PDFSHA256:   pdf.SHA256,
+		PDFPath:     pdf.Path,
+	}, html, shot)
+	if opts.DB != nil {
+		if _, err := changes.CompareThreads(opts.DB, ref, target, page.body); err != nil {
+			logger.Error("Could not compare the thread list with the last scrape", "error", err, "target", target)
 		}
 	}
-	return err
+	updateForumStatus(opts, forumRef{"forum_url", target}, classifier.ClassOK, page.body)
+	UpdateLastScan(target, opts.TargetName, []string{html.Path, shot.Path, mhtml.Path, pdf.Path}, opts.DB, page.body)
+	return nil
 }
 
 func RunPost(opts Options) error {
 	scanner := NewScanner(opts.Client, opts.Writer, opts.Timeout, opts.Proxy)
-	response, err := scanner.checkTorStatus()
+	torErr := scanner.checkConnectivity(opts)
+	for _, target := range opts.Targets {
+		run := history.Start(opts.ForumID, target, history.KindPost)
+		if torErr != nil {
+			run.Finish(opts.DB, torErr)
+			return torErr
+		}
+		run.ExitIP = scanner.exitIP
+		scanner.manifest = custody.NewManifest(run.RunID, opts.ForumID)
+		err := scanner.scrapePost(target, opts, run)
+		run.Finish(opts.DB, err)
+		scanner.seal(opts)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Scanner) scrapePost(target string, opts Options, run *history.Run) error {
+	logger.Info("Scanning target", "target", target, "name", opts.TargetName)
+	// The extra capture formats are for forum pages only.
+	opts.CaptureFormats = nil
+	page, err := s.attempts(target, opts, run, func() (*page, error) {
+		page, err := s.fetch(target, opts, true)
+		if err != nil {
+			return page, err
+		}
+		return page, s.check(target, opts, run, page)
+	})
 	if err != nil {
-		logger.Error("Failed to check Tor status: ", "error", err)
+		if page != nil && gone(page, run) {
+			post, _ := lookupPost(opts, target)
+			threadGone(opts, changes.Ref{ForumID: post.ForumID, PostID: post.PostID, TargetURL: target}, err)
+		}
 		return err
-	} else {
-		if response.IsTor {
-			logger.Info("Connected to Tor network", "IP", response.IP)
+	}
+
+	// Unlike a forum page, a thread fetched over HTTP is kept without its
+	// screenshot when the browser cannot take one.
+	if page.screenshot == nil {
+		if captured, err := s.CaptureScreenshot(target, opts); err != nil {
+			logger.Error("Could not take a screenshot of the thread", "error", err, "target", target)
 		} else {
-			logger.Error("Not connected to Tor network")
-			return errors.New("not connected to Tor network")
+			page.screenshot, page.renderedAt = captured.Screenshot, time.Now()
 		}
 	}
-	for _, target := range opts.Targets {
-		fmt.Printf("Scanning target: %s  (Name: %s)\n", target, opts.TargetName)
-		for i := 0; i < opts.Retries; i++ {
-			fmt.Printf("Scraping - Attempt %d/3\n", i+1)
-			response, err := opts.Client.Get(target)
-			if err != nil {
-				logger.Error("Request failed", "error", err, "target", target, "attempt", i+1)
-				time.Sleep(time.Duration(i+1) * 2 * time.Second)
-				if i == opts.Retries-1 {
-					return err
-				}
-				continue
-			}
-			if response.StatusCode == http.StatusOK {
-				body, err := io.ReadAll(response.Body)
-				if err != nil {
-					logger.Error("Failed to read response body", "error", err, "target", target)
-					fmt.Println(body)
-					response.Body.Close()
-					if i == opts.Retries-1 {
-						return err
-					}
-					continue
-				}
-				response.Body.Close()
-				logger.Info("Successfully scraped target", "target", target)
-				UpdateLastScanPost(target, opts.DB, body)
-
-				postBody := strings.NewReader(string(body))
-				err = severity.AssessSeverity(postBody, opts.DB, target)
-				if err != nil {
-					logger.Error("Failed to assess severity", "error", err, "target", target)
-				}
-				break
-			}
-			response.Body.Close()
-			return fmt.Errorf("request failed with status: %s", response.Status)
+	html, shot, err := opts.Writer.WriteResult(page.body, page.screenshot)
+	if err != nil {
+		logger.Error("Failed to write result", "error", err, "target", target)
+		return history.WithClass(history.ClassWrite, err)
+	}
+	logger.Info("Successfully scraped target", "target", target)
+	s.collect(opts, run, custody.KindHTML, page, html)
+	s.collect(opts, run, custody.KindScreenshot, page, shot)
+	post, previous := lookupPost(opts, target)
+	ref := recordSnapshot(opts, run, post, html, shot)
+	if opts.DB != nil {
+		if _, err := changes.ComparePost(opts.DB, ref, changes.FirstPost(previous), changes.FirstPost(page.body)); err != nil {
+			logger.Error("Could not compare the thread with the last scrape", "error", err, "target", target)
 		}
 	}
-	return err
+	UpdateLastScanPost(target, opts.DB, page.body)
+	flagIndicators(opts, post, target, page.body)
+
+	postBody := strings.NewReader(string(page.body))
+	err = severity.AssessSeverity(postBody, opts.DB, target)
+	if err != nil {
+		logger.Error("Failed to assess severity", "error", err, "target", target)
+	}
+	return nil
 }
 
-func (s *Scanner) CaptureScreenshot(targetURL string, opts Options) ([]byte, error) {
+// lookupPost returns the forum and post IDs of a thread URL and the page
+// kept from its previous scrape.
+func lookupPost(opts Options, target string) (models.Snapshot, []byte) {
+	var post models.Snapshot
+	var content []byte
+	if opts.DB != nil {
+		opts.DB.QueryRow(`SELECT post_id, COALESCE(forum_id, ''), COALESCE(content, '') FROM posts WHERE thread_url = ?`, target).
+			Scan(&post.PostID, &post.ForumID, &content)
+	}
+	if post.ForumID == "" {
+		post.ForumID = opts.ForumID
+	}
+	return post, content
+}
 
-	optsScr := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.ProxyServer(opts.Proxy),
-		chromedp.Flag("headless", true),
-		chromedp.Flag("disable-gpu", true),
-		chromedp.Flag("disable-extensions", true),
-		chromedp.Flag("disable-blink-features", "AutomationControlled"),
-		chromedp.Flag("no-first-run", true),
-		chromedp.Flag("no-default-browser-check", true),
-		chromedp.Flag("ignore-certificate-errors", true),
-		chromedp.NoSandbox,
-		chromedp.WindowSize(1920, 1080),
-	)
+// recordSnapshot stores the snapshot of the run's target. snapshot carries
+// the forum and post IDs; the rest is filled in from the run and the blobs.
+// It returns the reference changes are recorded against, with the previous
+// snapshot of the same URL.
+func recordSnapshot(opts Options, run *history.Run, snapshot models.Snapshot, html output.Blob, shot output.Blob) changes.Ref {
+	ref := changes.Ref{ForumID: snapshot.ForumID, PostID: snapshot.PostID, TargetURL: run.Target}
+	if opts.DB == nil {
+		return ref
+	}
+	if previous, err := snapshots.Latest(opts.DB, run.Target); err == nil {
+		ref.PreviousSnapshotID = previous.SnapshotID
+	}
+	snapshot.TargetURL = run.Target
+	snapshot.Kind = string(run.Kind)
+	snapshot.RunID = run.RunID
+	snapshot.HTMLSHA256 = html.SHA256
+	snapshot.HTMLPath = html.Path
+	snapshot.HTMLSize = html.Size
+	snapshot.ScreenshotSHA256 = shot.SHA256
+	snapshot.ScreenshotPath = shot.Path
+	snapshot.ScreenshotSize = shot.Size
+	recorded, err := snapshots.Record(opts.DB, snapshot)
+	if err != nil {
+		// The pages are on disk; changes are still compared, without a link
+		// to this snapshot.
+		logger.Error("Could not record the snapshot of a scrape", "error", err, "target", run.Target, "run_id", run.RunID)
+		return ref
+	}
+	ref.SnapshotID = recorded.SnapshotID
+	return ref
+}
 
-	allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), optsScr...)
-	defer cancel()
+// attempts runs try until it succeeds or the retry policy gives up, and logs
+// every attempt on the run.
+func (s *Scanner) attempts(target string, opts Options, run *history.Run, try func() (*page, error)) (*page, error) {
+	policy := opts.Retry
+	if policy.MaxAttempts <= 0 {
+		policy = retry.New(opts.Retries)
+	}
+	for attempt := 1; ; attempt++ {
+		logger.Info("Scraping", "target", target, "attempt", attempt, "max_attempts", policy.MaxAttempts)
+		started := time.Now()
+		page, err := try()
+		if page != nil {
+			s.archive(opts, run, page, attempt, err)
+		}
 
-	ctx, cancel := chromedp.NewContext(allocCtx)
-	defer cancel()
+		entry := models.ScanAttempt{
+			Attempt:    attempt,
+			StartedAt:  stored.Time(started),
+			DurationMs: time.Since(started).Milliseconds(),
+			URL:        target,
+		}
+		if page != nil {
+			run.HTTPStatus = page.status
+			run.Bytes = int64(len(page.body))
+			entry.HTTPStatus = page.status
+			entry.Redirects = page.redirects
+			if page.url != "" {
+				entry.URL = page.url
+			}
+		}
+		if err == nil {
+			run.Attempts = append(run.Attempts, entry)
+			return page, nil
+		}
 
-	ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
-	defer cancel()
+		decision := policy.Decide(attempt, err)
+		entry.ErrorClass = history.Classify(err)
+		entry.Error = err.Error()
+		entry.Retry = decision.Retry
+		entry.DelayMs = decision.Delay.Milliseconds()
+		entry.Reason = decision.Reason
+		run.Attempts = append(run.Attempts, entry)
+		logger.Error("Attempt failed", "error", err, "target", target, "attempt", attempt, "retry", decision.Retry, "delay", decision.Delay, "reason", decision.Reason)
+		if !decision.Retry {
+			return page, err
+		}
+		if decision.Renew && renewIdentity(opts) {
+			// The next attempt leaves through another exit.
+			run.ExitIP, s.exitIP = "", ""
+		}
+		time.Sleep(decision.Delay)
+	}
+}
 
-	var buf []byte
-	err := chromedp.Run(ctx,
-		chromedp.Navigate(targetURL),
-		chromedp.WaitVisible(`body`, chromedp.ByQuery),
-		chromedp.Sleep(25*time.Second),
-		chromedp.FullScreenshot(&buf, 90),
-	)
+// check turns a fetched page that is not usable content into an error: an
+// unexpected status, an expired session or a page the classifier rejects.
+func (s *Scanner) check(target string, opts Options, run *history.Run, page *page) error {
+	run.PageClass = ""
+	if page.status != http.StatusOK {
+		return retry.Status(page.status, page.statusText, page.header)
+	}
+	if loggedOut(opts, page.body) {
+		logger.Info("Session expired, logging in again", "target", target)
+		if err := opts.Reauthenticate(); err != nil {
+			logger.Error("Could not log in", "error", err, "target", target)
+			return history.WithClass(history.ClassAuth, err)
+		}
+		return retry.Again(history.WithClass(history.ClassAuth, errors.New("session expired, logged in again")))
+	}
+	result := classifier.Classify(page.body, run.Kind == history.KindForum)
+	run.PageClass = result.Class
+	if result.Class != classifier.ClassOK {
+		return history.WithClass(result.Class, discard(target, result))
+	}
+	return nil
+}
 
-	if err != nil {
-		return nil, err
+// CaptureScreenshot renders the page for a full-page screenshot and the
+// forum's extra capture formats.
+func (s *Scanner) CaptureScreenshot(targetURL string, opts Options) (*headless.Page, error) {
+	return s.render(targetURL, opts, captures(opts, headless.RenderOptions{Screenshot: true}))
+}
+
+// loggedOut reports whether the page was served to a logged-out visitor on a
+// forum we hold credentials for.
+func loggedOut(opts Options, body []byte) bool {
+	return opts.Reauthenticate != nil && session.LoggedOut(body)
+}
+
+// renewIdentity reports whether a new Tor identity was requested.
+func renewIdentity(opts Options) bool {
+	if opts.NewIdentity == nil {
+		return false
+	}
+	if err := opts.NewIdentity(); err != nil {
+		logger.Error("Could not request a new Tor identity", "error", err)
+		return false
 	}
-	return buf, nil
+	logger.Info("Requested a new Tor identity", "target", opts.TargetName)
+	return true
 }
 
-func (s *Scanner) checkTorStatus() (*TorStatus, error) {
-	resp, err := s.Client.Get("https://check.torproject.org/api/ip")
-	if err != nil {
-		return nil, err
+// checkConnectivity checks the scan's client and keeps the exit IP the
+// check saw. A cached check has none, since it may have been made through
+// another forum's circuit.
+func (s *Scanner) checkConnectivity(opts Options) error {
+	checker := opts.Connectivity
+	if checker == nil {
+		checker = connectivity.TorProject{URL: connectivity.TorProjectURL}
 	}
-	defer resp.Body.Close()
-	var status TorStatus
-	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
-		return nil, err
+	status, err := checker.Check(s.Client)
+	if err != nil {
+		logger.Error("Connectivity check failed", "error", err)
+		return history.WithClass(history.ClassTor, err)
 	}
-	return &status, nil
+	s.exitIP = status.IP
+	return nil
 }
 
 func UpdateLastScan(target string, name string, paths []string, db *sql.DB, body []byte) {
@@ -229,12 +418,19 @@ func UpdateLastScan(target string, name string, paths []string, db *sql.DB, body
 		logger.Error("Could not update: paths slice must contain at least 2 elements (HTML and Screenshot)")
 		return
 	}
+	// MHTML and PDF captures are optional.
+	for len(paths) < 4 {
+		paths = append(paths, "")
+	}
 	engine, err := identify_engine(string(body))
 	if err != nil {
 		logger.Error("Could not identify engine", err)
 	}
 
-	query := `UPDATE forums SET last_scaned = ?, forum_html = ?, forum_screenshot = ?, forum_engine = ? WHERE forum_url = ?`
+	// An engine hint given when the forum was added stands in for one the
+	// page does not reveal.
+	query := `UPDATE forums SET last_scaned = ?1, forum_html = ?2, forum_screenshot = ?3, forum_mhtml = ?4, forum_pdf = ?5,
+		forum_engine = CASE WHEN ?6 = 'Unknown' THEN COALESCE(NULLIF(engine_hint, ''), ?6) ELSE ?6 END WHERE forum_url = ?7`
 	statement, err := db.Prepare(query)
 	if err != nil {
 		logger.Error("Could not prepare the database statement", err)
@@ -242,7 +438,7 @@ func UpdateLastScan(target string, name string, paths []string, db *sql.DB, body
 	}
 	defer statement.Close()
 	ts := time.Now().Format("2006-01-02 15:04:05")
-	_, err = statement.Exec(ts, paths[0], paths[1], engine, target)
+	_, err = statement.Exec(ts, paths[0], paths[1], paths[2], paths[3], engine, target)
 	if err != nil {
 		logger.Error("Could not update forum in the database", err)
 		return
@@ -251,14 +447,18 @@ func UpdateLastScan(target string, name string, paths []string, db *sql.DB, body
 }
 
 func UpdateLastScanPost(target string, db *sql.DB, body []byte) {
-	statement, err := db.Prepare(`UPDATE posts SET content = ? WHERE thread_url = ?`)
+	// The text read from the page is what full-text search indexes. The
+	// page's <title> only stands in for a post that has no title yet.
+	statement, err := db.Prepare(`UPDATE posts SET content = ?, title = COALESCE(NULLIF(?, ''), NULLIF(title, ''), ?),
+		author = COALESCE(NULLIF(?, ''), author), body_text = ?, replies = ? WHERE thread_url = ?`)
 	if err != nil {
 		logger.Error("Could not prepare the database statement", err)
 		return
 	}
 	defer statement.Close()
 
-	_, err = statement.Exec(body, target)
+	page := search.Read(string(body))
+	_, err = statement.Exec(body, page.Title, page.PageTitle, page.Author, page.Body, page.Replies, target)
 	if err != nil {
 		logger.Error("Could not update forum in the database", err)
 		return
@@ -266,9 +466,13 @@ func UpdateLastScanPost(target string, db *sql.DB, body []byte) {
 	logger.Info("Successfully updated the last scan", "URL", target)
 }
 
+// Engines are the forum engines a scrape can identify.
+var Engines = []string{"XenForo", "phpBB"}
+
 func identify_engine(html_body string) (string, error) {
 	engines := map[string]string{
-		`id="XF"`: "XenForo",
+		`id="XF"`:    "XenForo",
+		`id="phpbb"`: "phpBB",
 	}
 	for signature, engine := range engines {
 		if strings.Contains(html_body, signature) {
@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"CTI-Dashboard/db/migrations"
+	"CTI-Dashboard/scraper/connectivity"
+	"CTI-Dashboard/scraper/history"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/output"
+	"CTI-Dashboard/scraper/retry"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRunFailure(t *testing.T) {
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "scanner.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := migrations.Apply(db); err != nil {
+		t.Fatal(err)
+	}
+	writer, err := output.NewWriter(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests, status atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+	fast := retry.New(2)
+	fast.BaseDelay, fast.MaxDelay = time.Millisecond, time.Millisecond
+
+	tests := []struct {
+		name     string
+		status   int32
+		retries  int
+		policy   retry.Policy
+		requests int32
+	}{
+		// No retries configured still fetches the page once and fails.
+		{"no retries", http.StatusNotFound, 0, retry.Policy{}, 1},
+		{"terminal status", http.StatusNotFound, 3, retry.Policy{}, 1},
+		{"retried status", http.StatusBadGateway, 0, fast, 2},
+	}
+	for _, test := range tests {
+		requests.Store(0)
+		status.Store(test.status)
+		if _, err := db.Exec(`DELETE FROM scan_runs`); err != nil {
+			t.Fatal(err)
+		}
+		err := Run(Options{
+			Targets:      []string{server.URL + "/forums/market/"},
+			Client:       server.Client(),
+			Writer:       writer,
+			Timeout:      time.Second,
+			Retries:      test.retries,
+			Retry:        test.policy,
+			DB:           db,
+			Connectivity: connectivity.None{},
+		})
+		if err == nil {
+			t.Errorf("%s: the scan succeeded", test.name)
+		}
+		if got := requests.Load(); got != test.requests {
+			t.Errorf("%s: %d requests, want %d", test.name, got, test.requests)
+		}
+		var outcome string
+		if err := db.QueryRow(`SELECT outcome FROM scan_runs`).Scan(&outcome); err != nil || outcome != history.OutcomeFailed {
+			t.Errorf("%s: run recorded as %q (%v), want failed", test.name, outcome, err)
+		}
+	}
+}
+
+func TestUpdateLastScan(t *testing.T) {
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "scanner.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := migrations.Apply(db); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO forums (forum_id, forum_name, forum_url, engine_hint) VALUES
+		('f1', 'Bazaar', 'http://bazaar.onion/', 'phpBB'), ('f2', 'Carder Hub', 'http://carder.onion/', 'phpBB')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	UpdateLastScan("http://bazaar.onion/", "Bazaar", []string{"a.html", "a.png"}, db, []byte(`<html id="XF"></html>`))
+	UpdateLastScan("http://carder.onion/", "Carder Hub", []string{"b.html", "b.png"}, db, []byte(`<html></html>`))
+	for _, want := range []struct{ id, html, engine string }{{"f1", "a.html", "XenForo"}, {"f2", "b.html", "phpBB"}} {
+		var html, engine string
+		if err := db.QueryRow(`SELECT forum_html, forum_engine FROM forums WHERE forum_id = ?`, want.id).Scan(&html, &engine); err != nil {
+			t.Fatal(err)
+		}
+		if html != want.html || engine != want.engine {
+			t.Errorf("%s: got %s, %s, want %s, %s", want.id, html, engine, want.html, want.engine)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package scanner
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/alerts"
+	"CTI-Dashboard/scraper/changes"
+	"CTI-Dashboard/scraper/classifier"
+	"CTI-Dashboard/scraper/history"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/watchlist"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// forumRef identifies the forum row a scan updates: forum scans by their
+// URL, like UpdateLastScan, post scans by the forum ID they carry.
+type forumRef struct {
+	column string
+	value  string
+}
+
+// updateForumStatus stores the page class as the forum's status. It raises
+// an alert when the forum starts showing a seizure banner and, for good
+// pages, when the forum looks like it changed hands since the last scan: its
+// title or engine changed, or it started showing a handover notice.
+func updateForumStatus(opts Options, ref forumRef, class string, body []byte) {
+	if opts.DB == nil || ref.value == "" {
+		return
+	}
+	var forumID, name, status, title, engine, notice sql.NullString
+	err := opts.DB.QueryRow(`SELECT forum_id, forum_name, status, page_title, forum_engine, handover_notice FROM forums WHERE `+ref.column+` = ?`, ref.value).
+		Scan(&forumID, &name, &status, &title, &engine, &notice)
+	if err != nil {
+		logger.Error("Could not read forum status", "error", err, ref.column, ref.value)
+		return
+	}
+
+	if class == classifier.ClassSeized && status.String != classifier.ClassSeized {
+		raise(opts, forumID.String, alerts.KindSeizure, fmt.Sprintf("%s shows a seizure banner", name.String))
+	}
+
+	if class != classifier.ClassOK {
+		_, err = opts.DB.Exec(`UPDATE forums SET status = ? WHERE forum_id = ?`, class, forumID.String)
+		if err != nil {
+			logger.Error("Could not update forum status", "error", err, "forum_id", forumID.String)
+		}
+		return
+	}
+
+	newTitle := classifier.Title(body)
+	newEngine, _ := identify_engine(string(body))
+	var reasons []string
+	if title.String != "" && newTitle != "" && !strings.EqualFold(title.String, newTitle) {
+		reasons = append(reasons, fmt.Sprintf("title changed from %q to %q", title.String, newTitle))
+	}
+	if known(engine.String) && known(newEngine) && engine.String != newEngine {
+		reasons = append(reasons, fmt.Sprintf("engine changed from %s to %s", engine.String, newEngine))
+	}
+	phrase := classifier.HandoverNotice(body)
+	if phrase != "" && !strings.EqualFold(phrase, notice.String) {
+		reasons = append(reasons, fmt.Sprintf("page says %q", phrase))
+	}
+	if len(reasons) > 0 {
+		raise(opts, forumID.String, alerts.KindOwnership,
+			fmt.Sprintf("%s may have changed ownership: %s", name.String, strings.Join(reasons, "; ")))
+	}
+
+	if newTitle == "" {
+		newTitle = title.String
+	}
+	_, err = opts.DB.Exec(`UPDATE forums SET status = ?, page_title = ?, handover_notice = ? WHERE forum_id = ?`, class, newTitle, phrase, forumID.String)
+	if err != nil {
+		logger.Error("Could not update forum status", "error", err, "forum_id", forumID.String)
+	}
+}
+
+func known(engine string) bool {
+	return engine != "" && engine != "Unknown"
+}
+
+func raise(opts Options, forumID string, kind string, message string) {
+	alert, err := alerts.Raise(opts.DB, forumID, kind, message)
+	if err != nil {
+		return
+	}
+	if opts.Alert != nil {
+		opts.Alert(alert)
+	}
+}
+
+// discard reports the page as junk and returns the run's error.
+func discard(target string, result classifier.Result) error {
+	logger.Error("Page is not forum content", "class", result.Class, "rule", result.Rule, "target", target)
+	return fmt.Errorf("page classified as %s by rule %s", result.Class, result.Rule)
+}
+
+// isPageClass reports whether class is a verdict of the classifier on a
+// junk page rather than a transport error.
+func isPageClass(class string) bool {
+	return class != "" && class != classifier.ClassOK
+}
+
+// gone reports whether a thread page failed because the thread no longer
+// exists rather than because the forum could not be reached.
+func gone(page *page, run *history.Run) bool {
+	return page.status == http.StatusNotFound || page.status == http.StatusGone || run.PageClass == classifier.ClassNotFound
+}
+
+// threadGone records a deleted thread and raises an alert the first time it
+// is seen gone.
+func threadGone(opts Options, ref changes.Ref, reason error) {
+	if opts.DB == nil {
+		return
+	}
+	change, err := changes.PostDeleted(opts.DB, ref, reason.Error())
+	if err != nil || change == nil {
+		return
+	}
+	raise(opts, ref.ForumID, alerts.KindDeletion, fmt.Sprintf("Thread %s was deleted: %s", ref.TargetURL, reason))
+}
+
+// flagIndicators flags the post if it contains watched indicators and raises
+// an alert for the ones it did not contain before.
+func flagIndicators(opts Options, post models.Snapshot, target string, body []byte) {
+	if opts.DB == nil {
+		return
+	}
+	matches, err := watchlist.Check(opts.DB, post.ForumID, post.PostID, target, body)
+	if err != nil || len(matches) == 0 {
+		return
+	}
+	values := make([]string, 0, len(matches))
+	for _, match := range matches {
+		values = append(values, match.Value)
+	}
+	raise(opts, post.ForumID, alerts.KindIndicator, fmt.Sprintf("Thread %s mentions watched indicators: %s", target, strings.Join(values, ", ")))
+}
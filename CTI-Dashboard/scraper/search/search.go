@@ -0,0 +1,350 @@
+// Package search is the full-text search over scraped posts. The index is an
+// FTS5 table over the title, author, opening post and replies of each post,
+// kept in sync with the posts table by triggers.
+//
+// The SQLite driver only has FTS5 when built with -tags sqlite_fts5, so the
+// index is set up by Prepare rather than by a migration: a build without it
+// still runs, and search reports ErrUnavailable. The tests of this package
+// skip without it; run them with go test -tags sqlite_fts5.
+package search
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/changes"
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mattn/go-sqlite3"
+)
+
+// PageSize is how many hits a page of results holds.
+const PageSize = 25
+
+var (
+	// ErrUnavailable is returned by a build without FTS5.
+	ErrUnavailable = errors.New("full-text search needs a build with -tags sqlite_fts5")
+	// ErrQuery marks a search query or filter that cannot be run.
+	ErrQuery = errors.New("invalid search")
+)
+
+// Matched terms are marked with control characters in SQLite and turned into
+// <mark> once the rest of the text is escaped.
+const (
+	markOpen  = "\x02"
+	markClose = "\x03"
+)
+
+// The index keeps its own copy of the text, keyed by post_id: an external
+// content table would be keyed by the rowid of posts, which VACUUM may change.
+const index = `CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts USING fts5(
+	post_id UNINDEXED, title, author, body_text, replies,
+	tokenize = 'unicode61 remove_diacritics 2'
+)`
+
+var triggers = []string{
+	`CREATE TRIGGER IF NOT EXISTS posts_fts_insert AFTER INSERT ON posts BEGIN
+		INSERT INTO posts_fts (post_id, title, author, body_text, replies)
+		VALUES (new.post_id, new.title, new.author, new.body_text, new.replies);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS posts_fts_delete AFTER DELETE ON posts BEGIN
+		DELETE FROM posts_fts WHERE post_id = old.post_id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS posts_fts_update AFTER UPDATE OF post_id, title, author, body_text, replies ON posts BEGIN
+		DELETE FROM posts_fts WHERE post_id = old.post_id;
+		INSERT INTO posts_fts (post_id, title, author, body_text, replies)
+		VALUES (new.post_id, new.title, new.author, new.body_text, new.replies);
+	END`,
+}
+
+var triggerNames = []string{"posts_fts_insert", "posts_fts_delete", "posts_fts_update"}
+
+// Page is the text of a thread page that goes into the index.
+type Page struct {
+	Title     string // from the engine's markup
+	PageTitle string // the <title> of the page, for when Title is empty
+	Author    string
+	Body      string // the opening post
+	Replies   string
+}
+
+// Read returns the title, author and posts of a stored thread page. When the
+// engine's markup is not recognised only PageTitle is set, and the body is
+// the visible page text.
+func Read(content string) Page {
+	var page Page
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return page
+	}
+	page.Title = strings.TrimSpace(doc.Find("h1.p-title-value, h2.topic-title").First().Text())
+	page.PageTitle = strings.TrimSpace(doc.Find("title").First().Text())
+	if author, ok := doc.Find("article.message[data-author]").First().Attr("data-author"); ok {
+		page.Author = author
+	} else {
+		page.Author = strings.TrimSpace(doc.Find("p.author a.username, p.author a.username-coloured").First().Text())
+	}
+
+	var posts []string
+	doc.Find("article.message--post div.bbWrapper, div.postbody div.content").Each(func(i int, s *goquery.Selection) {
+		if text := changes.Text(s); text != "" {
+			posts = append(posts, text)
+		}
+	})
+	if len(posts) == 0 {
+		page.Body = changes.Text(doc.Find("body"))
+		return page
+	}
+	page.Body = posts[0]
+	page.Replies = strings.Join(posts[1:], "\n\n")
+	return page
+}
+
+// Available reports whether the SQLite driver has FTS5.
+func Available(db *sql.DB) bool {
+	var used bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&used); err != nil {
+		return false
+	}
+	return used
+}
+
+// Prepare reads the text of posts scraped before it was kept, and sets up
+// the index and its triggers, rebuilding the index if the triggers were
+// missing. Without FTS5 it drops the triggers instead, so writes to posts
+// still work in a database a build with FTS5 has indexed.
+func Prepare(db *sql.DB) error {
+	if err := fill(db); err != nil {
+		return err
+	}
+	if !Available(db) {
+		for _, name := range triggerNames {
+			if _, err := db.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+				logger.Error("Could not drop search trigger", "error", err, "trigger", name)
+				return err
+			}
+		}
+		logger.Info("Full-text search is off: the SQLite driver was built without FTS5")
+		return nil
+	}
+
+	var existing int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'posts_fts_%'`).Scan(&existing)
+	if err != nil {
+		logger.Error("Could not read the search triggers", "error", err)
+		return err
+	}
+	// An index from an older build is an external content table keyed by rowid.
+	var table string
+	err = db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'posts_fts'`).Scan(&table)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Error("Could not read the search index", "error", err)
+		return err
+	}
+	stale := strings.Contains(table, "content_rowid")
+	if existing == len(triggers) && !stale {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		logger.Error("Could not begin transaction", "error", err)
+		return err
+	}
+	defer tx.Rollback()
+	if stale {
+		for _, name := range triggerNames {
+			if _, err := tx.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+				logger.Error("Could not drop search trigger", "error", err, "trigger", name)
+				return err
+			}
+		}
+		if _, err := tx.Exec(`DROP TABLE posts_fts`); err != nil {
+			logger.Error("Could not drop the old search index", "error", err)
+			return err
+		}
+	}
+	for _, statement := range append([]string{index}, triggers...) {
+		if _, err := tx.Exec(statement); err != nil {
+			logger.Error("Could not create the search index", "error", err)
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM posts_fts`); err != nil {
+		logger.Error("Could not clear the search index", "error", err)
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO posts_fts (post_id, title, author, body_text, replies)
+		SELECT post_id, title, author, body_text, replies FROM posts`); err != nil {
+		logger.Error("Could not build the search index", "error", err)
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("Could not commit the search index", "error", err)
+		return err
+	}
+	logger.Info("Built the search index")
+	return nil
+}
+
+// fill reads the text of scraped posts that do not have it yet.
+func fill(db *sql.DB) error {
+	rows, err := db.Query(`SELECT post_id, content FROM posts
+		WHERE content IS NOT NULL AND content != '' AND COALESCE(body_text, '') = ''`)
+	if err != nil {
+		logger.Error("Could not query posts to index", "error", err)
+		return err
+	}
+	pages := make(map[string]Page)
+	for rows.Next() {
+		var postID, content string
+		if err := rows.Scan(&postID, &content); err != nil {
+			logger.Error("Could not scan post row", "error", err)
+			continue
+		}
+		pages[postID] = Read(content)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logger.Error("Error during rows iteration", "error", err)
+		return err
+	}
+
+	for postID, page := range pages {
+		_, err := db.Exec(`UPDATE posts SET title = COALESCE(NULLIF(title, ''), NULLIF(?, ''), ?), author = COALESCE(NULLIF(author, ''), ?),
+			body_text = ?, replies = ? WHERE post_id = ?`, page.Title, page.PageTitle, page.Author, page.Body, page.Replies, postID)
+		if err != nil {
+			logger.Error("Could not store post text", "error", err, "post_id", postID)
+			return err
+		}
+	}
+	if len(pages) > 0 {
+		logger.Info("Read the text of scraped posts", "posts", len(pages))
+	}
+	return nil
+}
+
+// Search returns a page, counted from 1, of the posts matching query. The
+// query is FTS5 syntax: words must all appear, "quoted words" as a phrase,
+// word* as a prefix, and AND, OR, NOT and parentheses combine them; a column
+// name and colon, as in author:name, searches a single field.
+func Search(db *sql.DB, query string, filter models.SearchFilter, page int) (models.SearchResults, error) {
+	results := models.SearchResults{Query: query, Page: page, PageSize: PageSize, Hits: []models.SearchHit{}}
+	if strings.TrimSpace(query) == "" {
+		return results, fmt.Errorf("%w: the query is empty", ErrQuery)
+	}
+	if page < 1 {
+		return results, fmt.Errorf("%w: page must be at least 1, got %d", ErrQuery, page)
+	}
+	if !Available(db) {
+		return results, ErrUnavailable
+	}
+
+	where := []string{"posts_fts MATCH ?"}
+	args := []any{query}
+	in := func(column string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		where = append(where, column+" IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")+")")
+		for _, value := range values {
+			args = append(args, value)
+		}
+	}
+	in("p.forum_id", filter.ForumIDs)
+	in("COALESCE(p.severity_level, 'unassigned')", filter.Severities)
+	in("p.status", filter.Statuses)
+	if filter.From != "" {
+		from, err := parseDate(filter.From, false)
+		if err != nil {
+			return results, err
+		}
+		where = append(where, "p.created_at >= ?")
+		args = append(args, from)
+	}
+	if filter.To != "" {
+		to, err := parseDate(filter.To, true)
+		if err != nil {
+			return results, err
+		}
+		where = append(where, "p.created_at < ?")
+		args = append(args, to)
+	}
+	from := ` FROM posts_fts JOIN posts p ON p.post_id = posts_fts.post_id LEFT JOIN forums f ON f.forum_id = p.forum_id
+		WHERE ` + strings.Join(where, " AND ")
+
+	if err := db.QueryRow(`SELECT COUNT(*)`+from, args...).Scan(&results.Total); err != nil {
+		return results, queryError(err)
+	}
+
+	// Titles weigh most, then authors, then the opening post; post_id, column
+	// 0, is not indexed.
+	rows, err := db.Query(`SELECT p.post_id, COALESCE(p.forum_id, ''), COALESCE(f.forum_name, ''), p.thread_url,
+		highlight(posts_fts, 1, char(2), char(3)), COALESCE(p.author, ''), COALESCE(p.status, ''),
+		COALESCE(p.severity_level, 'unassigned'), COALESCE(p.created_at, ''),
+		snippet(posts_fts, -1, char(2), char(3), '…', 24), bm25(posts_fts, 0.0, 10.0, 5.0, 2.0, 1.0) AS score`+from+`
+		ORDER BY score, p.created_at DESC LIMIT ? OFFSET ?`, append(args, PageSize, (page-1)*PageSize)...)
+	if err != nil {
+		return results, queryError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hit models.SearchHit
+		var title, snippet sql.NullString
+		err := rows.Scan(&hit.PostID, &hit.ForumID, &hit.ForumName, &hit.ThreadURL, &title, &hit.Author, &hit.Status,
+			&hit.Severity, &hit.CreatedAt, &snippet, &hit.Score)
+		if err != nil {
+			logger.Error("Could not scan search hit", "error", err)
+			continue
+		}
+		hit.Title, hit.Snippet = marked(title.String), marked(snippet.String)
+		// bm25 is lower for better matches.
+		hit.Score = -hit.Score
+		results.Hits = append(results.Hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return results, queryError(err)
+	}
+	return results, nil
+}
+
+// queryError tells a query FTS5 cannot parse, which SQLite reports as a
+// plain SQL error, from a failure of the database.
+func queryError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrError {
+		return fmt.Errorf("%w: %v", ErrQuery, err)
+	}
+	logger.Error("Could not search posts", "error", err)
+	return err
+}
+
+// marked escapes text for HTML and turns the match marks into <mark>.
+func marked(text string) string {
+	text = html.EscapeString(text)
+	return strings.NewReplacer(markOpen, "<mark>", markClose, "</mark>").Replace(text)
+}
+
+// parseDate reads a filter bound as stored in posts.created_at. A date given
+// as the end of a range takes in the whole day.
+func parseDate(value string, end bool) (string, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t.Format("2006-01-02 15:04:05"), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q is not a date like 2025-01-31 or an RFC 3339 time", ErrQuery, value)
+	}
+	if end {
+		t = t.Add(time.Second)
+	}
+	return t.UTC().Format("2006-01-02 15:04:05"), nil
+}
@@ -0,0 +1,184 @@
+package search
+
+import (
+	"CTI-Dashboard/db/migrations"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const thread = `<html><head><title>Bazaar</title></head><body>
+<h1 class="p-title-value">Selling RDP access to a bank</h1>
+<article class="message message--post" data-author="seller"><div class="bbWrapper">Fresh <b>RDP</b> panels, <script>x</script>escrow only</div></article>
+<article class="message message--post" data-author="buyer"><div class="bbWrapper">Vouch, bought two &lt;ransomware&gt; kits</div></article>
+</body></html>`
+
+func setup(t *testing.T) *sql.DB {
+	t.Helper()
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "search.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Apply(db); err != nil {
+		t.Fatal(err)
+	}
+	if !Available(db) {
+		t.Skip("SQLite built without FTS5; run with -tags sqlite_fts5")
+	}
+	statements := []string{
+		`INSERT INTO forums (forum_id, forum_name, forum_url) VALUES ('f1', 'Exploit Bazaar', 'http://bazaar.onion/'), ('f2', 'Carder Hub', 'http://carder.onion/')`,
+		// Scraped before the text was kept: Prepare reads it from the page.
+		`INSERT INTO posts (post_id, forum_id, thread_url, status, content, severity_level, created_at) VALUES
+			('p1', 'f1', 'http://bazaar.onion/threads/1/', 'scraped', '` + thread + `', 'high', '2025-01-02 10:00:00')`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Prepare(db); err != nil {
+		t.Fatal(err)
+	}
+	// Indexed by the triggers.
+	_, err = db.Exec(`INSERT INTO posts (post_id, forum_id, thread_url, status, title, author, body_text, severity_level, created_at) VALUES
+		('p2', 'f2', 'http://carder.onion/threads/2/', 'scraped', 'Dumps with PIN', 'carder', 'Fresh dumps, bank logs on request', 'medium', '2025-02-01 10:00:00')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func ids(results models.SearchResults) string {
+	var list []string
+	for _, hit := range results.Hits {
+		list = append(list, hit.PostID)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+func TestSearch(t *testing.T) {
+	db := setup(t)
+	tests := []struct {
+		query  string
+		filter models.SearchFilter
+		want   string
+	}{
+		{"fresh", models.SearchFilter{}, "p1,p2"},
+		{`"bank logs"`, models.SearchFilter{}, "p2"},
+		{"ransom*", models.SearchFilter{}, "p1"},
+		{"fresh NOT dumps", models.SearchFilter{}, "p1"},
+		{"(rdp OR pin) AND bank", models.SearchFilter{}, "p1,p2"},
+		{"author:buyer", models.SearchFilter{}, ""},
+		{"author:seller", models.SearchFilter{}, "p1"},
+		{"fresh", models.SearchFilter{ForumIDs: []string{"f2"}}, "p2"},
+		{"fresh", models.SearchFilter{Severities: []string{"high"}}, "p1"},
+		{"fresh", models.SearchFilter{From: "2025-01-03"}, "p2"},
+		{"fresh", models.SearchFilter{To: "2025-01-02"}, "p1"},
+		{"fresh", models.SearchFilter{Statuses: []string{"failed"}}, ""},
+	}
+	for _, test := range tests {
+		results, err := Search(db, test.query, test.filter, 1)
+		if err != nil {
+			t.Errorf("Search(%q): %v", test.query, err)
+			continue
+		}
+		if got := ids(results); got != test.want || results.Total != len(results.Hits) {
+			t.Errorf("Search(%q, %+v) = %s (total %d), want %s", test.query, test.filter, got, results.Total, test.want)
+		}
+	}
+}
+
+func TestSearchHighlight(t *testing.T) {
+	db := setup(t)
+	results, err := Search(db, "ransomware", models.SearchFilter{}, 1)
+	if err != nil || len(results.Hits) != 1 {
+		t.Fatalf("got %+v, %v", results, err)
+	}
+	hit := results.Hits[0]
+	if !strings.Contains(hit.Snippet, "&lt;<mark>ransomware</mark>&gt;") {
+		t.Errorf("snippet %q does not escape the text and mark the match", hit.Snippet)
+	}
+	if hit.Title != "Selling RDP access to a bank" || hit.Author != "seller" || hit.ForumName != "Exploit Bazaar" {
+		t.Errorf("got %+v", hit)
+	}
+
+	if _, err := db.Exec(`DELETE FROM posts WHERE post_id = 'p1'`); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := Search(db, "ransomware", models.SearchFilter{}, 1); err != nil || len(results.Hits) != 0 {
+		t.Errorf("deleted post still found: %+v, %v", results, err)
+	}
+}
+
+func TestSearchInvalid(t *testing.T) {
+	db := setup(t)
+	for _, query := range []string{"", `"unterminated`, "AND"} {
+		if _, err := Search(db, query, models.SearchFilter{}, 1); !errors.Is(err, ErrQuery) {
+			t.Errorf("Search(%q) = %v, want ErrQuery", query, err)
+		}
+	}
+	if _, err := Search(db, "fresh", models.SearchFilter{From: "yesterday"}, 1); !errors.Is(err, ErrQuery) {
+		t.Errorf("bad date: %v, want ErrQuery", err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	page := Read(thread)
+	if page.Title != "Selling RDP access to a bank" || page.PageTitle != "Bazaar" || page.Author != "seller" {
+		t.Errorf("got %+v", page)
+	}
+	if page.Body != "Fresh RDP panels, escrow only" || page.Replies != "Vouch, bought two <ransomware> kits" {
+		t.Errorf("body %q, replies %q", page.Body, page.Replies)
+	}
+
+	page = Read(`<html><head><title>Just a moment...</title></head><body><p>Checking your browser</p></body></html>`)
+	if page.Title != "" || page.PageTitle != "Just a moment..." || page.Body != "Checking your browser" {
+		t.Errorf("unrecognised page: got %+v, want only the page title and text", page)
+	}
+}
+
+func TestPrepareRowidIndex(t *testing.T) {
+	db := setup(t)
+	// The index of an older build, keyed by the rowid of posts.
+	statements := []string{
+		`DROP TRIGGER posts_fts_insert`,
+		`DROP TRIGGER posts_fts_delete`,
+		`DROP TRIGGER posts_fts_update`,
+		`DROP TABLE posts_fts`,
+		`CREATE VIRTUAL TABLE posts_fts USING fts5(title, author, body_text, replies, content = 'posts', content_rowid = 'rowid')`,
+		`CREATE TRIGGER posts_fts_insert AFTER INSERT ON posts BEGIN SELECT 1; END`,
+		`CREATE TRIGGER posts_fts_delete AFTER DELETE ON posts BEGIN SELECT 1; END`,
+		`CREATE TRIGGER posts_fts_update AFTER UPDATE ON posts BEGIN SELECT 1; END`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Prepare(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DELETE FROM posts WHERE post_id = 'p1'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`VACUUM`); err != nil {
+		t.Fatal(err)
+	}
+	results, err := Search(db, "fresh", models.SearchFilter{}, 1)
+	if err != nil || ids(results) != "p2" || results.Hits[0].Title != "Dumps with PIN" {
+		t.Errorf("got %+v, %v, want p2 alone", results, err)
+	}
+}
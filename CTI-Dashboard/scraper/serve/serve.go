@@ -0,0 +1,35 @@
+// Package serve runs the HTTP servers of the app: the TAXII and REST APIs.
+package serve
+
+import (
+	"CTI-Dashboard/scraper/logger"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Listen serves handler on address in the background, over TLS when a
+// certificate and key file are given. name labels the server in the log.
+// The returned server's Addr is the address listened on; shut it down to
+// stop it.
+func Listen(name string, handler http.Handler, address string, certFile string, keyFile string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Addr: listener.Addr().String(), Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		var err error
+		if certFile != "" {
+			err = server.ServeTLS(listener, certFile, keyFile)
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(name+" server stopped", "error", err)
+		}
+	}()
+	logger.Info(name+" server listening", "address", server.Addr, "tls", certFile != "")
+	return server, nil
+}
@@ -0,0 +1,41 @@
+package serve
+
+import (
+	"CTI-Dashboard/scraper/logger"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestListen(t *testing.T) {
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") })
+	if _, err := Listen("Test", handler, "256.0.0.1:0", "", ""); err == nil {
+		t.Error("listened on an invalid address")
+	}
+
+	server, err := Listen("Test", handler, "127.0.0.1:0", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.Get("http://" + server.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("got %q", body)
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := http.Get("http://" + server.Addr); err == nil {
+		t.Error("server still answers after shutdown")
+	}
+}
@@ -0,0 +1,191 @@
+package session
+
+import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/vault"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// storedCookie is a cookie together with the URL that set it, which is all
+// cookiejar needs to replay it after a restart.
+type storedCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// Jar is a forum's cookie jar. It behaves like net/http/cookiejar and also
+// remembers every cookie it was given so the session can be persisted.
+type Jar struct {
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	cookies map[string]storedCookie
+}
+
+func newJar() *Jar {
+	jar, _ := cookiejar.New(nil)
+	return &Jar{jar: jar, cookies: make(map[string]storedCookie)}
+}
+
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar.SetCookies(u, cookies)
+	now := time.Now()
+	for _, c := range cookies {
+		key := u.Host + "|" + c.Domain + "|" + c.Path + "|" + c.Name
+		if c.MaxAge < 0 || (!c.Expires.IsZero() && c.Expires.Before(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = storedCookie{URL: u.String(), Cookie: c}
+	}
+}
+
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// All returns every cookie the jar holds with the URL that set it.
+func (j *Jar) All() map[*url.URL][]*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	all := make(map[*url.URL][]*http.Cookie)
+	byURL := make(map[string]*url.URL)
+	for _, stored := range j.cookies {
+		u, ok := byURL[stored.URL]
+		if !ok {
+			parsed, err := url.Parse(stored.URL)
+			if err != nil {
+				continue
+			}
+			u = parsed
+			byURL[stored.URL] = u
+		}
+		all[u] = append(all[u], stored.Cookie)
+	}
+	return all
+}
+
+func (j *Jar) marshal() ([]byte, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	stored := make([]storedCookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		stored = append(stored, c)
+	}
+	return json.Marshal(stored)
+}
+
+func (j *Jar) load(data []byte) error {
+	var stored []storedCookie
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+	for _, c := range stored {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{c.Cookie})
+	}
+	return nil
+}
+
+// Store hands out one persistent cookie jar per forum. Jars are saved sealed
+// with the vault key, so sessions are only restored while the vault is
+// unlocked.
+type Store struct {
+	db    *sql.DB
+	vault *vault.Vault
+	mu    sync.Mutex
+	jars  map[string]*Jar
+	// loaded records the forums whose saved session was restored.
+	loaded map[string]bool
+}
+
+func NewStore(db *sql.DB, v *vault.Vault) *Store {
+	return &Store{
+		db:     db,
+		vault:  v,
+		jars:   make(map[string]*Jar),
+		loaded: make(map[string]bool),
+	}
+}
+
+// Jar returns the forum's cookie jar, restoring the saved session the first
+// time it is asked for with the vault unlocked.
+func (s *Store) Jar(forumID string) *Jar {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jar, ok := s.jars[forumID]
+	if !ok {
+		jar = newJar()
+		s.jars[forumID] = jar
+	}
+	if !s.loaded[forumID] && !s.vault.Locked() {
+		s.loaded[forumID] = true
+		if err := s.restore(forumID, jar); err != nil {
+			logger.Error("Could not restore forum session", "error", err, "forum_id", forumID)
+		}
+	}
+	return jar
+}
+
+func (s *Store) restore(forumID string, jar *Jar) error {
+	var sealed []byte
+	err := s.db.QueryRow(`SELECT cookies FROM forum_sessions WHERE forum_id = ?`, forumID).Scan(&sealed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := s.vault.Open(sealed, "session/"+forumID)
+	if err != nil {
+		return err
+	}
+	return jar.load(data)
+}
+
+// Save persists the forum's cookie jar.
+func (s *Store) Save(forumID string) error {
+	data, err := s.Jar(forumID).marshal()
+	if err != nil {
+		return err
+	}
+	sealed, err := s.vault.Seal(data, "session/"+forumID)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO forum_sessions (forum_id, cookies, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(forum_id) DO UPDATE SET cookies = excluded.cookies, updated_at = excluded.updated_at`,
+		forumID, sealed, stored.Now())
+	if err != nil {
+		logger.Error("Could not save forum session", "error", err, "forum_id", forumID)
+		return err
+	}
+	return nil
+}
+
+// Clear drops the forum's session, in memory and on disk.
+func (s *Store) Clear(forumID string) error {
+	s.mu.Lock()
+	s.jars[forumID] = newJar()
+	s.loaded[forumID] = true
+	s.mu.Unlock()
+	_, err := s.db.Exec(`DELETE FROM forum_sessions WHERE forum_id = ?`, forumID)
+	if err != nil {
+		logger.Error("Could not delete forum session", "error", err, "forum_id", forumID)
+	}
+	return err
+}
@@ -0,0 +1,186 @@
+package session
+
+import (
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/vault"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	EngineXenForo = "XenForo"
+	EnginePhpBB   = "phpBB"
+)
+
+var (
+	ErrUnsupportedEngine = errors.New("no login flow for this forum engine")
+	ErrLoginFailed       = errors.New("login was rejected")
+)
+
+// flow describes how to log in to one forum engine. CSRF tokens need no
+// special handling: every hidden input of the login form is posted back.
+type flow struct {
+	// loginLink finds the link to the login page on any page of the forum.
+	loginLink string
+	// loginPath is used when no login link is found, relative to the page.
+	loginPath string
+	form      string
+	userField string
+	passField string
+	extra     url.Values
+	loggedOut func(doc *goquery.Document) bool
+}
+
+var flows = map[string]flow{
+	EngineXenForo: {
+		loginLink: `a[href*="login/"]`,
+		loginPath: "/login/",
+		form:      `form[action*="login/login"]`,
+		userField: "login",
+		passField: "password",
+		extra:     url.Values{"remember": {"1"}},
+		loggedOut: func(doc *goquery.Document) bool {
+			return doc.Find(`html[data-logged-in="false"]`).Length() > 0
+		},
+	},
+	EnginePhpBB: {
+		loginLink: `a[href*="mode=login"]`,
+		loginPath: "ucp.php?mode=login",
+		form:      `form#login`,
+		userField: "username",
+		passField: "password",
+		extra:     url.Values{"autologin": {"on"}, "login": {"Login"}},
+		loggedOut: func(doc *goquery.Document) bool {
+			return doc.Find(`a[href*="mode=logout"]`).Length() == 0
+		},
+	},
+}
+
+// DetectEngine names the forum software that rendered the page, or returns
+// an empty string.
+func DetectEngine(doc *goquery.Document) string {
+	if doc.Find(`html#XF`).Length() > 0 {
+		return EngineXenForo
+	}
+	if doc.Find(`body#phpbb`).Length() > 0 {
+		return EnginePhpBB
+	}
+	return ""
+}
+
+// LoggedOut reports whether body is a page of a supported engine shown to a
+// visitor who is not logged in.
+func LoggedOut(body []byte) bool {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	f, ok := flows[DetectEngine(doc)]
+	return ok && f.loggedOut(doc)
+}
+
+// Login signs in to the forum that serves pageURL. client must carry the
+// forum's cookie jar, which holds the session afterwards.
+func Login(client *http.Client, pageURL string, cred vault.Credential) error {
+	page, doc, err := get(client, pageURL)
+	if err != nil {
+		return err
+	}
+	engine := DetectEngine(doc)
+	f, ok := flows[engine]
+	if !ok {
+		return ErrUnsupportedEngine
+	}
+	if !f.loggedOut(doc) {
+		return nil
+	}
+
+	loginURL, err := page.Parse(f.loginPath)
+	if err != nil {
+		return err
+	}
+	if href, ok := doc.Find(f.loginLink).First().Attr("href"); ok {
+		if u, err := page.Parse(href); err == nil {
+			loginURL = u
+		}
+	}
+
+	loginPage, loginDoc, err := get(client, loginURL.String())
+	if err != nil {
+		return err
+	}
+	form := loginDoc.Find(f.form).First()
+	if form.Length() == 0 {
+		return fmt.Errorf("no %s login form found on %s", engine, loginPage)
+	}
+
+	fields := url.Values{}
+	form.Find(`input[type="hidden"]`).Each(func(_ int, input *goquery.Selection) {
+		if name, ok := input.Attr("name"); ok {
+			fields.Set(name, input.AttrOr("value", ""))
+		}
+	})
+	// XenForo 2 keeps the CSRF token on the html element as well.
+	if engine == EngineXenForo && fields.Get("_xfToken") == "" {
+		if token, ok := loginDoc.Find("html").Attr("data-csrf"); ok {
+			fields.Set("_xfToken", token)
+		}
+	}
+	for name, values := range f.extra {
+		fields[name] = values
+	}
+	fields.Set(f.userField, cred.Username)
+	fields.Set(f.passField, cred.Password)
+
+	action, err := loginPage.Parse(form.AttrOr("action", loginPage.String()))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, action.String(), strings.NewReader(fields.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Referer", loginPage.String())
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode >= 400 {
+		return fmt.Errorf("%w: status %s", ErrLoginFailed, response.Status)
+	}
+	if LoggedOut(body) {
+		return ErrLoginFailed
+	}
+	logger.Info("Logged in to forum", "engine", engine, "url", pageURL)
+	return nil
+}
+
+func get(client *http.Client, pageURL string) (*url.URL, *goquery.Document, error) {
+	response, err := client.Get(pageURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("request failed with status: %s", response.Status)
+	}
+	doc, err := goquery.NewDocumentFromReader(response.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Relative links resolve against the final URL after redirects.
+	return response.Request.URL, doc, nil
+}
@@ -0,0 +1,177 @@
+package session
+
+import (
+	"CTI-Dashboard/db/migrations"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/vault"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setup(t *testing.T) (*sql.DB, *vault.Vault) {
+	t.Helper()
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "session.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Apply(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO forums (forum_id, forum_name, forum_url) VALUES ('f1', 'Exploit Bazaar', 'http://bazaar.onion/')`); err != nil {
+		t.Fatal(err)
+	}
+	v := vault.New(db)
+	if err := v.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	return db, v
+}
+
+func cookie(jar *Jar, forum *url.URL) string {
+	for _, c := range jar.Cookies(forum) {
+		if c.Name == "xf_session" {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func TestStore(t *testing.T) {
+	db, v := setup(t)
+	forum, _ := url.Parse("http://bazaar.onion/")
+	store := NewStore(db, v)
+	store.Jar("f1").SetCookies(forum, []*http.Cookie{{Name: "xf_session", Value: "abc", Path: "/"}})
+	if err := store.Save("f1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart restores the session once the vault is unlocked.
+	v.Lock()
+	restarted := NewStore(db, v)
+	if got := cookie(restarted.Jar("f1"), forum); got != "" {
+		t.Errorf("restored %q with the vault locked", got)
+	}
+	if err := v.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cookie(restarted.Jar("f1"), forum); got != "abc" {
+		t.Errorf("restored %q, want abc", got)
+	}
+
+	// A session sealed for one forum does not restore as another's, and a
+	// tampered one not at all.
+	if _, err := db.Exec(`INSERT INTO forums (forum_id, forum_name, forum_url) VALUES ('f2', 'Carder Hub', 'http://carder.onion/')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO forum_sessions (forum_id, cookies, updated_at) SELECT 'f2', cookies, updated_at FROM forum_sessions WHERE forum_id = 'f1'`); err != nil {
+		t.Fatal(err)
+	}
+	if got := cookie(NewStore(db, v).Jar("f2"), forum); got != "" {
+		t.Errorf("restored %q from another forum's session", got)
+	}
+	var sealed []byte
+	if err := db.QueryRow(`SELECT cookies FROM forum_sessions WHERE forum_id = 'f1'`).Scan(&sealed); err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)/2] ^= 1
+	if _, err := db.Exec(`UPDATE forum_sessions SET cookies = ? WHERE forum_id = 'f1'`, sealed); err != nil {
+		t.Fatal(err)
+	}
+	if got := cookie(NewStore(db, v).Jar("f1"), forum); got != "" {
+		t.Errorf("restored %q from a tampered session", got)
+	}
+
+	if err := restarted.Clear("f1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cookie(restarted.Jar("f1"), forum); got != "" {
+		t.Errorf("cleared session still holds %q", got)
+	}
+}
+
+// xenForo serves a XenForo board that accepts analyst/hunter2 and checks the
+// CSRF token of the login form.
+func xenForo(t *testing.T) *httptest.Server {
+	t.Helper()
+	page := func(w http.ResponseWriter, r *http.Request) {
+		loggedIn := "false"
+		if c, err := r.Cookie("xf_user"); err == nil && c.Value == "analyst" {
+			loggedIn = "true"
+		}
+		fmt.Fprintf(w, `<html id="XF" data-logged-in="%s" data-csrf="token123"><body><a href="/login/">Log in</a></body></html>`, loggedIn)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", page)
+	mux.HandleFunc("/login/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html id="XF" data-logged-in="false"><body><form action="/login/login" method="post">
+			<input type="hidden" name="_xfToken" value="token123"><input name="login"><input name="password" type="password">
+			</form></body></html>`)
+	})
+	mux.HandleFunc("/login/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("_xfToken") != "token123" {
+			http.Error(w, "Security error", http.StatusBadRequest)
+			return
+		}
+		if r.PostFormValue("login") == "analyst" && r.PostFormValue("password") == "hunter2" && r.PostFormValue("remember") == "1" {
+			http.SetCookie(w, &http.Cookie{Name: "xf_user", Value: "analyst", Path: "/"})
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		page(w, r)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLogin(t *testing.T) {
+	server := xenForo(t)
+	tests := []struct {
+		cred vault.Credential
+		want error
+	}{
+		{vault.Credential{Username: "analyst", Password: "hunter2"}, nil},
+		{vault.Credential{Username: "analyst", Password: "wrong"}, ErrLoginFailed},
+	}
+	for _, test := range tests {
+		jar := newJar()
+		client := &http.Client{Jar: jar}
+		err := Login(client, server.URL+"/forums/market/", test.cred)
+		if !errors.Is(err, test.want) {
+			t.Errorf("Login(%s) = %v, want %v", test.cred.Password, err, test.want)
+		}
+		forum, _ := url.Parse(server.URL)
+		if loggedIn := len(jar.Cookies(forum)) > 0; loggedIn != (test.want == nil) {
+			t.Errorf("Login(%s) left the session cookie set: %v", test.cred.Password, loggedIn)
+		}
+	}
+
+	// Already logged in: nothing is posted.
+	jar := newJar()
+	forum, _ := url.Parse(server.URL)
+	jar.SetCookies(forum, []*http.Cookie{{Name: "xf_user", Value: "analyst", Path: "/"}})
+	if err := Login(&http.Client{Jar: jar}, server.URL, vault.Credential{}); err != nil {
+		t.Errorf("Login when logged in: %v", err)
+	}
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>Welcome</body></html>`)
+	}))
+	defer plain.Close()
+	if err := Login(plain.Client(), plain.URL, vault.Credential{}); !errors.Is(err, ErrUnsupportedEngine) {
+		t.Errorf("Login on an unknown engine: %v, want ErrUnsupportedEngine", err)
+	}
+}
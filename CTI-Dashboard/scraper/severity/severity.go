@@ -51,26 +51,53 @@ var keywordSets = map[SeverityLevel][]string{
 	},
 }
 
-func AssessSeverity(postBody io.Reader, db *sql.DB, thread_url string) error {
+// levels are checked from the most severe down.
+var levels = []SeverityLevel{High, Medium, Low}
+
+// Assess returns the highest severity whose keywords appear in a post of the
+// thread page, or an empty level when none do.
+func Assess(postBody io.Reader) (SeverityLevel, error) {
 	doc, err := goquery.NewDocumentFromReader(postBody)
 	if err != nil {
-		return err
+		return "", err
 	}
-	severity := doc.Find("div.bbWrapper").Each(func(i int, s *goquery.Selection) {
+	var found SeverityLevel
+	doc.Find("div.bbWrapper").EachWithBreak(func(i int, s *goquery.Selection) bool {
 		content := strings.ToLower(s.Text())
-		for severity, keywords := range keywordSets {
-			for _, keyword := range keywords {
-				if strings.Contains(content, keyword) {
-					_, err := db.Exec(`UPDATE posts SET severity_level = ? WHERE thread_url = ?`, severity, thread_url)
-					if err != nil {
-						logger.Error("Could not insert severity level to the database", "error", err)
-						return
-					}
-					return
-				}
+		// Only levels above the one found so far can change it.
+		for _, level := range levels {
+			if level == found {
+				break
+			}
+			if mentions(content, keywordSets[level]) {
+				found = level
+				break
 			}
 		}
+		return found != High
 	})
-	logger.Info("Severity: ", &severity)
+	return found, nil
+}
+
+func mentions(content string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(content, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func AssessSeverity(postBody io.Reader, db *sql.DB, thread_url string) error {
+	severity, err := Assess(postBody)
+	if err != nil || severity == "" {
+		return err
+	}
+	_, err = db.Exec(`UPDATE posts SET severity_level = ? WHERE thread_url = ?`, severity, thread_url)
+	if err != nil {
+		logger.Error("Could not insert severity level to the database", "error", err)
+		return err
+	}
+	logger.Info("Severity: ", "level", severity, "thread_url", thread_url)
 	return nil
 }
@@ -0,0 +1,101 @@
+package snapshots
+
+import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const columns = `snapshot_id, COALESCE(forum_id, ''), COALESCE(post_id, ''), target_url, kind, COALESCE(run_id, ''), fetched_at,
+	html_sha256, html_path, html_size, COALESCE(screenshot_sha256, ''), COALESCE(screenshot_path, ''), screenshot_size,
+	COALESCE(mhtml_sha256, ''), COALESCE(mhtml_path, ''), COALESCE(pdf_sha256, ''), COALESCE(pdf_path, '')`
+
+// Record stores a snapshot and returns it with its ID and fetch time set.
+func Record(db *sql.DB, snapshot models.Snapshot) (models.Snapshot, error) {
+	snapshot.SnapshotID = uuid.New().String()
+	fetchedAt := stored.Now()
+	_, err := db.Exec(`INSERT INTO snapshots (snapshot_id, forum_id, post_id, target_url, kind, run_id, fetched_at,
+		html_sha256, html_path, html_size, screenshot_sha256, screenshot_path, screenshot_size,
+		mhtml_sha256, mhtml_path, pdf_sha256, pdf_path)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		snapshot.SnapshotID, stored.Nullable(snapshot.ForumID), stored.Nullable(snapshot.PostID), snapshot.TargetURL, snapshot.Kind,
+		stored.Nullable(snapshot.RunID), fetchedAt, snapshot.HTMLSHA256, snapshot.HTMLPath, snapshot.HTMLSize,
+		stored.Nullable(snapshot.ScreenshotSHA256), stored.Nullable(snapshot.ScreenshotPath), snapshot.ScreenshotSize,
+		stored.Nullable(snapshot.MHTMLSHA256), stored.Nullable(snapshot.MHTMLPath), stored.Nullable(snapshot.PDFSHA256), stored.Nullable(snapshot.PDFPath),
+	)
+	if err != nil {
+		logger.Error("Could not record snapshot", "error", err, "target", snapshot.TargetURL)
+		return snapshot, err
+	}
+	snapshot.FetchedAt = fetchedAt
+	return snapshot, nil
+}
+
+// List returns the snapshots of a forum, its posts included, newest first.
+func List(db *sql.DB, forumID string, limit int) ([]models.Snapshot, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	return query(db, `SELECT `+columns+` FROM snapshots WHERE forum_id = ? ORDER BY fetched_at DESC LIMIT ?`, forumID, limit)
+}
+
+// ListPost returns the snapshots of one post, newest first.
+func ListPost(db *sql.DB, postID string) ([]models.Snapshot, error) {
+	return query(db, `SELECT `+columns+` FROM snapshots WHERE post_id = ? ORDER BY fetched_at DESC`, postID)
+}
+
+// ListURL returns the snapshots of one URL, newest first.
+func ListURL(db *sql.DB, targetURL string) ([]models.Snapshot, error) {
+	return query(db, `SELECT `+columns+` FROM snapshots WHERE target_url = ? ORDER BY fetched_at DESC`, targetURL)
+}
+
+// Latest returns the newest snapshot of a URL, sql.ErrNoRows if there is none.
+func Latest(db *sql.DB, targetURL string) (models.Snapshot, error) {
+	return one(db, `SELECT `+columns+` FROM snapshots WHERE target_url = ? ORDER BY fetched_at DESC LIMIT 1`, targetURL)
+}
+
+func Get(db *sql.DB, snapshotID string) (models.Snapshot, error) {
+	return one(db, `SELECT `+columns+` FROM snapshots WHERE snapshot_id = ?`, snapshotID)
+}
+
+func one(db *sql.DB, statement string, args ...any) (models.Snapshot, error) {
+	list, err := query(db, statement, args...)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+	if len(list) == 0 {
+		return models.Snapshot{}, sql.ErrNoRows
+	}
+	return list[0], nil
+}
+
+func query(db *sql.DB, query string, args ...any) ([]models.Snapshot, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		logger.Error("Could not query snapshots", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.Snapshot
+	for rows.Next() {
+		var s models.Snapshot
+		err := rows.Scan(&s.SnapshotID, &s.ForumID, &s.PostID, &s.TargetURL, &s.Kind, &s.RunID, &s.FetchedAt,
+			&s.HTMLSHA256, &s.HTMLPath, &s.HTMLSize, &s.ScreenshotSHA256, &s.ScreenshotPath, &s.ScreenshotSize,
+			&s.MHTMLSHA256, &s.MHTMLPath, &s.PDFSHA256, &s.PDFPath)
+		if err != nil {
+			logger.Error("Could not scan snapshot row", "error", err)
+			continue
+		}
+		list = append(list, s)
+	}
+	if err = rows.Err(); err != nil {
+		logger.Error("Error during rows iteration", "error", err)
+		return nil, err
+	}
+	return list, nil
+}
@@ -0,0 +1,510 @@
+package taxii
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/export"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/serve"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// MediaType is the media type of every TAXII 2.1 response.
+const MediaType = "application/taxii+json;version=2.1"
+
+const stixMediaType = "application/stix+json;version=2.1"
+
+const (
+	// APIRoot is the path of the only API root.
+	APIRoot = "/api/"
+	// DefaultLimit and MaxLimit bound the objects of one page.
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Collections besides the one per forum. Their IDs are CollectionID of the
+// name.
+const (
+	CollectionHighSeverity = "high-severity"
+	CollectionIndicators   = "indicators"
+)
+
+// namespace makes collection IDs stable across restarts.
+var namespace = uuid.MustParse("3b0a4c55-8d0e-4f43-9a6d-5c1e7f2b9d11")
+
+// CollectionID is the UUID of a fixed collection, or of the collection of a
+// forum when called with ForumCollection of its ID.
+func CollectionID(name string) string {
+	return uuid.NewSHA1(namespace, []byte(name)).String()
+}
+
+func ForumCollection(forumID string) string {
+	return "forum:" + forumID
+}
+
+const timeLayout = "2006-01-02T15:04:05.000000Z"
+
+// CacheTTL is how long the objects of a collection are kept once built, so
+// a client paging through it does not have every page build it again.
+// Objects scraped in the meantime show up when it expires.
+const CacheTTL = time.Minute
+
+// Server serves the scraped data as TAXII 2.1 collections. Every request
+// needs HTTP basic auth with one of the user names and API keys it was
+// created with.
+type Server struct {
+	db   *sql.DB
+	keys map[string][32]byte
+	// TLP marks the exported objects, amber when empty.
+	TLP string
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+// cached is the objects of a collection as built at a time.
+type cached struct {
+	entries []export.Entry
+	built   time.Time
+}
+
+// NewServer returns a server for the database. keys maps basic-auth user
+// names to API keys; a server without keys refuses every request.
+func NewServer(db *sql.DB, keys map[string]string) *Server {
+	s := &Server{db: db, keys: make(map[string][32]byte), cache: make(map[string]cached)}
+	for name, key := range keys {
+		s.keys[name] = sha256.Sum256([]byte(key))
+	}
+	return s
+}
+
+// Collection is a TAXII collection resource.
+type Collection struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	CanRead     bool     `json:"can_read"`
+	CanWrite    bool     `json:"can_write"`
+	MediaTypes  []string `json:"media_types"`
+	filter      models.ExportFilter
+	indicators  bool
+}
+
+type envelope struct {
+	More    bool            `json:"more"`
+	Next    string          `json:"next,omitempty"`
+	Objects []export.Object `json:"objects,omitempty"`
+}
+
+type manifestRecord struct {
+	ID        string `json:"id"`
+	DateAdded string `json:"date_added"`
+	Version   string `json:"version"`
+	MediaType string `json:"media_type"`
+}
+
+type manifest struct {
+	More    bool             `json:"more"`
+	Next    string           `json:"next,omitempty"`
+	Objects []manifestRecord `json:"objects,omitempty"`
+}
+
+type taxiiError struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	HTTPStatus  string `json:"http_status"`
+}
+
+// Listen serves the TAXII API on address in the background, over TLS when
+// a certificate and key file are given. Shut the returned server down to
+// stop it.
+func (s *Server) Listen(address string, certFile string, keyFile string) (*http.Server, error) {
+	return serve.Listen("TAXII", s, address, certFile, keyFile)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="CTI-Dashboard TAXII"`)
+		writeError(w, http.StatusUnauthorized, "Authentication required", "")
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed", "collections are read-only")
+		return
+	}
+	if !accepts(r.Header.Get("Accept")) {
+		writeError(w, http.StatusNotAcceptable, "Not acceptable", "use Accept: "+MediaType)
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == "/taxii2/":
+		s.discovery(w)
+	case path == APIRoot:
+		s.apiRoot(w)
+	case path == APIRoot+"collections/":
+		s.collections(w)
+	case strings.HasPrefix(path, APIRoot+"collections/"):
+		s.collection(w, r, strings.Split(strings.TrimSuffix(strings.TrimPrefix(path, APIRoot+"collections/"), "/"), "/"))
+	default:
+		writeError(w, http.StatusNotFound, "Not found", "")
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	name, key, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	want, ok := s.keys[name]
+	if !ok {
+		return false
+	}
+	got := sha256.Sum256([]byte(key))
+	return subtle.ConstantTimeCompare(got[:], want[:]) == 1
+}
+
+// accepts reports whether the client takes TAXII 2.1 responses. A missing
+// Accept header, or one allowing anything, does.
+func accepts(header string) bool {
+	if header == "" {
+		return true
+	}
+	for _, part := range strings.Split(header, ",") {
+		media := strings.ReplaceAll(strings.TrimSpace(strings.Split(part, ";q=")[0]), " ", "")
+		switch media {
+		case "*/*", "application/*", "application/taxii+json", MediaType:
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) discovery(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"title":       "CTI-Dashboard",
+		"description": "Forums, posts and indicators collected by CTI-Dashboard",
+		"default":     APIRoot,
+		"api_roots":   []string{APIRoot},
+	})
+}
+
+func (s *Server) apiRoot(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"title":              "CTI-Dashboard",
+		"versions":           []string{MediaType},
+		"max_content_length": 0,
+	})
+}
+
+func (s *Server) collections(w http.ResponseWriter) {
+	list, err := s.list()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Could not list collections", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"collections": list})
+}
+
+// list returns the fixed collections followed by one per forum.
+func (s *Server) list() ([]Collection, error) {
+	list := []Collection{
+		collection(CollectionID(CollectionHighSeverity), "High-severity posts", "Every post assessed as high severity, with its forum, author and indicators",
+			models.ExportFilter{Severities: []string{"high"}}, false),
+		collection(CollectionID(CollectionIndicators), "Indicators", "Indicators found in the content of every scraped post", models.ExportFilter{}, true),
+	}
+	rows, err := s.db.Query(`SELECT forum_id, forum_name FROM forums ORDER BY forum_name`)
+	if err != nil {
+		logger.Error("Could not query forums for TAXII collections", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			logger.Error("Could not scan forum row", "error", err)
+			continue
+		}
+		list = append(list, collection(CollectionID(ForumCollection(id)), name, "Posts scraped from "+name, models.ExportFilter{ForumIDs: []string{id}}, false))
+	}
+	return list, rows.Err()
+}
+
+func collection(id string, title string, description string, filter models.ExportFilter, indicators bool) Collection {
+	return Collection{
+		ID:          id,
+		Title:       title,
+		Description: description,
+		CanRead:     true,
+		MediaTypes:  []string{stixMediaType},
+		filter:      filter,
+		indicators:  indicators,
+	}
+}
+
+func (s *Server) find(id string) (Collection, bool, error) {
+	list, err := s.list()
+	if err != nil {
+		return Collection{}, false, err
+	}
+	for _, c := range list {
+		if c.ID == id {
+			return c, true, nil
+		}
+	}
+	return Collection{}, false, nil
+}
+
+// collection serves /collections/{id}/ and the resources below it.
+func (s *Server) collection(w http.ResponseWriter, r *http.Request, parts []string) {
+	c, ok, err := s.find(parts[0])
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Could not load collection", err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "Unknown collection", parts[0])
+		return
+	}
+	switch {
+	case len(parts) == 1:
+		writeJSON(w, http.StatusOK, c)
+	case len(parts) == 2 && parts[1] == "objects":
+		s.objects(w, r, c, "")
+	case len(parts) == 3 && parts[1] == "objects":
+		s.objects(w, r, c, parts[2])
+	case len(parts) == 2 && parts[1] == "manifest":
+		s.manifest(w, r, c)
+	default:
+		writeError(w, http.StatusNotFound, "Not found", "")
+	}
+}
+
+func (s *Server) objects(w http.ResponseWriter, r *http.Request, c Collection, objectID string) {
+	page, more, next, err := s.page(r, c, objectID)
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	if objectID != "" && len(page) == 0 {
+		writeError(w, http.StatusNotFound, "Unknown object", objectID)
+		return
+	}
+	body := envelope{More: more, Next: next}
+	for _, entry := range page {
+		body.Objects = append(body.Objects, entry.Object)
+	}
+	dateHeaders(w, page)
+	writeJSON(w, http.StatusOK, body)
+}
+
+func (s *Server) manifest(w http.ResponseWriter, r *http.Request, c Collection) {
+	page, more, next, err := s.page(r, c, "")
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	body := manifest{More: more, Next: next}
+	for _, entry := range page {
+		version := entry.Object.Modified
+		if version == "" {
+			version = entry.Object.Created
+		}
+		if version == "" {
+			version = entry.Added.UTC().Format(timeLayout)
+		}
+		body.Objects = append(body.Objects, manifestRecord{
+			ID:        entry.Object.ID,
+			DateAdded: entry.Added.UTC().Format(timeLayout),
+			Version:   version,
+			MediaType: stixMediaType,
+		})
+	}
+	dateHeaders(w, page)
+	writeJSON(w, http.StatusOK, body)
+}
+
+// errQuery marks a bad filter or pagination parameter.
+var errQuery = errors.New("bad query parameter")
+
+// page returns the entries of the collection the request's added_after,
+// match[id], match[type], limit and next parameters select, whether there
+// are more and the next token to get them.
+func (s *Server) page(r *http.Request, c Collection, objectID string) ([]export.Entry, bool, string, error) {
+	query := r.URL.Query()
+	entries, err := s.entries(c)
+	if err != nil {
+		return nil, false, "", err
+	}
+
+	limit := DefaultLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, false, "", fmt.Errorf("%w: limit %q", errQuery, value)
+		}
+		limit = min(n, MaxLimit)
+	}
+	var after time.Time
+	if value := query.Get("added_after"); value != "" {
+		if after, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			return nil, false, "", fmt.Errorf("%w: added_after %q", errQuery, value)
+		}
+	}
+	var cursor *export.Entry
+	if value := query.Get("next"); value != "" {
+		if cursor, err = decodeNext(value); err != nil {
+			return nil, false, "", err
+		}
+	}
+	ids := matchSet(query.Get("match[id]"))
+	if objectID != "" {
+		ids = map[string]bool{objectID: true}
+	}
+	types := matchSet(query.Get("match[type]"))
+
+	var selected []export.Entry
+	for _, entry := range entries {
+		if !after.IsZero() && !entry.Added.After(after) {
+			continue
+		}
+		if cursor != nil && !later(entry, *cursor) {
+			continue
+		}
+		if ids != nil && !ids[entry.Object.ID] || types != nil && !types[entry.Object.Type] {
+			continue
+		}
+		selected = append(selected, entry)
+	}
+	if len(selected) <= limit {
+		return selected, false, "", nil
+	}
+	selected = selected[:limit]
+	return selected, true, encodeNext(selected[limit-1]), nil
+}
+
+// entries returns the objects of the collection, built at most CacheTTL ago.
+func (s *Server) entries(c Collection) ([]export.Entry, error) {
+	filter := c.filter
+	filter.TLP = s.TLP
+	key := c.ID + " " + filter.TLP
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if hit, ok := s.cache[key]; ok && now.Sub(hit.built) < CacheTTL {
+		return hit.entries, nil
+	}
+	entries, err := export.Entries(s.db, filter)
+	if err != nil {
+		return nil, err
+	}
+	if c.indicators {
+		entries = indicators(entries)
+	}
+	for other, hit := range s.cache {
+		if now.Sub(hit.built) >= CacheTTL {
+			delete(s.cache, other)
+		}
+	}
+	s.cache[key] = cached{entries: entries, built: now}
+	return entries, nil
+}
+
+// indicators keeps the indicator objects of a collection and the marking
+// and identity they refer to.
+func indicators(entries []export.Entry) []export.Entry {
+	var kept []export.Entry
+	for _, entry := range entries {
+		switch entry.Object.Type {
+		case "indicator", "marking-definition", "identity":
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// later reports whether entry comes after the cursor in collection order.
+func later(entry export.Entry, cursor export.Entry) bool {
+	if !entry.Added.Equal(cursor.Added) {
+		return entry.Added.After(cursor.Added)
+	}
+	return entry.Object.ID > cursor.Object.ID
+}
+
+// The next token is the date added and ID of the last object returned, so
+// pages stay consistent while new objects come in.
+func encodeNext(entry export.Entry) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(entry.Added.UTC().Format(time.RFC3339Nano) + "|" + entry.Object.ID))
+}
+
+func decodeNext(value string) (*export.Entry, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: next %q", errQuery, value)
+	}
+	added, id, ok := strings.Cut(string(data), "|")
+	if !ok {
+		return nil, fmt.Errorf("%w: next %q", errQuery, value)
+	}
+	t, err := time.Parse(time.RFC3339Nano, added)
+	if err != nil {
+		return nil, fmt.Errorf("%w: next %q", errQuery, value)
+	}
+	return &export.Entry{Object: export.Object{ID: id}, Added: t}, nil
+}
+
+func matchSet(value string) map[string]bool {
+	if value == "" {
+		return nil
+	}
+	set := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		set[strings.TrimSpace(item)] = true
+	}
+	return set
+}
+
+// dateHeaders sets the headers telling the client the range of dates added
+// in the page.
+func dateHeaders(w http.ResponseWriter, page []export.Entry) {
+	if len(page) == 0 {
+		return
+	}
+	w.Header().Set("X-TAXII-Date-Added-First", page[0].Added.UTC().Format(timeLayout))
+	w.Header().Set("X-TAXII-Date-Added-Last", page[len(page)-1].Added.UTC().Format(timeLayout))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", MediaType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Error("Could not write TAXII response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, title string, description string) {
+	writeJSON(w, status, taxiiError{Title: title, Description: description, HTTPStatus: strconv.Itoa(status)})
+}
+
+func writeQueryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errQuery) {
+		writeError(w, http.StatusBadRequest, "Bad request", err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "Could not load objects", err.Error())
+}
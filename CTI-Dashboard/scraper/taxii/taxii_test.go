@@ -0,0 +1,206 @@
+package taxii
+
+import (
+	"CTI-Dashboard/db/migrations"
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const post = `<html><head><title>Selling access</title></head><body>
+<article class="message" data-author="seller"><div class="bbWrapper">Panel at hxxp://panel[.]example[.]com/login and 203.0.113.50</div></article>
+</body></html>`
+
+// newTestServer serves a database with one forum holding a high and a low
+// severity post. The low one was scraped two days after its link was found.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	if err := logger.Init(filepath.Join(t.TempDir(), "taxii.log")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "taxii.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Apply(db); err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		`INSERT INTO forums (forum_id, forum_name, forum_url, created_at) VALUES ('f1', 'Exploit Bazaar', 'http://bazaar.onion/', '2025-01-01 00:00:00')`,
+		`INSERT INTO posts (post_id, forum_id, thread_url, content, severity_level, created_at) VALUES
+			('p1', 'f1', 'http://bazaar.onion/threads/1/', '` + post + `', 'high', '2025-01-02 00:00:00'),
+			('p2', 'f1', 'http://bazaar.onion/threads/2/', '<p>Looking for a crypter, contact 198.51.100.7</p>', 'low', '2025-01-03 00:00:00')`,
+		`INSERT INTO snapshots (snapshot_id, forum_id, post_id, target_url, kind, fetched_at, html_sha256, html_path) VALUES
+			('s1', 'f1', 'p2', 'http://bazaar.onion/threads/2/', 'post', '2025-01-04 00:00:00.000', 'aa', 'html/aa.html'),
+			('s2', 'f1', 'p2', 'http://bazaar.onion/threads/2/', 'post', '2025-01-05 00:00:00.000', 'bb', 'html/bb.html')`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := httptest.NewServer(NewServer(db, map[string]string{"opencti": "secret"}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, server *httptest.Server, path string, query url.Values, body any) *http.Response {
+	t.Helper()
+	request, err := http.NewRequest(http.MethodGet, server.URL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.SetBasicAuth("opencti", "secret")
+	request.Header.Set("Accept", MediaType)
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", path, response.StatusCode)
+	}
+	if got := response.Header.Get("Content-Type"); got != MediaType {
+		t.Fatalf("GET %s: content type %q", path, got)
+	}
+	if err := json.NewDecoder(response.Body).Decode(body); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestAuthentication(t *testing.T) {
+	server := newTestServer(t)
+	for _, key := range []string{"", "wrong"} {
+		request, _ := http.NewRequest(http.MethodGet, server.URL+"/taxii2/", nil)
+		if key != "" {
+			request.SetBasicAuth("opencti", key)
+		}
+		response, err := server.Client().Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("key %q: status %d, want 401", key, response.StatusCode)
+		}
+	}
+}
+
+func TestCollections(t *testing.T) {
+	server := newTestServer(t)
+	var discovery struct {
+		APIRoots []string `json:"api_roots"`
+	}
+	get(t, server, "/taxii2/", nil, &discovery)
+	if len(discovery.APIRoots) != 1 || discovery.APIRoots[0] != APIRoot {
+		t.Fatalf("api roots %v", discovery.APIRoots)
+	}
+
+	var list struct {
+		Collections []Collection `json:"collections"`
+	}
+	get(t, server, APIRoot+"collections/", nil, &list)
+	want := []string{CollectionID(CollectionHighSeverity), CollectionID(CollectionIndicators), CollectionID(ForumCollection("f1"))}
+	if len(list.Collections) != len(want) {
+		t.Fatalf("got %d collections, want %d", len(list.Collections), len(want))
+	}
+	for i, id := range want {
+		if list.Collections[i].ID != id || !list.Collections[i].CanRead || list.Collections[i].CanWrite {
+			t.Errorf("collection %d: %+v", i, list.Collections[i])
+		}
+	}
+}
+
+type objectsPage struct {
+	More    bool   `json:"more"`
+	Next    string `json:"next"`
+	Objects []struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Name    string `json:"name"`
+		Pattern string `json:"pattern"`
+	} `json:"objects"`
+}
+
+func TestHighSeverityCollection(t *testing.T) {
+	server := newTestServer(t)
+	var page objectsPage
+	get(t, server, APIRoot+"collections/"+CollectionID(CollectionHighSeverity)+"/objects/", nil, &page)
+	reports := 0
+	for _, object := range page.Objects {
+		if object.Type == "report" {
+			reports++
+			if object.Name != "Selling access" {
+				t.Errorf("report name %q", object.Name)
+			}
+		}
+		if object.Type == "indicator" && object.Name == "198.51.100.7" {
+			t.Error("indicator of the low-severity post in the high-severity collection")
+		}
+	}
+	if reports != 1 {
+		t.Errorf("got %d reports, want 1", reports)
+	}
+}
+
+func TestIndicatorsPagination(t *testing.T) {
+	server := newTestServer(t)
+	path := APIRoot + "collections/" + CollectionID(CollectionIndicators) + "/objects/"
+
+	var all objectsPage
+	get(t, server, path, url.Values{"match[type]": {"indicator"}}, &all)
+	patterns := map[string]bool{}
+	for _, object := range all.Objects {
+		patterns[object.Pattern] = true
+	}
+	for _, pattern := range []string{
+		"[url:value = 'http://panel.example.com/login']",
+		"[ipv4-addr:value = '203.0.113.50']",
+		"[ipv4-addr:value = '198.51.100.7']",
+	} {
+		if !patterns[pattern] {
+			t.Errorf("missing indicator %s in %v", pattern, patterns)
+		}
+	}
+
+	// One object per page, following next until more is false.
+	var seen []string
+	query := url.Values{"match[type]": {"indicator"}, "limit": {"1"}}
+	for range 10 {
+		var page objectsPage
+		get(t, server, path, query, &page)
+		for _, object := range page.Objects {
+			seen = append(seen, object.ID)
+		}
+		if !page.More {
+			break
+		}
+		query.Set("next", page.Next)
+	}
+	if len(seen) != len(all.Objects) {
+		t.Fatalf("paged through %d objects, want %d", len(seen), len(all.Objects))
+	}
+
+	// Only the indicator of the later post was added after the first post,
+	// when it was last scraped.
+	var later objectsPage
+	response := get(t, server, path, url.Values{"match[type]": {"indicator"}, "added_after": {"2025-01-02T00:00:00Z"}}, &later)
+	if len(later.Objects) != 1 || later.Objects[0].Pattern != "[ipv4-addr:value = '198.51.100.7']" {
+		t.Fatalf("added_after returned %+v", later.Objects)
+	}
+	if got := response.Header.Get("X-TAXII-Date-Added-Last"); got != "2025-01-05T00:00:00.000000Z" {
+		t.Errorf("X-TAXII-Date-Added-Last %q", got)
+	}
+}
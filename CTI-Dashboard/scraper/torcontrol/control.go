@@ -0,0 +1,316 @@
+package torcontrol
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auth selects how to authenticate to the control port. A password wins over
+// a cookie; with neither set the cookie path announced by PROTOCOLINFO is used.
+type Auth struct {
+	Password   string
+	CookiePath string
+}
+
+type Controller struct {
+	conn   net.Conn
+	reader *textproto.Reader
+	mu     sync.Mutex
+	// timeout bounds every command, so a control port that accepts the
+	// connection but stops answering cannot hang the caller.
+	timeout time.Duration
+}
+
+// Reply is a parsed control-port response. Lines holds the text of every
+// line; Data holds the "+" data blocks keyed by the text before "=".
+type Reply struct {
+	Code  int
+	Lines []string
+	Data  map[string]string
+}
+
+type Bootstrap struct {
+	Progress int
+	Tag      string
+	Summary  string
+	Warning  string
+}
+
+type Circuit struct {
+	ID      string
+	Status  string
+	Path    []string
+	Purpose string
+}
+
+// Dial connects to the control port. timeout bounds the dial and every
+// command sent afterwards.
+func Dial(addr string, timeout time.Duration) (*Controller, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Controller{
+		conn:    conn,
+		reader:  textproto.NewReader(bufio.NewReader(conn)),
+		timeout: timeout,
+	}, nil
+}
+
+// Connect dials the control port and authenticates.
+func Connect(addr string, auth Auth, timeout time.Duration) (*Controller, error) {
+	c, err := Dial(addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Authenticate(auth); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Controller) Close() error {
+	return c.conn.Close()
+}
+
+// Command sends a single command and reads its reply. Any non-2xx reply is
+// returned as an error.
+func (c *Controller) Command(format string, args ...any) (*Reply, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timeout > 0 {
+		if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return nil, err
+		}
+		defer c.conn.SetDeadline(time.Time{})
+	}
+	if _, err := fmt.Fprintf(c.conn, format+"\r\n", args...); err != nil {
+		return nil, err
+	}
+	reply, err := c.readReply()
+	if err != nil {
+		return nil, err
+	}
+	if reply.Code < 200 || reply.Code > 299 {
+		return reply, fmt.Errorf("tor control: %d %s", reply.Code, strings.Join(reply.Lines, " "))
+	}
+	return reply, nil
+}
+
+func (c *Controller) readReply() (*Reply, error) {
+	reply := &Reply{Data: make(map[string]string)}
+	for {
+		line, err := c.reader.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) < 4 {
+			return nil, fmt.Errorf("tor control: malformed reply line %q", line)
+		}
+		code, err := strconv.Atoi(line[:3])
+		if err != nil {
+			return nil, fmt.Errorf("tor control: malformed reply code %q", line)
+		}
+		reply.Code = code
+		text := line[4:]
+		reply.Lines = append(reply.Lines, text)
+
+		switch line[3] {
+		case ' ':
+			return reply, nil
+		case '-':
+		case '+':
+			data, err := c.reader.ReadDotLines()
+			if err != nil {
+				return nil, err
+			}
+			key, _, _ := strings.Cut(text, "=")
+			reply.Data[key] = strings.Join(data, "\n")
+		default:
+			return nil, fmt.Errorf("tor control: malformed reply line %q", line)
+		}
+	}
+}
+
+func (c *Controller) Authenticate(auth Auth) error {
+	if auth.Password != "" {
+		_, err := c.Command("AUTHENTICATE %s", quote(auth.Password))
+		return err
+	}
+
+	reply, err := c.Command("PROTOCOLINFO 1")
+	if err != nil {
+		return err
+	}
+	var methods []string
+	cookiePath := auth.CookiePath
+	for _, line := range reply.Lines {
+		if !strings.HasPrefix(line, "AUTH ") {
+			continue
+		}
+		fields := parseKeyValues(strings.TrimPrefix(line, "AUTH "))
+		methods = strings.Split(fields["METHODS"], ",")
+		if cookiePath == "" {
+			cookiePath = fields["COOKIEFILE"]
+		}
+	}
+
+	for _, method := range methods {
+		if method == "NULL" {
+			_, err := c.Command("AUTHENTICATE")
+			return err
+		}
+	}
+	if cookiePath == "" {
+		return errors.New("tor control: no password set and no cookie file available")
+	}
+	cookie, err := os.ReadFile(cookiePath)
+	if err != nil {
+		return err
+	}
+	_, err = c.Command("AUTHENTICATE %s", strings.ToUpper(hex.EncodeToString(cookie)))
+	return err
+}
+
+// NewIdentity asks Tor to use fresh circuits for new connections. Tor rate
+// limits NEWNYM, so calling it more often than every ~10 seconds is a no-op.
+func (c *Controller) NewIdentity() error {
+	_, err := c.Command("SIGNAL NEWNYM")
+	return err
+}
+
+// GetInfo returns the values of the requested GETINFO keys.
+func (c *Controller) GetInfo(keys ...string) (map[string]string, error) {
+	reply, err := c.Command("GETINFO %s", strings.Join(keys, " "))
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for key, data := range reply.Data {
+		values[key] = data
+	}
+	for _, line := range reply.Lines {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if _, isData := reply.Data[key]; !isData {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (c *Controller) Bootstrap() (Bootstrap, error) {
+	values, err := c.GetInfo("status/bootstrap-phase")
+	if err != nil {
+		return Bootstrap{}, err
+	}
+	return ParseBootstrap(values["status/bootstrap-phase"]), nil
+}
+
+func (c *Controller) Circuits() ([]Circuit, error) {
+	values, err := c.GetInfo("circuit-status")
+	if err != nil {
+		return nil, err
+	}
+	return ParseCircuits(values["circuit-status"]), nil
+}
+
+// ParseBootstrap parses a status/bootstrap-phase value such as
+// `NOTICE BOOTSTRAP PROGRESS=100 TAG=done SUMMARY="Done"`.
+func ParseBootstrap(value string) Bootstrap {
+	fields := parseKeyValues(value)
+	progress, _ := strconv.Atoi(fields["PROGRESS"])
+	return Bootstrap{
+		Progress: progress,
+		Tag:      fields["TAG"],
+		Summary:  fields["SUMMARY"],
+		Warning:  fields["WARNING"],
+	}
+}
+
+// ParseCircuits parses the lines of GETINFO circuit-status.
+func ParseCircuits(value string) []Circuit {
+	var circuits []Circuit
+	for _, line := range strings.Split(value, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			continue
+		}
+		circuit := Circuit{ID: parts[0], Status: parts[1]}
+		rest := parts[2:]
+		if len(rest) > 0 && !strings.Contains(rest[0], "=") {
+			circuit.Path = strings.Split(rest[0], ",")
+			rest = rest[1:]
+		}
+		fields := parseKeyValues(strings.Join(rest, " "))
+		circuit.Purpose = fields["PURPOSE"]
+		circuits = append(circuits, circuit)
+	}
+	return circuits
+}
+
+// parseKeyValues splits `KEY=value KEY="quoted value"` pairs. Words without
+// "=" are ignored.
+func parseKeyValues(s string) map[string]string {
+	values := make(map[string]string)
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ")
+		end := strings.IndexAny(s, " =")
+		if end == -1 {
+			break
+		}
+		if s[end] == ' ' {
+			s = s[end:]
+			continue
+		}
+		key := s[:end]
+		s = s[end+1:]
+		if strings.HasPrefix(s, `"`) {
+			value, rest := unquote(s)
+			values[key] = value
+			s = rest
+			continue
+		}
+		value, rest, _ := strings.Cut(s, " ")
+		values[key] = value
+		s = rest
+	}
+	return values
+}
+
+// unquote reads a quoted string from the start of s and returns it with the
+// remainder of s.
+func unquote(s string) (string, string) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), ""
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,177 @@
+package torcontrol
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTor is a minimal control port that answers the commands used by the
+// dashboard and records everything it receives.
+type fakeTor struct {
+	listener net.Listener
+	password string
+	cookie   string
+	commands chan string
+}
+
+func startFakeTor(t *testing.T, password string, cookiePath string) *fakeTor {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeTor{listener: listener, password: password, cookie: cookiePath, commands: make(chan string, 16)}
+	go f.serve()
+	t.Cleanup(func() { listener.Close() })
+	return f
+}
+
+func (f *fakeTor) serve() {
+	conn, err := f.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		f.commands <- line
+		switch {
+		case line == "PROTOCOLINFO 1":
+			conn.Write([]byte("250-PROTOCOLINFO 1\r\n250-AUTH METHODS=COOKIE,SAFECOOKIE COOKIEFILE=\"" + f.cookie + "\"\r\n250-VERSION Tor=\"0.4.8.9\"\r\n250 OK\r\n"))
+		case strings.HasPrefix(line, "AUTHENTICATE "):
+			arg := strings.TrimPrefix(line, "AUTHENTICATE ")
+			if (f.password != "" && arg == `"`+f.password+`"`) || (f.password == "" && arg == "0102FF") {
+				authenticated = true
+				conn.Write([]byte("250 OK\r\n"))
+			} else {
+				conn.Write([]byte("515 Authentication failed\r\n"))
+			}
+		case !authenticated:
+			conn.Write([]byte("514 Authentication required.\r\n"))
+		case line == "SIGNAL NEWNYM":
+			conn.Write([]byte("250 OK\r\n"))
+		case line == "GETINFO status/bootstrap-phase":
+			conn.Write([]byte("250-status/bootstrap-phase=NOTICE BOOTSTRAP PROGRESS=85 TAG=ap_conn_done SUMMARY=\"Connected to a relay to build circuits\"\r\n250 OK\r\n"))
+		case line == "GETINFO circuit-status":
+			conn.Write([]byte("250+circuit-status=\r\n" +
+				"1 BUILT $AAAA~guard,$BBBB~middle,$CCCC~exit BUILD_FLAGS=NEED_CAPACITY PURPOSE=GENERAL\r\n" +
+				"2 LAUNCHED PURPOSE=HS_CLIENT_REND\r\n" +
+				".\r\n250 OK\r\n"))
+		default:
+			conn.Write([]byte("510 Unrecognized command\r\n"))
+		}
+	}
+}
+
+func TestPasswordAuthAndNewIdentity(t *testing.T) {
+	// The fake compares the raw argument, so it expects the quoted form.
+	tor := startFakeTor(t, `s3cr\"et`, "")
+
+	c, err := Connect(tor.listener.Addr().String(), Auth{Password: `s3cr"et`}, time.Second)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Close()
+	if got := <-tor.commands; got != `AUTHENTICATE "s3cr\"et"` {
+		t.Fatalf("unexpected auth command %q", got)
+	}
+
+	if err := c.NewIdentity(); err != nil {
+		t.Fatalf("NewIdentity: %v", err)
+	}
+	if got := <-tor.commands; got != "SIGNAL NEWNYM" {
+		t.Fatalf("unexpected command %q", got)
+	}
+}
+
+func TestCookieAuthFromProtocolInfo(t *testing.T) {
+	cookiePath := filepath.Join(t.TempDir(), "control_auth_cookie")
+	if err := os.WriteFile(cookiePath, []byte{0x01, 0x02, 0xff}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tor := startFakeTor(t, "", cookiePath)
+
+	c, err := Connect(tor.listener.Addr().String(), Auth{}, time.Second)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Close()
+}
+
+// TestUnresponsive checks that a control port that accepts the connection
+// but never answers fails the command once the timeout is up.
+func TestUnresponsive(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(2 * time.Second)
+	}()
+
+	started := time.Now()
+	_, err = Connect(listener.Addr().String(), Auth{Password: "pw"}, 100*time.Millisecond)
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Connect: %v, want a timeout", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Connect took %s", elapsed)
+	}
+}
+
+func TestFailedAuthentication(t *testing.T) {
+	tor := startFakeTor(t, "right", "")
+	if _, err := Connect(tor.listener.Addr().String(), Auth{Password: "wrong"}, time.Second); err == nil {
+		t.Fatal("expected authentication error")
+	}
+}
+
+func TestBootstrapAndCircuits(t *testing.T) {
+	tor := startFakeTor(t, "pw", "")
+	c, err := Connect(tor.listener.Addr().String(), Auth{Password: "pw"}, time.Second)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer c.Close()
+
+	bootstrap, err := c.Bootstrap()
+	if err != nil {
+		t.Fatalf("Bootstrap: %v", err)
+	}
+	if bootstrap.Progress != 85 || bootstrap.Tag != "ap_conn_done" || bootstrap.Summary != "Connected to a relay to build circuits" {
+		t.Fatalf("unexpected bootstrap %+v", bootstrap)
+	}
+
+	circuits, err := c.Circuits()
+	if err != nil {
+		t.Fatalf("Circuits: %v", err)
+	}
+	if len(circuits) != 2 {
+		t.Fatalf("expected 2 circuits, got %d", len(circuits))
+	}
+	if circuits[0].Status != "BUILT" || len(circuits[0].Path) != 3 || circuits[0].Purpose != "GENERAL" {
+		t.Fatalf("unexpected circuit %+v", circuits[0])
+	}
+	if circuits[1].Status != "LAUNCHED" || len(circuits[1].Path) != 0 || circuits[1].Purpose != "HS_CLIENT_REND" {
+		t.Fatalf("unexpected circuit %+v", circuits[1])
+	}
+}
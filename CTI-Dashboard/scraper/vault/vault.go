@@ -0,0 +1,235 @@
+package vault
+
+import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/scraper/logger"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt parameters for deriving the vault key from the master passphrase.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keyLen  = 32
+	saltLen = 16
+)
+
+// verifierText is sealed with the key when the vault is created so a wrong
+// passphrase is rejected on unlock instead of failing on first use.
+const verifierText = "cti-dashboard vault"
+
+var (
+	ErrLocked          = errors.New("vault is locked")
+	ErrWrongPassphrase = errors.New("wrong vault passphrase")
+	ErrNotFound        = errors.New("no credentials stored for forum")
+)
+
+// Credential is a forum login.
+type Credential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Vault keeps forum credentials and session cookies encrypted at rest with
+// AES-256-GCM. The key is derived from the master passphrase with scrypt and
+// only lives in memory while the vault is unlocked.
+type Vault struct {
+	db  *sql.DB
+	mu  sync.RWMutex
+	key []byte
+}
+
+func New(db *sql.DB) *Vault {
+	return &Vault{db: db}
+}
+
+// Unlock derives the key from the passphrase. The first unlock creates the
+// vault with a fresh salt.
+func (v *Vault) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("passphrase cannot be empty")
+	}
+
+	var salt, verifier []byte
+	err := v.db.QueryRow(`SELECT salt, verifier FROM vault WHERE id = 1`).Scan(&salt, &verifier)
+	if errors.Is(err, sql.ErrNoRows) {
+		return v.create(passphrase)
+	}
+	if err != nil {
+		logger.Error("Could not read the vault", "error", err)
+		return err
+	}
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	plain, err := open(key, verifier, []byte("verifier"))
+	if err != nil || string(plain) != verifierText {
+		return ErrWrongPassphrase
+	}
+
+	v.mu.Lock()
+	v.key = key
+	v.mu.Unlock()
+	logger.Info("Vault unlocked")
+	return nil
+}
+
+func (v *Vault) create(passphrase string) error {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	verifier, err := seal(key, []byte(verifierText), []byte("verifier"))
+	if err != nil {
+		return err
+	}
+	_, err = v.db.Exec(`INSERT INTO vault (id, salt, verifier) VALUES (1, ?, ?)`, salt, verifier)
+	if err != nil {
+		logger.Error("Could not create the vault", "error", err)
+		return err
+	}
+
+	v.mu.Lock()
+	v.key = key
+	v.mu.Unlock()
+	logger.Info("Vault created")
+	return nil
+}
+
+// Lock forgets the key.
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i := range v.key {
+		v.key[i] = 0
+	}
+	v.key = nil
+}
+
+func (v *Vault) Locked() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.key == nil
+}
+
+// Seal encrypts plaintext. aad binds the ciphertext to its owner (e.g. the
+// forum ID) so blobs cannot be swapped between rows.
+func (v *Vault) Seal(plaintext []byte, aad string) ([]byte, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.key == nil {
+		return nil, ErrLocked
+	}
+	return seal(v.key, plaintext, []byte(aad))
+}
+
+func (v *Vault) Open(sealed []byte, aad string) ([]byte, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.key == nil {
+		return nil, ErrLocked
+	}
+	return open(v.key, sealed, []byte(aad))
+}
+
+func (v *Vault) SetCredential(forumID string, cred Credential) error {
+	if cred.Username == "" || cred.Password == "" {
+		return errors.New("username and password cannot be empty")
+	}
+	plain, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	secret, err := v.Seal(plain, "credential/"+forumID)
+	if err != nil {
+		return err
+	}
+	_, err = v.db.Exec(`INSERT INTO forum_credentials (forum_id, secret, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(forum_id) DO UPDATE SET secret = excluded.secret, updated_at = excluded.updated_at`,
+		forumID, secret, stored.Now())
+	if err != nil {
+		logger.Error("Could not store forum credentials", "error", err, "forum_id", forumID)
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) Credential(forumID string) (Credential, error) {
+	var cred Credential
+	var secret []byte
+	err := v.db.QueryRow(`SELECT secret FROM forum_credentials WHERE forum_id = ?`, forumID).Scan(&secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return cred, ErrNotFound
+	}
+	if err != nil {
+		return cred, err
+	}
+	plain, err := v.Open(secret, "credential/"+forumID)
+	if err != nil {
+		return cred, err
+	}
+	err = json.Unmarshal(plain, &cred)
+	return cred, err
+}
+
+func (v *Vault) DeleteCredential(forumID string) error {
+	_, err := v.db.Exec(`DELETE FROM forum_credentials WHERE forum_id = ?`, forumID)
+	if err != nil {
+		logger.Error("Could not delete forum credentials", "error", err, "forum_id", forumID)
+	}
+	return err
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLen)
+}
+
+// seal returns nonce || ciphertext.
+func seal(key []byte, plaintext []byte, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key []byte, sealed []byte, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("sealed data too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
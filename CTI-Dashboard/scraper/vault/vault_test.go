@@ -0,0 +1,127 @@
+package vault
+
+import (
+	"CTI-Dashboard/db/migrations"
+	"CTI-Dashboard/scraper/logger"
+	"bytes"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setup(t *testing.T) *sql.DB {
+	t.Helper()
+	if err := logger.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "vault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Apply(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO forums (forum_id, forum_name, forum_url) VALUES ('f1', 'Exploit Bazaar', 'http://bazaar.onion/')`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSealOpen(t *testing.T) {
+	v := New(setup(t))
+	if _, err := v.Seal([]byte("x"), "a"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Seal while locked: %v, want ErrLocked", err)
+	}
+	if err := v.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	plain := []byte("xf_session=abc")
+	sealed, err := v.Seal(plain, "session/f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, plain) {
+		t.Error("sealed data holds the plaintext")
+	}
+	again, _ := v.Seal(plain, "session/f1")
+	if bytes.Equal(sealed, again) {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+	if got, err := v.Open(sealed, "session/f1"); err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("Open = %q, %v", got, err)
+	}
+
+	// Ciphertext bound to one forum does not open as another's.
+	if _, err := v.Open(sealed, "session/f2"); err == nil {
+		t.Error("opened with another forum's data")
+	}
+	for _, i := range []int{0, len(sealed) / 2, len(sealed) - 1} {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 1
+		if _, err := v.Open(tampered, "session/f1"); err == nil {
+			t.Errorf("opened with byte %d flipped", i)
+		}
+	}
+	if _, err := v.Open(sealed[:5], "session/f1"); err == nil {
+		t.Error("opened truncated data")
+	}
+
+	v.Lock()
+	if _, err := v.Open(sealed, "session/f1"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Open while locked: %v, want ErrLocked", err)
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	db := setup(t)
+	v := New(db)
+	if err := v.Unlock(""); err == nil {
+		t.Error("unlocked with an empty passphrase")
+	}
+	if err := v.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.SetCredential("f1", Credential{Username: "analyst", Password: "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart reads the vault back with the same passphrase only.
+	reopened := New(db)
+	if err := reopened.Unlock("wrong horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Unlock with the wrong passphrase: %v, want ErrWrongPassphrase", err)
+	}
+	if !reopened.Locked() {
+		t.Error("vault unlocked by the wrong passphrase")
+	}
+	if _, err := reopened.Credential("f1"); !errors.Is(err, ErrLocked) {
+		t.Errorf("Credential while locked: %v, want ErrLocked", err)
+	}
+	if err := reopened.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	cred, err := reopened.Credential("f1")
+	if err != nil || cred.Username != "analyst" || cred.Password != "hunter2" {
+		t.Errorf("Credential = %+v, %v", cred, err)
+	}
+	if _, err := reopened.Credential("f2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Credential of another forum: %v, want ErrNotFound", err)
+	}
+
+	var secret []byte
+	if err := db.QueryRow(`SELECT secret FROM forum_credentials WHERE forum_id = 'f1'`).Scan(&secret); err != nil {
+		t.Fatal(err)
+	}
+	secret[len(secret)-1] ^= 1
+	if _, err := db.Exec(`UPDATE forum_credentials SET secret = ? WHERE forum_id = 'f1'`, secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Credential("f1"); err == nil {
+		t.Error("read tampered credentials")
+	}
+}
@@ -0,0 +1,108 @@
+package watchlist
+
+import (
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/export"
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Indicator type of each MISP attribute type that is imported. Composite
+// types such as filename|md5 or ip-dst|port are split first.
+var mispTypes = map[string]string{
+	"url":      export.IndicatorURL,
+	"uri":      export.IndicatorURL,
+	"domain":   export.IndicatorDomain,
+	"hostname": export.IndicatorDomain,
+	"ip-dst":   export.IndicatorIPv4,
+	"ip-src":   export.IndicatorIPv4,
+	"md5":      export.IndicatorMD5,
+	"sha1":     export.IndicatorSHA1,
+	"sha256":   export.IndicatorSHA256,
+}
+
+// ImportMISP adds the indicators of the MISP event file at path to the
+// watchlist. The file holds one event as MISP exports it, a list of events,
+// or a REST response with a list of events.
+func ImportMISP(db *sql.DB, path string) (models.MISPImport, error) {
+	summary := models.MISPImport{File: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.Error("Could not read MISP file", "error", err, "path", path)
+		return summary, err
+	}
+	events, err := ParseMISP(data)
+	if err != nil {
+		logger.Error("Could not parse MISP file", "error", err, "path", path)
+		return summary, err
+	}
+
+	var indicators []models.WatchIndicator
+	for _, event := range events {
+		for _, indicator := range eventIndicators(event.Event) {
+			indicator.Source = filepath.Base(path)
+			indicators = append(indicators, indicator)
+		}
+	}
+	summary.Events = len(events)
+	summary.Indicators = len(indicators)
+	summary.Added, err = Add(db, indicators)
+	if err != nil {
+		return summary, err
+	}
+	logger.Info("Imported MISP indicators", "path", path, "events", summary.Events, "indicators", summary.Indicators, "added", summary.Added)
+	return summary, nil
+}
+
+// ParseMISP reads MISP event JSON in any of the forms ImportMISP accepts.
+func ParseMISP(data []byte) ([]export.MISPEvent, error) {
+	var one export.MISPEvent
+	if err := json.Unmarshal(data, &one); err == nil && (one.Event.UUID != "" || len(one.Event.Attribute) > 0) {
+		return []export.MISPEvent{one}, nil
+	}
+	var list []export.MISPEvent
+	if err := json.Unmarshal(data, &list); err == nil && len(list) > 0 {
+		return list, nil
+	}
+	var response struct {
+		Response []export.MISPEvent `json:"response"`
+	}
+	if err := json.Unmarshal(data, &response); err == nil && len(response.Response) > 0 {
+		return response.Response, nil
+	}
+	return nil, errors.New("no MISP event found")
+}
+
+func eventIndicators(event export.MISPEventBody) []models.WatchIndicator {
+	attributes := event.Attribute
+	for _, object := range event.Object {
+		attributes = append(attributes, object.Attribute...)
+	}
+	var list []models.WatchIndicator
+	for _, attribute := range attributes {
+		kinds := strings.Split(attribute.Type, "|")
+		values := strings.Split(attribute.Value, "|")
+		for i, kind := range kinds {
+			indicatorType, ok := mispTypes[kind]
+			if !ok || i >= len(values) || strings.TrimSpace(values[i]) == "" {
+				continue
+			}
+			value := strings.TrimSpace(values[i])
+			if indicatorType != export.IndicatorURL {
+				value = strings.ToLower(value)
+			}
+			list = append(list, models.WatchIndicator{
+				Type:      indicatorType,
+				Value:     value,
+				EventUUID: event.UUID,
+				EventInfo: event.Info,
+			})
+		}
+	}
+	return list
+}
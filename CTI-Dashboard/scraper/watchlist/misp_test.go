@@ -0,0 +1,66 @@
+package watchlist
+
+import (
+	"CTI-Dashboard/scraper/export"
+	"testing"
+)
+
+const event = `{"Event": {"uuid": "5f0c2d4e-0000-4000-8000-000000000001", "info": "Carding shop", "Attribute": [
+	{"type": "domain", "value": "Shop.Example.onion"},
+	{"type": "ip-dst|port", "value": "203.0.113.7|443"},
+	{"type": "filename|md5", "value": "kit.zip|D41D8CD98F00B204E9800998ECF8427E"},
+	{"type": "comment", "value": "not an indicator"}
+], "Object": [{"Attribute": [{"type": "url", "value": "http://Shop.example.onion/Buy"}]}]}}`
+
+func TestParseMISP(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		events int
+	}{
+		{"event", event, 1},
+		{"list", "[" + event + "," + event + "]", 2},
+		{"response", `{"response": [` + event + `]}`, 1},
+	}
+	for _, test := range tests {
+		events, err := ParseMISP([]byte(test.data))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(events) != test.events || events[0].Event.Info != "Carding shop" {
+			t.Errorf("%s: got %+v, want %d events", test.name, events, test.events)
+		}
+	}
+	for _, data := range []string{`{}`, `[]`, `{"response": []}`, `not json`} {
+		if _, err := ParseMISP([]byte(data)); err == nil {
+			t.Errorf("ParseMISP(%s) did not fail", data)
+		}
+	}
+}
+
+func TestEventIndicators(t *testing.T) {
+	events, err := ParseMISP([]byte(event))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"shop.example.onion":               export.IndicatorDomain,
+		"203.0.113.7":                      export.IndicatorIPv4,
+		"d41d8cd98f00b204e9800998ecf8427e": export.IndicatorMD5,
+		// URLs keep their case: paths are case-sensitive.
+		"http://Shop.example.onion/Buy": export.IndicatorURL,
+	}
+	indicators := eventIndicators(events[0].Event)
+	if len(indicators) != len(want) {
+		t.Errorf("got %d indicators, want %d: %+v", len(indicators), len(want), indicators)
+	}
+	for _, indicator := range indicators {
+		if want[indicator.Value] != indicator.Type {
+			t.Errorf("got %s %q", indicator.Type, indicator.Value)
+		}
+		if indicator.EventUUID != "5f0c2d4e-0000-4000-8000-000000000001" || indicator.EventInfo != "Carding shop" {
+			t.Errorf("event not kept on %+v", indicator)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package watchlist
+
+import (
+	"CTI-Dashboard/db/stored"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/changes"
+	"CTI-Dashboard/scraper/export"
+	"CTI-Dashboard/scraper/logger"
+	"database/sql"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Add stores indicators not on the watchlist yet and returns how many were
+// new.
+func Add(db *sql.DB, indicators []models.WatchIndicator) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		logger.Error("Could not begin transaction", "error", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	importedAt := stored.Now()
+	added := 0
+	for _, indicator := range indicators {
+		result, err := tx.Exec(`INSERT OR IGNORE INTO watch_indicators (indicator_id, type, value, event_uuid, event_info, source, imported_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			uuid.New().String(), indicator.Type, indicator.Value, indicator.EventUUID, indicator.EventInfo, indicator.Source, importedAt)
+		if err != nil {
+			logger.Error("Could not store indicator", "error", err, "type", indicator.Type, "value", indicator.Value)
+			return 0, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+	return added, tx.Commit()
+}
+
+func List(db *sql.DB) ([]models.WatchIndicator, error) {
+	rows, err := db.Query(`SELECT indicator_id, type, value, COALESCE(event_uuid, ''), COALESCE(event_info, ''), COALESCE(source, ''), imported_at
+		FROM watch_indicators ORDER BY imported_at DESC, type, value`)
+	if err != nil {
+		logger.Error("Could not query indicators", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.WatchIndicator
+	for rows.Next() {
+		var i models.WatchIndicator
+		if err := rows.Scan(&i.IndicatorID, &i.Type, &i.Value, &i.EventUUID, &i.EventInfo, &i.Source, &i.ImportedAt); err != nil {
+			logger.Error("Could not scan indicator row", "error", err)
+			continue
+		}
+		list = append(list, i)
+	}
+	return list, rows.Err()
+}
+
+// Delete removes an indicator and the flags it set on posts.
+func Delete(db *sql.DB, indicatorID string) error {
+	if _, err := db.Exec(`DELETE FROM indicator_matches WHERE indicator_id = ?`, indicatorID); err != nil {
+		logger.Error("Could not delete indicator matches", "error", err, "indicator_id", indicatorID)
+		return err
+	}
+	if _, err := db.Exec(`DELETE FROM watch_indicators WHERE indicator_id = ?`, indicatorID); err != nil {
+		logger.Error("Could not delete indicator", "error", err, "indicator_id", indicatorID)
+		return err
+	}
+	return nil
+}
+
+// Check flags the post if its page contains watched indicators and returns
+// the matches not flagged before.
+func Check(db *sql.DB, forumID string, postID string, threadURL string, body []byte) ([]models.IndicatorMatch, error) {
+	indicators, err := List(db)
+	if err != nil || len(indicators) == 0 || postID == "" {
+		return nil, err
+	}
+	text := pageText(body)
+	matchedAt := stored.Now()
+
+	var found []models.IndicatorMatch
+	for _, indicator := range indicators {
+		if !contains(text, strings.ToLower(indicator.Value)) {
+			continue
+		}
+		result, err := db.Exec(`INSERT OR IGNORE INTO indicator_matches (post_id, indicator_id, forum_id, thread_url, matched_at) VALUES (?, ?, ?, ?, ?)`,
+			postID, indicator.IndicatorID, stored.Nullable(forumID), threadURL, matchedAt)
+		if err != nil {
+			logger.Error("Could not flag post", "error", err, "post_id", postID, "indicator_id", indicator.IndicatorID)
+			return found, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			continue
+		}
+		found = append(found, models.IndicatorMatch{
+			PostID:      postID,
+			ForumID:     forumID,
+			ThreadURL:   threadURL,
+			IndicatorID: indicator.IndicatorID,
+			Type:        indicator.Type,
+			Value:       indicator.Value,
+			EventInfo:   indicator.EventInfo,
+			MatchedAt:   matchedAt,
+		})
+	}
+	return found, nil
+}
+
+// Matches returns the flagged posts of a forum, or of all forums when
+// forumID is empty, newest first.
+func Matches(db *sql.DB, forumID string) ([]models.IndicatorMatch, error) {
+	rows, err := db.Query(`SELECT m.post_id, COALESCE(m.forum_id, ''), m.thread_url, m.indicator_id, i.type, i.value, COALESCE(i.event_info, ''), m.matched_at
+		FROM indicator_matches m JOIN watch_indicators i ON i.indicator_id = m.indicator_id
+		WHERE ? = '' OR m.forum_id = ? ORDER BY m.matched_at DESC`, forumID, forumID)
+	if err != nil {
+		logger.Error("Could not query indicator matches", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.IndicatorMatch
+	for rows.Next() {
+		var m models.IndicatorMatch
+		if err := rows.Scan(&m.PostID, &m.ForumID, &m.ThreadURL, &m.IndicatorID, &m.Type, &m.Value, &m.EventInfo, &m.MatchedAt); err != nil {
+			logger.Error("Could not scan indicator match row", "error", err)
+			continue
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// pageText is the lowercased visible text of a page followed by its link
+// targets, so indicators only present in a link are found too. Defanged
+// forms are matched like the real ones.
+func pageText(body []byte) string {
+	text := string(body)
+	if doc, err := goquery.NewDocumentFromReader(strings.NewReader(text)); err == nil {
+		parts := []string{changes.Text(doc.Find("body"))}
+		doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+			parts = append(parts, s.AttrOr("href", ""))
+		})
+		text = strings.Join(parts, "\n")
+	}
+	return export.Refang(strings.ToLower(text))
+}
+
+// contains reports whether value occurs in text on its own, so that 1.2.3.4
+// does not match 11.2.3.45 and a hash does not match part of a longer one.
+func contains(text string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for start := 0; ; {
+		i := strings.Index(text[start:], value)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(value)
+		if (i == 0 || !joins(text, i-1)) && (end == len(text) || !joins(text, end)) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+// joins reports whether the character at i continues a word. A dot only
+// does when more of the word follows, not at the end of a sentence.
+func joins(text string, i int) bool {
+	c := text[i]
+	switch {
+	case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_':
+		return true
+	case c == '.':
+		return i+1 < len(text) && i > 0 && isAlnum(text[i+1]) && isAlnum(text[i-1])
+	}
+	return false
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
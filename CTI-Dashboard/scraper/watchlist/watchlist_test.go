@@ -0,0 +1,44 @@
+package watchlist
+
+import "testing"
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		text  string
+		value string
+		want  bool
+	}{
+		{"drop at 1.2.3.4 now", "1.2.3.4", true},
+		{"drop at 11.2.3.45 now", "1.2.3.4", false},
+		{"drop at 1.2.3.4.", "1.2.3.4", true},
+		{"drop at 1.2.3.4.5", "1.2.3.4", false},
+		{"1.2.3.4", "1.2.3.4", true},
+		{"see evil.onion/path", "evil.onion", true},
+		{"see notevil.onion", "evil.onion", false},
+		{"see sub.evil.onion", "evil.onion", false},
+		{"see evil.onion-mirror", "evil.onion", false},
+		{"hash d41d8cd98f00b204e9800998ecf8427e,", "d41d8cd98f00b204e9800998ecf8427e", true},
+		{"hash d41d8cd98f00b204e9800998ecf8427eff", "d41d8cd98f00b204e9800998ecf8427e", false},
+		// A later occurrence on its own still counts.
+		{"11.2.3.4 and 1.2.3.4", "1.2.3.4", true},
+		{"anything", "", false},
+	}
+	for _, test := range tests {
+		if got := contains(test.text, test.value); got != test.want {
+			t.Errorf("contains(%q, %q) = %v, want %v", test.text, test.value, got, test.want)
+		}
+	}
+}
+
+func TestPageText(t *testing.T) {
+	text := pageText([]byte(`<html><body><script>var x = "hidden.onion"</script>
+		<p>Mirror at Evil[.]Onion, panel hxxp://203.0.113.7/</p><a href="http://link.onion/">here</a></body></html>`))
+	for _, value := range []string{"evil.onion", "http://203.0.113.7/", "link.onion"} {
+		if !contains(text, value) {
+			t.Errorf("%q not found in %q", value, text)
+		}
+	}
+	if contains(text, "hidden.onion") {
+		t.Errorf("script text kept in %q", text)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/config"
+	"CTI-Dashboard/scraper/connectivity"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/proxy"
+)
+
+// option ties a setting to its configuration option. Options that need a
+// restart are saved but keep their running value until the next start.
+type option struct {
+	key     string
+	restart bool
+	set     func(to *config.Config, from config.Config)
+}
+
+var settingOptions = []option{
+	{"target_file", false, func(to *config.Config, from config.Config) { to.TargetFile = from.TargetFile }},
+	{"db_path", true, func(to *config.Config, from config.Config) { to.DBPath = from.DBPath }},
+	{"output_dir", true, func(to *config.Config, from config.Config) { to.OutputDir = from.OutputDir }},
+	{"tor_proxy", false, func(to *config.Config, from config.Config) { to.TorProxy = from.TorProxy }},
+	{"tor_control", false, func(to *config.Config, from config.Config) { to.TorControl = from.TorControl }},
+	{"proxy_strategy", false, func(to *config.Config, from config.Config) { to.ProxyStrategy = from.ProxyStrategy }},
+	{"timeout", false, func(to *config.Config, from config.Config) { to.Timeout = from.Timeout }},
+	{"max_retries", false, func(to *config.Config, from config.Config) { to.MaxRetries = from.MaxRetries }},
+	{"workers", false, func(to *config.Config, from config.Config) { to.Workers = from.Workers }},
+	{"connectivity_check", false, func(to *config.Config, from config.Config) { to.ConnectivityCheck = from.ConnectivityCheck }},
+	{"canary_url", false, func(to *config.Config, from config.Config) { to.CanaryURL = from.CanaryURL }},
+	{"connectivity_ttl", false, func(to *config.Config, from config.Config) { to.ConnectivityTTL = from.ConnectivityTTL }},
+	{"browser_tabs", true, func(to *config.Config, from config.Config) { to.BrowserTabs = from.BrowserTabs }},
+	{"browser_recycle", true, func(to *config.Config, from config.Config) { to.BrowserRecycle = from.BrowserRecycle }},
+	{"warc_rotation", true, func(to *config.Config, from config.Config) { to.WARCRotation = from.WARCRotation }},
+	{"taxii_address", false, func(to *config.Config, from config.Config) { to.TAXIIAddress = from.TAXIIAddress }},
+	{"api_address", false, func(to *config.Config, from config.Config) { to.APIAddress = from.APIAddress }},
+}
+
+// Settings in the configuration file, as saved
+func (a *App) GetSettings() models.Settings {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	cfg, restart := a.cfg, a.restart
+	if a.saved != nil {
+		cfg = *a.saved
+	}
+	settings := settingsOf(cfg)
+	settings.ConfigFile = a.configPath
+	settings.RestartRequired = append([]string{}, restart...)
+	return settings
+}
+
+// Save settings to the configuration file and apply them, rebuilding the
+// proxies and connectivity check and restarting the servers as needed
+func (a *App) UpdateSettings(settings models.Settings) (models.Settings, error) {
+	a.updating.Lock()
+	defer a.updating.Unlock()
+
+	a.mu.RLock()
+	current, base := a.cfg, a.cfg
+	if a.saved != nil {
+		base = *a.saved
+	}
+	a.mu.RUnlock()
+
+	next, err := applySettings(base, settings)
+	if err != nil {
+		return models.Settings{}, err
+	}
+	if err := next.Validate(); err != nil {
+		return models.Settings{}, invalid("%v", err)
+	}
+	var changed []option
+	for _, opt := range settingOptions {
+		if differs(opt, base, next) {
+			changed = append(changed, opt)
+		}
+	}
+	if len(changed) == 0 {
+		return a.GetSettings(), nil
+	}
+
+	// live is what runs from now on: every change but those that need a
+	// restart.
+	live := current
+	var restart []string
+	rebuildProxies, rebuildCheck, restartTAXII, restartAPI := false, false, false, false
+	for _, opt := range settingOptions {
+		if !differs(opt, current, next) {
+			continue
+		}
+		if opt.restart {
+			restart = append(restart, opt.key)
+			continue
+		}
+		opt.set(&live, next)
+		switch opt.key {
+		case "tor_proxy", "proxy_strategy", "timeout":
+			rebuildProxies = true
+		case "tor_control", "connectivity_check", "canary_url", "connectivity_ttl":
+			rebuildCheck = true
+		case "taxii_address":
+			restartTAXII = true
+		case "api_address":
+			restartAPI = true
+		}
+	}
+
+	// Build what the new options need before anything is saved, so a
+	// setting that cannot work is refused as a whole.
+	pool, isolator, checker := a.network()
+	if rebuildProxies {
+		if pool, err = proxy.NewPool(live); err != nil {
+			return models.Settings{}, invalid("%v", err)
+		}
+		isolator = proxy.NewIsolator(live)
+	}
+	if rebuildCheck {
+		if checker, err = connectivity.New(live); err != nil {
+			return models.Settings{}, invalid("%v", err)
+		}
+	}
+
+	// Only the options changed go to the file, so secrets and overrides
+	// from the environment stay out of it.
+	saved, err := config.Read(a.configPath)
+	if err != nil {
+		logger.Error("Could not read the configuration file", "error", err, "path", a.configPath)
+		return models.Settings{}, err
+	}
+	for _, opt := range changed {
+		opt.set(&saved, next)
+	}
+	if err := config.Save(a.configPath, saved); err != nil {
+		logger.Error("Could not save the configuration file", "error", err, "path", a.configPath)
+		return models.Settings{}, err
+	}
+
+	a.mu.Lock()
+	if rebuildProxies {
+		a.retired = append(a.retired, a.isolator)
+	}
+	a.cfg, a.pool, a.isolator, a.checker = live, pool, isolator, checker
+	a.saved, a.restart = &next, restart
+	a.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if restartTAXII {
+		a.mu.Lock()
+		server := a.taxii
+		a.taxii = nil
+		a.mu.Unlock()
+		if server != nil {
+			server.Shutdown(ctx)
+		}
+		a.startTAXII()
+	}
+	if restartAPI {
+		a.mu.Lock()
+		server := a.api
+		a.api = nil
+		a.mu.Unlock()
+		if server != nil {
+			server.Shutdown(ctx)
+		}
+		a.startAPI()
+	}
+	logger.Info("Settings updated", "path", a.configPath, "restart_required", restart)
+	return a.GetSettings(), nil
+}
+
+// differs reports whether the option has another value in b than in a.
+func differs(opt option, a config.Config, b config.Config) bool {
+	probe := a
+	opt.set(&probe, b)
+	return !reflect.DeepEqual(probe, a)
+}
+
+func settingsOf(cfg config.Config) models.Settings {
+	return models.Settings{
+		TargetFile:        cfg.TargetFile,
+		DBPath:            cfg.DBPath,
+		OutputDir:         cfg.OutputDir,
+		TorProxy:          cfg.TorProxy,
+		TorControl:        cfg.TorControl,
+		ProxyStrategy:     cfg.ProxyStrategy,
+		Timeout:           cfg.Timeout.String(),
+		MaxRetries:        cfg.MaxRetries,
+		Workers:           cfg.Workers,
+		ConnectivityCheck: cfg.ConnectivityCheck,
+		CanaryURL:         cfg.CanaryURL,
+		ConnectivityTTL:   cfg.ConnectivityTTL.String(),
+		BrowserTabs:       cfg.BrowserTabs,
+		BrowserRecycle:    cfg.BrowserRecycle,
+		WARCRotation:      cfg.WARCRotation,
+		TAXIIAddress:      cfg.TAXIIAddress,
+		APIAddress:        cfg.APIAddress,
+	}
+}
+
+// applySettings returns cfg with the options of settings.
+func applySettings(cfg config.Config, settings models.Settings) (config.Config, error) {
+	timeout, err := time.ParseDuration(settings.Timeout)
+	if err != nil {
+		return cfg, invalid("timeout: %v", err)
+	}
+	ttl, err := time.ParseDuration(settings.ConnectivityTTL)
+	if err != nil {
+		return cfg, invalid("connectivity_ttl: %v", err)
+	}
+	cfg.TargetFile = settings.TargetFile
+	cfg.DBPath = settings.DBPath
+	cfg.OutputDir = settings.OutputDir
+	cfg.TorProxy = settings.TorProxy
+	cfg.TorControl = settings.TorControl
+	cfg.ProxyStrategy = settings.ProxyStrategy
+	cfg.Timeout = timeout
+	cfg.MaxRetries = settings.MaxRetries
+	cfg.Workers = settings.Workers
+	cfg.ConnectivityCheck = settings.ConnectivityCheck
+	cfg.CanaryURL = settings.CanaryURL
+	cfg.ConnectivityTTL = ttl
+	cfg.BrowserTabs = settings.BrowserTabs
+	cfg.BrowserRecycle = settings.BrowserRecycle
+	cfg.WARCRotation = settings.WARCRotation
+	cfg.TAXIIAddress = settings.TAXIIAddress
+	cfg.APIAddress = settings.APIAddress
+	return cfg, nil
+}
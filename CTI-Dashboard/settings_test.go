@@ -0,0 +1,124 @@
+package main
+
+import (
+	"CTI-Dashboard/db/migrations"
+	"CTI-Dashboard/models"
+	"CTI-Dashboard/scraper/config"
+	"CTI-Dashboard/scraper/connectivity"
+	"CTI-Dashboard/scraper/logger"
+	"CTI-Dashboard/scraper/output"
+	"CTI-Dashboard/scraper/proxy"
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func testApp(t *testing.T) *App {
+	t.Helper()
+	dir := t.TempDir()
+	if err := logger.Init(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(logger.Close)
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Apply(db); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.OutputDir = filepath.Join(dir, "output")
+	cfg.APIKeys = map[string]string{"ci": "secret"}
+	path := filepath.Join(dir, "config.yaml")
+	if err := config.Save(path, config.Default()); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := proxy.NewPool(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer, err := output.NewWriter(cfg.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := NewApp(cfg, path, pool, connectivity.None{}, writer, db)
+	t.Cleanup(func() { app.shutdown(context.Background()) })
+	return app
+}
+
+func TestUpdateSettings(t *testing.T) {
+	app := testApp(t)
+	tests := []struct {
+		name    string
+		change  func(*models.Settings)
+		restart []string
+		check   func(t *testing.T, cfg config.Config)
+		invalid bool
+	}{
+		{name: "live option", change: func(s *models.Settings) { s.Timeout, s.Workers = "45s", 8 },
+			check: func(t *testing.T, cfg config.Config) {
+				if cfg.Timeout != 45*time.Second || cfg.Workers != 8 {
+					t.Errorf("running with %s and %d workers", cfg.Timeout, cfg.Workers)
+				}
+			}},
+		{name: "restart option", change: func(s *models.Settings) { s.DBPath = "other.db" }, restart: []string{"db_path"},
+			check: func(t *testing.T, cfg config.Config) {
+				if cfg.DBPath == "other.db" {
+					t.Error("db_path changed without a restart")
+				}
+			}},
+		{name: "server restarted", change: func(s *models.Settings) { s.APIAddress = "127.0.0.1:0" }, restart: []string{"db_path"},
+			check: func(t *testing.T, cfg config.Config) {
+				if _, apiServer := app.servers(); apiServer == nil {
+					t.Error("API server not started")
+				}
+			}},
+		{name: "bad duration", change: func(s *models.Settings) { s.Timeout = "soon" }, invalid: true},
+		{name: "out of range", change: func(s *models.Settings) { s.Workers = 0 }, invalid: true},
+		{name: "bad strategy", change: func(s *models.Settings) { s.ProxyStrategy = "random" }, invalid: true},
+	}
+	for _, test := range tests {
+		settings := app.GetSettings()
+		test.change(&settings)
+		updated, err := app.UpdateSettings(settings)
+		if test.invalid {
+			var bad invalidError
+			if !errors.As(err, &bad) {
+				t.Errorf("%s: err = %v, want an invalid setting", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !slices.Equal(updated.RestartRequired, test.restart) {
+			t.Errorf("%s: restart required for %v, want %v", test.name, updated.RestartRequired, test.restart)
+		}
+		test.check(t, app.config())
+	}
+
+	// Only the options changed are written, over what the file held.
+	saved, err := config.Read(app.configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Timeout != 45*time.Second || saved.Workers != 8 || saved.DBPath != "other.db" || saved.APIAddress != "127.0.0.1:0" {
+		t.Errorf("saved %+v", saved)
+	}
+	if len(saved.APIKeys) != 0 {
+		t.Errorf("saved the API keys given outside the file: %v", saved.APIKeys)
+	}
+	if settings := app.GetSettings(); settings.DBPath != "other.db" || settings.Timeout != "45s" {
+		t.Errorf("GetSettings = %+v, want the saved settings", settings)
+	}
+}
@@ -3,7 +3,7 @@
 # Entrypoint script for Wails development container
 
 # Set up environment for Ubuntu 24.04 WebKit compatibility
-export WEBKIT_TAGS="-tags webkit2_41"
+export WEBKIT_TAGS="-tags webkit2_41,sqlite_fts5"
 
 # Ensure PATH includes Go and user binaries
 export PATH="/home/wailsdev/go/bin:/usr/local/go/bin:$PATH"
@@ -29,7 +29,7 @@ fi
 # Function to run wails commands with proper tags
 wails_with_tags() {
     if [[ "$1" == "build" ]] || [[ "$1" == "dev" ]]; then
-        # Add webkit2_41 tags for Ubuntu 24.04 compatibility
+        # Add webkit2_41 for Ubuntu 24.04 compatibility and sqlite_fts5 for post search
         wails "$@" $WEBKIT_TAGS
     else
         wails "$@"